@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
 
 	"llmapibenchmark/internal/utils"
@@ -11,23 +12,19 @@ import (
 
 func (benchmark *Benchmark) runCli() error {
 	ctx := context.Background() // CLI always uses background context
-	// Test latency
-	latency, err := utils.MeasureLatency(benchmark.BaseURL, 5)
-	if err != nil {
-		return fmt.Errorf("latency test error: %v", err)
-	}
+	measurement := benchmark.describeMeasurement(ctx)
 
 	// Print benchmark header
-	utils.PrintBenchmarkHeader(benchmark.ModelName, benchmark.InputTokens, benchmark.MaxTokens, latency)
+	utils.PrintBenchmarkHeader(benchmark.ModelName, benchmark.InputTokens, benchmark.MaxTokens, measurement.NetworkRttMs)
 
 	// Print table header
 	fmt.Println("| Concurrency | Generation Throughput (tokens/s) |  Prompt Throughput (tokens/s) | Min TTFT (s) | Max TTFT (s) |")
 	fmt.Println("|-------------|----------------------------------|-------------------------------|--------------|--------------|")
 
 	// Test each concurrency level and print results
-	var results [][]interface{}
+	var results []utils.SpeedResult
 	for _, concurrency := range benchmark.ConcurrencyLevels {
-		result, err := benchmark.measureSpeed(ctx, latency, concurrency, true)
+		result, err := benchmark.measureSpeed(ctx, concurrency, true)
 		if err != nil {
 			return fmt.Errorf("concurrency %d: %v", concurrency, err)
 		}
@@ -42,19 +39,39 @@ func (benchmark *Benchmark) runCli() error {
 		)
 
 		// Save results for later
-		results = append(results, []interface{}{
-			concurrency,
-			result.GenerationSpeed,
-			result.PromptThroughput,
-			result.MinTtft,
-			result.MaxTtft,
-		})
+		results = append(results, result)
+	}
+
+	// Print per-request metrics
+	fmt.Printf("\nPer-request metrics\n\n%s", utils.FormatRequestMetricsTable(results))
+
+	// Print where the time to first token went
+	if table := utils.FormatTtftBreakdownTable(results); table != "" {
+		fmt.Printf("\nTTFT breakdown (mean ms per request)\n\n%s", table)
+	}
+
+	// Print failed requests by cause
+	if table := utils.FormatErrorClassesTable(results); table != "" {
+		fmt.Printf("\nFailed requests\n\n%s", table)
+	}
+
+	// Print run statistics when levels were repeated
+	if table := utils.FormatRunStatsTable(results); table != "" {
+		fmt.Printf("\nRun statistics (%d runs per level)\n\n%s", benchmark.Runs, table)
 	}
 
 	fmt.Println("\n================================================================================================================")
 
 	// Save results to Markdown
-	utils.SaveResultsToMD(results, benchmark.ModelName, benchmark.InputTokens, benchmark.MaxTokens, latency)
+	utils.SaveResultsToMD(results, benchmark.ModelName, benchmark.InputTokens, benchmark.MaxTokens, measurement.NetworkRttMs)
+
+	benchmark.publishResult("benchmark", results, BenchmarkResult{
+		ModelName:   benchmark.ModelName,
+		InputTokens: benchmark.InputTokens,
+		MaxTokens:   benchmark.MaxTokens,
+		Results:     results,
+		Measurement: measurement,
+	})
 
 	return nil
 }
@@ -65,12 +82,7 @@ func (benchmark *Benchmark) run(ctx context.Context) (BenchmarkResult, error) {
 	result.InputTokens = benchmark.InputTokens
 	result.MaxTokens = benchmark.MaxTokens
 
-	// Test latency
-	latency, err := utils.MeasureLatency(benchmark.BaseURL, 5)
-	if err != nil {
-		return result, fmt.Errorf("error testing latency: %v", err)
-	}
-	result.Latency = latency
+	result.Measurement = benchmark.describeMeasurement(ctx)
 
 	for _, concurrency := range benchmark.ConcurrencyLevels {
 		// Check for cancellation before each concurrency level
@@ -80,7 +92,7 @@ func (benchmark *Benchmark) run(ctx context.Context) (BenchmarkResult, error) {
 		default:
 		}
 		
-		measurement, err := benchmark.measureSpeed(ctx, latency, concurrency, false)
+		measurement, err := benchmark.measureSpeed(ctx, concurrency, false)
 		if err != nil {
 			return result, fmt.Errorf("concurrency %d: %v", concurrency, err)
 		}
@@ -88,13 +100,29 @@ func (benchmark *Benchmark) run(ctx context.Context) (BenchmarkResult, error) {
 		result.Results = append(result.Results, measurement)
 	}
 
+	benchmark.publishResult("benchmark", result.Results, result)
 	return result, nil
 }
 
-func (benchmark *Benchmark) measureSpeed(ctx context.Context, latency float64, concurrency int, clearProgress bool) (utils.SpeedResult, error) {
+// describeMeasurement returns the measurement model, with a network round-trip estimate
+// when requested. A failed estimate is reported but does not stop the benchmark.
+func (benchmark *Benchmark) describeMeasurement(ctx context.Context) utils.MeasurementModel {
+	measurement := utils.DescribeMeasurement()
+	if benchmark.EstimateRTT {
+		rtt, err := utils.EstimateNetworkRTT(ctx, benchmark.BaseURL, 5)
+		if err != nil {
+			log.Printf("Network RTT estimate failed: %v", err)
+		} else {
+			measurement.NetworkRttMs = rtt
+		}
+	}
+	return measurement
+}
+
+func (benchmark *Benchmark) measureSpeed(ctx context.Context, concurrency int, clearProgress bool) (utils.SpeedResult, error) {
 
 	// Create a progress bar for this specific concurrency level
-	expectedTokens := concurrency * benchmark.MaxTokens
+	expectedTokens := concurrency * benchmark.MaxTokens * max(benchmark.Runs, 1)
 	bar := progressbar.NewOptions(expectedTokens,
 		progressbar.OptionSetWriter(os.Stderr),
 		progressbar.OptionSetDescription(fmt.Sprintf("Concurrency %d", concurrency)),
@@ -107,14 +135,21 @@ func (benchmark *Benchmark) measureSpeed(ctx context.Context, latency float64, c
 	)
 
 	speedMeasurement := utils.SpeedMeasurement{
-		BaseUrl:     benchmark.BaseURL,
-		ApiKey:      benchmark.ApiKey,
-		ModelName:   benchmark.ModelName,
-		Prompt:      benchmark.Prompt,
-		NumWords:    benchmark.NumWords,
-		MaxTokens:   benchmark.MaxTokens,
-		Latency:     latency,
-		Concurrency: concurrency,
+		BaseUrl:        benchmark.BaseURL,
+		ApiKey:         benchmark.ApiKey,
+		ModelName:      benchmark.ModelName,
+		Prompt:         benchmark.Prompt,
+		NumWords:       benchmark.NumWords,
+		MaxTokens:      benchmark.MaxTokens,
+		Concurrency:    concurrency,
+		Repetitions:    benchmark.Runs,
+		Cooldown:       benchmark.Cooldown,
+		TolerateErrors: benchmark.TolerateErrors,
+		GoodputTargets: benchmark.GoodputTargets,
+		Transport:      benchmark.Transport,
+		Timeouts:       benchmark.Timeouts,
+		Trace:          benchmark.Trace,
+		Limiter:        benchmark.Limiter,
 	}
 	if benchmark.UseRandomInput {
 		speedMeasurement.UseRandomInput = true
@@ -24,3 +24,21 @@ func (benchmark *BenchmarkResult) Yaml() (string, error) {
 
 	return string(yamlData), nil
 }
+
+func (search *SearchResult) Json() (string, error) {
+	prettyJSON, err := json.MarshalIndent(search, "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling JSON: %w", err)
+	}
+
+	return string(prettyJSON), nil
+}
+
+func (search *SearchResult) Yaml() (string, error) {
+	yamlData, err := yaml.Marshal(&search)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling yaml: %v", err)
+	}
+
+	return string(yamlData), nil
+}
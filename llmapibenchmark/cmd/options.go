@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"llmapibenchmark/internal/api"
+	"llmapibenchmark/internal/sinks"
+	"llmapibenchmark/internal/utils"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultPrompt = "Write a long story, no less than 10,000 words, starting from a long, long time ago."
+)
+
+// commonOptions holds the flags shared by the benchmark and the search command.
+type commonOptions struct {
+	baseURL               *string
+	apiKey                *string
+	model                 *string
+	prompt                *string
+	numWords              *int
+	maxTokens             *int
+	insecureSkipTLSVerify *bool
+	goodputTtft           *time.Duration
+	goodputTpot           *time.Duration
+	estimateRTT           *bool
+	maxIdleConnsPerHost   *int
+	httpVersion           *string
+	proxyURL              *string
+	caFile                *string
+	clientCert            *string
+	clientKey             *string
+	connectTimeout        *time.Duration
+	tlsHandshakeTimeout   *time.Duration
+	responseHeaderTimeout *time.Duration
+	ttftTimeout           *time.Duration
+	idleTimeout           *time.Duration
+	requestTimeout        *time.Duration
+	rateLimitRequests     *float64
+	rateLimitTokens       *float64
+	traceFile             *string
+	sinkInfluxURL         *string
+	sinkInfluxToken       *string
+	sinkPushgatewayURL    *string
+	sinkWebhookURL        *string
+	sinkDir               *string
+	sinkRetries           *int
+}
+
+// bindCommonFlags registers the endpoint, model and prompt flags on a flag set.
+func bindCommonFlags(flags *pflag.FlagSet) *commonOptions {
+	return &commonOptions{
+		baseURL:               flags.StringP("base-url", "u", "", "Base URL of the OpenAI API"),
+		apiKey:                flags.StringP("api-key", "k", "", "API key for authentication"),
+		model:                 flags.StringP("model", "m", "", "Model to be used for the requests (optional)"),
+		prompt:                flags.StringP("prompt", "p", defaultPrompt, "Prompt to be used for generating responses"),
+		numWords:              flags.IntP("num-words", "n", 0, "If set to a value above 0 a random string with this length will be used as prompt"),
+		maxTokens:             flags.IntP("max-tokens", "t", 512, "Maximum number of tokens to generate"),
+		insecureSkipTLSVerify: flags.Bool("insecure-skip-tls-verify", false, "Skip TLS certificate verification. Use with caution, this is insecure."),
+		goodputTtft:           flags.Duration("goodput-ttft", 0, "Per-request time to first token target for goodput (e.g. 2s); 0 disables the check"),
+		goodputTpot:           flags.Duration("goodput-tpot", 0, "Per-request time per output token target for goodput (e.g. 50ms); 0 disables the check"),
+		estimateRTT:           flags.Bool("estimate-rtt", false, "Estimate the network round-trip time from TCP connect timing (informational only)"),
+		maxIdleConnsPerHost:   flags.Int("max-idle-conns-per-host", api.DefaultMaxIdleConnsPerHost, "Idle connections kept open per host for reuse"),
+		httpVersion:           flags.String("http-version", "", "Force the HTTP version: \"1.1\" or \"2\" (default negotiates)"),
+		proxyURL:              flags.String("proxy", "", "HTTP(S) proxy URL (default uses HTTP_PROXY/HTTPS_PROXY)"),
+		caFile:                flags.String("ca-file", "", "PEM CA bundle to trust in addition to the system roots"),
+		clientCert:            flags.String("client-cert", "", "PEM client certificate for mutual TLS"),
+		clientKey:             flags.String("client-key", "", "PEM private key of the client certificate"),
+		connectTimeout:        flags.Duration("connect-timeout", api.DefaultRequestTimeouts.Connect, "Deadline for getting a connection, including DNS, TCP and TLS; 0 disables it"),
+		tlsHandshakeTimeout:   flags.Duration("tls-handshake-timeout", 0, "TLS handshake timeout (default 10s)"),
+		responseHeaderTimeout: flags.Duration("response-header-timeout", 0, "Maximum wait for response headers; 0 waits indefinitely"),
+		ttftTimeout:           flags.Duration("ttft-timeout", api.DefaultRequestTimeouts.FirstToken, "Deadline from sending a request to its first token; 0 disables it"),
+		idleTimeout:           flags.Duration("idle-timeout", api.DefaultRequestTimeouts.Idle, "Maximum gap between two stream chunks; 0 disables it"),
+		requestTimeout:        flags.Duration("request-timeout", api.DefaultRequestTimeouts.Total, "Deadline for a whole request; 0 disables it"),
+		rateLimitRequests:     flags.Float64("rate-limit-rpm", 0, "Send at most this many requests per minute, waiting before sending; 0 disables the limit"),
+		rateLimitTokens:       flags.Float64("rate-limit-tpm", 0, "Send at most this many prompt and completion tokens per minute; 0 disables the limit"),
+		traceFile:             flags.String("trace-file", "", "Write one JSON line per request to this file for offline analysis"),
+		sinkInfluxURL:         flags.String("sink-influx-url", "", "Push results to this InfluxDB write URL (line protocol)"),
+		sinkInfluxToken:       flags.String("sink-influx-token", "", "InfluxDB API token"),
+		sinkPushgatewayURL:    flags.String("sink-pushgateway-url", "", "Push results to this Prometheus Pushgateway"),
+		sinkWebhookURL:        flags.String("sink-webhook-url", "", "POST results as JSON to this URL"),
+		sinkDir:               flags.String("sink-dir", "", "Write results as timestamped JSON files to this directory"),
+		sinkRetries:           flags.Int("sink-retries", 3, "Extra attempts per result sink after a failure"),
+	}
+}
+
+// transportConfig returns the HTTP transport settings selected on the command line.
+func (opts *commonOptions) transportConfig() api.TransportConfig {
+	return api.TransportConfig{
+		MaxIdleConnsPerHost:   *opts.maxIdleConnsPerHost,
+		HTTPVersion:           *opts.httpVersion,
+		ProxyURL:              *opts.proxyURL,
+		CAFile:                *opts.caFile,
+		ClientCertFile:        *opts.clientCert,
+		ClientKeyFile:         *opts.clientKey,
+		InsecureSkipTLSVerify: *opts.insecureSkipTLSVerify,
+		DialTimeout:           *opts.connectTimeout,
+		TLSHandshakeTimeout:   *opts.tlsHandshakeTimeout,
+		ResponseHeaderTimeout: *opts.responseHeaderTimeout,
+	}
+}
+
+// requestTimeouts returns the per-request deadlines selected on the command line.
+func (opts *commonOptions) requestTimeouts() api.RequestTimeouts {
+	return api.RequestTimeouts{
+		Connect:    *opts.connectTimeout,
+		FirstToken: *opts.ttftTimeout,
+		Idle:       *opts.idleTimeout,
+		Total:      *opts.requestTimeout,
+	}
+}
+
+// newBenchmark connects to the endpoint, discovers the model if needed and
+// measures the prompt size, returning a Benchmark ready to run.
+func (opts *commonOptions) newBenchmark() (Benchmark, error) {
+	benchmark := Benchmark{}
+	benchmark.BaseURL = *opts.baseURL
+	benchmark.ApiKey = *opts.apiKey
+	benchmark.ModelName = *opts.model
+	benchmark.Prompt = *opts.prompt
+	benchmark.NumWords = *opts.numWords
+	benchmark.MaxTokens = *opts.maxTokens
+	benchmark.EstimateRTT = *opts.estimateRTT
+	benchmark.GoodputTargets = utils.GoodputTargets{
+		Ttft: opts.goodputTtft.Seconds(),
+		Tpot: opts.goodputTpot.Seconds(),
+	}
+
+	// Initialize OpenAI client
+	if *opts.baseURL == "" {
+		return benchmark, fmt.Errorf("--base-url is required")
+	}
+	benchmark.Transport = opts.transportConfig()
+	benchmark.Timeouts = opts.requestTimeouts()
+	benchmark.Limiter = utils.NewRateLimiter(utils.RateLimit{
+		RequestsPerMinute: *opts.rateLimitRequests,
+		TokensPerMinute:   *opts.rateLimitTokens,
+	})
+	if benchmark.Transport.InsecureSkipTLSVerify {
+		fmt.Fprintln(os.Stderr, "\n/!\\ WARNING: Skipping TLS certificate verification. This is insecure and should not be used in production. /!\\")
+	}
+
+	client, err := api.NewClient(*opts.baseURL, *opts.apiKey, benchmark.Transport)
+	if err != nil {
+		return benchmark, err
+	}
+
+	// Discover model name if not provided
+	if *opts.model == "" {
+		discoveredModel, err := api.GetFirstAvailableModel(client)
+		if err != nil {
+			return benchmark, fmt.Errorf("error discovering model: %v", err)
+		}
+		benchmark.ModelName = discoveredModel
+	}
+
+	// Determine input parameters
+	if *opts.prompt != defaultPrompt {
+		benchmark.UseRandomInput = false
+	} else if *opts.numWords != 0 {
+		benchmark.UseRandomInput = true
+	} else {
+		benchmark.UseRandomInput = false
+	}
+
+	// Get input tokens
+	if benchmark.UseRandomInput {
+		stats, err := api.AskOpenAiRandomInput(context.Background(), client, benchmark.ModelName, *opts.numWords/4, 4, benchmark.Timeouts, nil)
+		if err != nil {
+			return benchmark, fmt.Errorf("error getting prompt tokens: %v", err)
+		}
+		benchmark.InputTokens = stats.PromptTokens
+	} else {
+		stats, err := api.AskOpenAi(context.Background(), client, benchmark.ModelName, *opts.prompt, 4, benchmark.Timeouts, nil)
+		if err != nil {
+			return benchmark, fmt.Errorf("error getting prompt tokens: %v", err)
+		}
+		benchmark.InputTokens = stats.PromptTokens
+	}
+
+	benchmark.Sinks, err = sinks.New(sinks.Config{
+		InfluxURL:      *opts.sinkInfluxURL,
+		InfluxToken:    *opts.sinkInfluxToken,
+		PushgatewayURL: *opts.sinkPushgatewayURL,
+		WebhookURL:     *opts.sinkWebhookURL,
+		Directory:      *opts.sinkDir,
+		Retries:        *opts.sinkRetries,
+	})
+	if err != nil {
+		return benchmark, err
+	}
+
+	// Records are written unbuffered, the file is closed when the process exits
+	if *opts.traceFile != "" {
+		trace, err := utils.CreateJSONLTraceFile(*opts.traceFile)
+		if err != nil {
+			return benchmark, fmt.Errorf("error creating trace file: %v", err)
+		}
+		benchmark.Trace = trace
+	}
+
+	return benchmark, nil
+}
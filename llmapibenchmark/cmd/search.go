@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"llmapibenchmark/internal/utils"
+
+	"github.com/spf13/pflag"
+)
+
+// runSearchCommand implements `llmapibenchmark search`: it ramps concurrency until the
+// SLO breaks and reports the highest sustainable level.
+func runSearchCommand(args []string) {
+	flags := pflag.NewFlagSet("search", pflag.ExitOnError)
+	opts := bindCommonFlags(flags)
+	maxP95Ttft := flags.Duration("slo-p95-ttft", 0, "Maximum p95 time to first token (e.g. 2s); 0 disables the check")
+	minUserSpeed := flags.Float64("slo-min-user-speed", 0, "Minimum per-user generation speed in tokens/s; 0 disables the check")
+	maxErrorRate := flags.Float64("slo-max-error-rate", 0.01, "Maximum fraction of failed requests (0.01 = 1%)")
+	startConcurrency := flags.Int("start-concurrency", 1, "Concurrency level the search starts from")
+	maxConcurrency := flags.Int("max-concurrency", 256, "Highest concurrency level the search may try")
+	format := flags.StringP("format", "f", "", "Output format (optional)")
+	help := flags.BoolP("help", "h", false, "Show this help message")
+	flags.Parse(args)
+
+	if *help {
+		fmt.Printf("Usage of %s search:\n", os.Args[0])
+		flags.PrintDefaults()
+		os.Exit(0)
+	}
+	if *maxP95Ttft == 0 && *minUserSpeed == 0 && *maxErrorRate >= 1 {
+		log.Fatalf("At least one SLO constraint is required (--slo-p95-ttft, --slo-min-user-speed or --slo-max-error-rate)")
+	}
+
+	benchmark, err := opts.newBenchmark()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	benchmark.TolerateErrors = true
+
+	measurement := benchmark.describeMeasurement(context.Background())
+
+	interactive := *format == ""
+	if interactive {
+		utils.PrintBenchmarkHeader(benchmark.ModelName, benchmark.InputTokens, benchmark.MaxTokens, measurement.NetworkRttMs)
+		fmt.Println("| Phase  | Concurrency | Generation Throughput (tokens/s) | P95 TTFT (s) | Per-User Speed (tokens/s) | Error Rate | Result |")
+		fmt.Println("|--------|-------------|----------------------------------|--------------|---------------------------|------------|--------|")
+	}
+
+	search := utils.SaturationSearch{
+		SLO: utils.SLO{
+			MaxP95Ttft:      maxP95Ttft.Seconds(),
+			MinPerUserSpeed: *minUserSpeed,
+			MaxErrorRate:    *maxErrorRate,
+		},
+		StartConcurrency: *startConcurrency,
+		MaxConcurrency:   *maxConcurrency,
+		Measure: func(ctx context.Context, concurrency int) (utils.SpeedResult, error) {
+			return benchmark.measureSpeed(ctx, concurrency, interactive)
+		},
+	}
+	if interactive {
+		search.OnStep = printSearchStep
+	}
+
+	saturation, err := search.Run(context.Background())
+	if err != nil {
+		log.Fatalf("Error running search: %v", err)
+	}
+
+	result := SearchResult{
+		ModelName:        benchmark.ModelName,
+		InputTokens:      benchmark.InputTokens,
+		MaxTokens:        benchmark.MaxTokens,
+		Measurement:      measurement,
+		SaturationResult: saturation,
+	}
+	steps := make([]utils.SpeedResult, len(saturation.Steps))
+	for i, step := range saturation.Steps {
+		steps[i] = step.Result
+		steps[i].Concurrency = step.Concurrency
+	}
+	benchmark.publishResult("search", steps, result)
+
+	if interactive {
+		printSearchSummary(result)
+		return
+	}
+
+	var output string
+	switch *format {
+	case "json":
+		output, err = result.Json()
+	case "yaml":
+		output, err = result.Yaml()
+	default:
+		log.Printf("Invalid format specified")
+	}
+	if err != nil {
+		log.Fatalf("Error formatting search result: %v", err)
+	}
+	fmt.Println(output)
+}
+
+func printSearchStep(step utils.SaturationStep) {
+	verdict := "pass"
+	if !step.Passed {
+		verdict = "fail"
+	}
+	fmt.Printf("| %-6s | %11d | %32.2f | %12.2f | %25.2f | %9.2f%% | %-6s |\n",
+		step.Phase,
+		step.Concurrency,
+		step.Result.GenerationSpeed,
+		step.Result.P95Ttft,
+		step.Result.PerUserSpeed,
+		step.Result.ErrorRate*100,
+		verdict,
+	)
+	if len(step.Violations) > 0 {
+		fmt.Fprintf(os.Stderr, "  concurrency %d: %s\n", step.Concurrency, strings.Join(step.Violations, "; "))
+	}
+}
+
+func printSearchSummary(result SearchResult) {
+	fmt.Println("\n================================================================================================================")
+	if result.MaxSustainableConcurrency == 0 {
+		fmt.Println("No concurrency level met the SLO.")
+		return
+	}
+	fmt.Printf("Max sustainable concurrency: %d", result.MaxSustainableConcurrency)
+	if result.LimitReached {
+		fmt.Printf(" (search limit reached, capacity may be higher)")
+	}
+	fmt.Printf("\nGoodput: %.2f tokens/s\n", result.Goodput)
+	if result.Best != nil {
+		fmt.Printf("P95 TTFT: %.2f s, per-user speed: %.2f tokens/s, error rate: %.2f%%\n",
+			result.Best.P95Ttft, result.Best.PerUserSpeed, result.Best.ErrorRate*100)
+	}
+}
@@ -10,12 +10,23 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"llmapibenchmark/internal/tracing"
 	"llmapibenchmark/server"
 )
 
 func Run() error {
 	// Initialize structured logger first
 	server.AppLogger = server.NewLogger()
+
+	// Export OpenTelemetry spans when an OTLP endpoint is configured
+	tracingConfig := tracing.ConfigFromEnv()
+	shutdownTracing, err := tracing.Setup(tracingConfig)
+	if err != nil {
+		return fmt.Errorf("failed to configure tracing: %w", err)
+	}
+	if tracing.Enabled() {
+		server.AppLogger.Info("Tracing enabled, exporting spans to %s", tracingConfig.Endpoint)
+	}
 	
 	// Set Gin mode based on environment
 	if os.Getenv("GIN_MODE") == "" {
@@ -28,6 +39,9 @@ func Run() error {
 	// Setup routes with SSE approach
 	server.SetupRoutes(router)
 
+	// Start recurring benchmarks
+	stopSchedules := server.GetScheduleRunner().Start()
+
 	// Get port from environment or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -49,7 +63,7 @@ func Run() error {
 		server.AppLogger.Info("API endpoints available at http://localhost:%s/api", port)
 		server.AppLogger.Info("UI available at http://localhost:%s/ui", port)
 		server.AppLogger.Info("WebSocket endpoint available at ws://localhost:%s/ws", port)
-		server.AppLogger.Info("Async benchmark endpoint: POST /api/benchmark/async", port)
+		server.AppLogger.Info("Async benchmark endpoint: POST /api/benchmark/async")
 		
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			server.AppLogger.Fatal("Failed to start server: %v", err)
@@ -67,6 +81,12 @@ func Run() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	stopSchedules()
+
+	if err := shutdownTracing(ctx); err != nil {
+		server.AppLogger.Warn("Failed to export remaining spans: %v", err)
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		server.AppLogger.Error("Server forced to shutdown: %v", err)
 		return fmt.Errorf("server forced to shutdown: %w", err)
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"llmapibenchmark/internal/sinks"
+	"llmapibenchmark/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// publishResult pushes a completed run to the configured result sinks. Failures are
+// logged by the dispatcher and do not fail the run.
+func (benchmark *Benchmark) publishResult(resultType string, results []utils.SpeedResult, payload any) {
+	if benchmark.Sinks == nil {
+		return
+	}
+	result := sinks.Result{
+		ID:        uuid.New().String(),
+		Type:      resultType,
+		Source:    "cli",
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+	for _, level := range results {
+		result.Levels = append(result.Levels, sinks.Level{
+			Model:       benchmark.ModelName,
+			Concurrency: level.Concurrency,
+			Metrics:     sinks.SpeedMetrics(level),
+		})
+	}
+	benchmark.Sinks.Publish(context.Background(), result)
+}
@@ -1,6 +1,12 @@
 package main
 
-import "llmapibenchmark/internal/utils"
+import (
+	"time"
+
+	"llmapibenchmark/internal/api"
+	"llmapibenchmark/internal/sinks"
+	"llmapibenchmark/internal/utils"
+)
 
 type Benchmark struct {
 	BaseURL           string
@@ -12,12 +18,30 @@ type Benchmark struct {
 	ConcurrencyLevels []int
 	UseRandomInput    bool
 	NumWords          int
+	Runs              int           // Repetitions per concurrency level
+	Cooldown          time.Duration // Pause between repeated runs
+	TolerateErrors    bool          // Record failed requests in the error rate instead of aborting
+	GoodputTargets    utils.GoodputTargets
+	EstimateRTT       bool // Add a network round-trip estimate to the results
+	Transport         api.TransportConfig
+	Timeouts          api.RequestTimeouts
+	Trace             utils.TraceSink    // Optional, receives one record per request
+	Limiter           *utils.RateLimiter // Optional, spaces out requests
+	Sinks             *sinks.Dispatcher  // Optional, receives completed results
 }
 
 type BenchmarkResult struct {
-	ModelName   string              `json:"model_name" yaml:"model-name"`
-	InputTokens int                 `json:"input_tokens" yaml:"input-tokens"`
-	MaxTokens   int                 `json:"output_tokens" yaml:"output-tokens"` // Historically been called Output Tokens
-	Latency     float64             `json:"latency" yaml:"latency"`
-	Results     []utils.SpeedResult `json:"results" yaml:"results"`
+	ModelName   string                 `json:"model_name" yaml:"model-name"`
+	InputTokens int                    `json:"input_tokens" yaml:"input-tokens"`
+	MaxTokens   int                    `json:"output_tokens" yaml:"output-tokens"` // Historically been called Output Tokens
+	Results     []utils.SpeedResult    `json:"results" yaml:"results"`
+	Measurement utils.MeasurementModel `json:"measurement" yaml:"measurement"`
+}
+
+type SearchResult struct {
+	ModelName              string                 `json:"model_name" yaml:"model-name"`
+	InputTokens            int                    `json:"input_tokens" yaml:"input-tokens"`
+	MaxTokens              int                    `json:"output_tokens" yaml:"output-tokens"`
+	Measurement            utils.MeasurementModel `json:"measurement" yaml:"measurement"`
+	utils.SaturationResult `yaml:",inline"`
 }
@@ -6,25 +6,102 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http/httptrace"
 	"strings"
 	"time"
 
+	"llmapibenchmark/internal/tracing"
+
 	"github.com/sashabaranov/go-openai"
 	"github.com/schollz/progressbar/v3"
 )
 
+// RequestStats holds the timing and token counts of one streamed chat completion.
+// All durations are measured from SentAt, the moment the connection was acquired and
+// the request started to be written, so DNS, connect and TLS setup are excluded.
+type RequestStats struct {
+	SentAt           time.Time
+	HeadersAt        time.Time // First byte of the response headers
+	FirstChunkAt     time.Time // First stream chunk, with or without content; DoneAt when there was none
+	FirstTokenAt     time.Time // First chunk with non-blank content; DoneAt when there was none
+	DoneAt           time.Time // Last chunk received
+	CompletionTokens int
+	PromptTokens     int
+	ChunkTimes       []time.Time // Arrival of every chunk with content
+	FinishReason     string
+	HTTPStatus       int // 200 once the stream was accepted, the error status otherwise; 0 when unknown
+
+	// Connection setup before SentAt; all zero when ConnReused is set
+	ConnReused bool
+	DNS        time.Duration
+	Connect    time.Duration
+	TLS        time.Duration
+}
+
+// Ttft returns the time to first token in seconds.
+func (stats RequestStats) Ttft() float64 {
+	return stats.FirstTokenAt.Sub(stats.SentAt).Seconds()
+}
+
+// Duration returns the time from sending the request to the last chunk in seconds.
+func (stats RequestStats) Duration() float64 {
+	return stats.DoneAt.Sub(stats.SentAt).Seconds()
+}
+
 // AskOpenAi sends a prompt to the OpenAI API, processes the response stream and returns stats on it.
-func AskOpenAi(ctx context.Context, client *openai.Client, model string, prompt string, maxTokens int, bar *progressbar.ProgressBar) (float64, int, int, error) {
+// A request that exceeds one of the timeouts is aborted with a *TimeoutError. The stats
+// are filled in as far as the request got, also when an error is returned.
+func AskOpenAi(ctx context.Context, client *openai.Client, model string, prompt string, maxTokens int, timeouts RequestTimeouts, bar *progressbar.ProgressBar) (stats RequestStats, err error) {
+	ctx, span := tracing.StartKind(ctx, "chat completion", tracing.KindClient,
+		tracing.String("gen_ai.request.model", model),
+		tracing.Int("gen_ai.request.max_tokens", maxTokens))
+	ctx, dog := startWatchdog(ctx, timeouts)
+	defer dog.stop()
+
+	// Time the request from the moment it is written on an established connection and
+	// record the connection phases before that
+	trace := &phaseTrace{dog: dog}
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
 	start := time.Now()
 
 	var (
-		timeToFirstToken   float64
+		headersAt          time.Time
+		firstChunkAt       time.Time
+		firstTokenAt       time.Time
+		doneAt             time.Time
 		firstTokenSeen     bool
 		lastUsage          *openai.Usage
 		accumulatedContent string // Accumulate all content to count tokens more accurately
 		estimatedTokens    int    // Real-time token estimation
 	)
 
+	// Complete the timeline on every return, so failed requests can be traced too
+	defer func() {
+		stats.DoneAt = doneAt
+		if doneAt.IsZero() {
+			stats.DoneAt = time.Now()
+		}
+		trace.apply(&stats)
+		if stats.SentAt.IsZero() {
+			stats.SentAt = start // Transport without trace support
+		}
+		if stats.HeadersAt.IsZero() {
+			stats.HeadersAt = headersAt
+		}
+		stats.FirstChunkAt = firstChunkAt
+		if firstChunkAt.IsZero() {
+			stats.FirstChunkAt = stats.DoneAt
+		}
+		stats.FirstTokenAt = firstTokenAt
+		if !firstTokenSeen {
+			stats.FirstTokenAt = stats.DoneAt
+		}
+		if status := httpStatus(err); status > 0 {
+			stats.HTTPStatus = status
+		}
+		endRequestSpan(span, stats, firstTokenSeen, err)
+	}()
+
 	log.Printf("🔌 Creating chat completion stream for model: %s", model)
 	
 	// Debug: Check if this is a complex model ID and extract the actual model name
@@ -57,9 +134,14 @@ func AskOpenAi(ctx context.Context, client *openai.Client, model string, prompt
 		},
 	)
 	if err != nil {
+		if timeout := timeoutCause(ctx); timeout != nil {
+			err = timeout
+		}
 		log.Printf("❌ OpenAI API request failed: %v", err)
-		return 0, 0, 0, fmt.Errorf("OpenAI API request failed: %w", err)
+		return stats, fmt.Errorf("OpenAI API request failed: %w", err)
 	}
+	headersAt = time.Now()
+	stats.HTTPStatus = 200
 	log.Printf("✅ Chat completion stream created successfully")
 	defer stream.Close()
 
@@ -67,31 +149,50 @@ func AskOpenAi(ctx context.Context, client *openai.Client, model string, prompt
 		// Check for context cancellation before receiving
 		select {
 		case <-ctx.Done():
+			if timeout := timeoutCause(ctx); timeout != nil {
+				log.Printf("⏱️ Request to model %s aborted: %v", model, timeout)
+				return stats, timeout
+			}
 			log.Printf("🛑 Context cancelled during streaming for model: %s", model)
-			return 0, 0, 0, ctx.Err()
+			return stats, ctx.Err()
 		default:
 		}
 		
 		resp, err := stream.Recv()
 		if errors.Is(err, io.EOF) {
+			doneAt = time.Now()
 			break
 		}
 		if err != nil {
-			return 0, 0, 0, fmt.Errorf("stream error: %w", err)
+			if timeout := timeoutCause(ctx); timeout != nil {
+				err = timeout
+			}
+			return stats, fmt.Errorf("stream error: %w", err)
+		}
+		receivedAt := time.Now()
+		dog.gotChunk()
+
+		if firstChunkAt.IsZero() {
+			firstChunkAt = receivedAt
 		}
 
 		if !firstTokenSeen && len(resp.Choices) > 0 {
 			content := resp.Choices[0].Delta.Content
 			if strings.TrimSpace(content) != "" {
-				timeToFirstToken = time.Since(start).Seconds()
+				firstTokenAt = receivedAt
 				firstTokenSeen = true
+				dog.gotFirstToken()
 			}
 		}
 
 		// Process each chunk, accumulating to response content
 		if len(resp.Choices) > 0 {
+			if reason := resp.Choices[0].FinishReason; reason != "" {
+				stats.FinishReason = string(reason)
+			}
 			content := resp.Choices[0].Delta.Content
 			if content != "" {
+				stats.ChunkTimes = append(stats.ChunkTimes, receivedAt)
 				accumulatedContent += content
 
 				// Estimate number of tokens in current chunk
@@ -126,12 +227,39 @@ func AskOpenAi(ctx context.Context, client *openai.Client, model string, prompt
 		completionTokens = estimatedTokens
 	}
 
-	return timeToFirstToken, completionTokens, promptTokens, nil
+	stats.CompletionTokens = completionTokens
+	stats.PromptTokens = promptTokens
+	return stats, nil
+}
+
+// endRequestSpan records the outcome of a request on its span and ends it.
+func endRequestSpan(span *tracing.Span, stats RequestStats, firstTokenSeen bool, err error) {
+	if span == nil {
+		return
+	}
+	span.SetAttributes(
+		tracing.Int("http.response.status_code", stats.HTTPStatus),
+		tracing.Int("gen_ai.usage.input_tokens", stats.PromptTokens),
+		tracing.Int("gen_ai.usage.output_tokens", stats.CompletionTokens),
+		tracing.Bool("llm.connection_reused", stats.ConnReused),
+		tracing.Float("llm.duration_ms", float64(stats.DoneAt.Sub(stats.SentAt).Microseconds())/1000),
+	)
+	if firstTokenSeen {
+		span.SetAttributes(tracing.Float("llm.ttft_ms", float64(stats.FirstTokenAt.Sub(stats.SentAt).Microseconds())/1000))
+	}
+	if stats.FinishReason != "" {
+		span.SetAttributes(tracing.String("gen_ai.response.finish_reason", stats.FinishReason))
+	}
+	if err != nil {
+		span.SetAttributes(tracing.String("error.type", ClassifyError(err)))
+		span.RecordError(err)
+	}
+	span.End()
 }
 
-func AskOpenAiRandomInput(ctx context.Context, client *openai.Client, model string, numWords int, maxTokens int, bar *progressbar.ProgressBar) (float64, int, int, error) {
+func AskOpenAiRandomInput(ctx context.Context, client *openai.Client, model string, numWords int, maxTokens int, timeouts RequestTimeouts, bar *progressbar.ProgressBar) (RequestStats, error) {
 	prompt := generateRandomPhrase(numWords)
-	return AskOpenAi(ctx, client, model, prompt, maxTokens, bar)
+	return AskOpenAi(ctx, client, model, prompt, maxTokens, timeouts, bar)
 }
 
 func estimateTokens(content string) int {
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// RequestTimeouts bounds the phases of a single streamed request. Zero disables a limit.
+type RequestTimeouts struct {
+	Connect    time.Duration // Acquiring a connection: DNS, TCP and TLS, or waiting for a pooled one
+	FirstToken time.Duration // From sending the request to the first content chunk
+	Idle       time.Duration // Between two stream chunks once streaming has started
+	Total      time.Duration // The whole request, from start to the last chunk
+}
+
+// DefaultRequestTimeouts keeps a hung endpoint from stalling a benchmark while leaving
+// room for slow prefill at high concurrency.
+var DefaultRequestTimeouts = RequestTimeouts{
+	Connect:    30 * time.Second,
+	FirstToken: 5 * time.Minute,
+	Idle:       2 * time.Minute,
+}
+
+// Timeout phases reported by TimeoutError
+const (
+	PhaseConnect    = "connect"
+	PhaseFirstToken = "ttft"
+	PhaseIdle       = "idle"
+	PhaseTotal      = "total"
+)
+
+// TimeoutError reports that a request exceeded one of its RequestTimeouts.
+type TimeoutError struct {
+	Phase string
+	Limit time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timeout after %s", e.Phase, e.Limit)
+}
+
+// watchdog cancels a request's context when one of its deadlines passes. The timers are
+// driven from the request goroutine and from httptrace callbacks.
+type watchdog struct {
+	timeouts RequestTimeouts
+	cancel   context.CancelCauseFunc
+
+	mu         sync.Mutex
+	connect    *time.Timer
+	firstToken *time.Timer
+	idle       *time.Timer
+	total      *time.Timer
+}
+
+// startWatchdog derives a context that is cancelled with a *TimeoutError cause when a
+// deadline passes. stop must be called once the request is finished.
+func startWatchdog(parent context.Context, timeouts RequestTimeouts) (context.Context, *watchdog) {
+	ctx, cancel := context.WithCancelCause(parent)
+	dog := &watchdog{timeouts: timeouts, cancel: cancel}
+	dog.connect = dog.arm(PhaseConnect, timeouts.Connect)
+	dog.total = dog.arm(PhaseTotal, timeouts.Total)
+	return ctx, dog
+}
+
+func (dog *watchdog) arm(phase string, limit time.Duration) *time.Timer {
+	if limit <= 0 {
+		return nil
+	}
+	return time.AfterFunc(limit, func() {
+		dog.cancel(&TimeoutError{Phase: phase, Limit: limit})
+	})
+}
+
+// connected stops the connect deadline and starts the wait for the first token.
+func (dog *watchdog) connected() {
+	dog.mu.Lock()
+	defer dog.mu.Unlock()
+	stopTimer(dog.connect)
+	if dog.firstToken == nil {
+		dog.firstToken = dog.arm(PhaseFirstToken, dog.timeouts.FirstToken)
+	}
+}
+
+// gotChunk restarts the idle deadline after every received chunk.
+func (dog *watchdog) gotChunk() {
+	dog.mu.Lock()
+	defer dog.mu.Unlock()
+	if dog.idle == nil {
+		dog.idle = dog.arm(PhaseIdle, dog.timeouts.Idle)
+	} else {
+		dog.idle.Reset(dog.timeouts.Idle)
+	}
+}
+
+// gotFirstToken stops the first token deadline.
+func (dog *watchdog) gotFirstToken() {
+	dog.mu.Lock()
+	defer dog.mu.Unlock()
+	stopTimer(dog.firstToken)
+}
+
+// stop disarms every deadline and releases the context.
+func (dog *watchdog) stop() {
+	dog.mu.Lock()
+	defer dog.mu.Unlock()
+	for _, timer := range []*time.Timer{dog.connect, dog.firstToken, dog.idle, dog.total} {
+		stopTimer(timer)
+	}
+	dog.cancel(nil)
+}
+
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// timeoutCause returns the *TimeoutError that cancelled ctx, or nil.
+func timeoutCause(ctx context.Context) error {
+	var timeout *TimeoutError
+	if errors.As(context.Cause(ctx), &timeout) {
+		return timeout
+	}
+	return nil
+}
+
+// Error classes reported by ClassifyError besides the "<phase>_timeout" and "http_<status>" forms
+const (
+	ErrorClassCanceled   = "canceled"
+	ErrorClassConnection = "connection"
+	ErrorClassStream     = "stream"
+	ErrorClassOther      = "other"
+)
+
+// ClassifyError maps a request error to a short class such as "ttft_timeout", "http_429"
+// or "connection", used to count failures by cause.
+func ClassifyError(err error) string {
+	var timeout *TimeoutError
+	if errors.As(err, &timeout) {
+		return timeout.Phase + "_timeout"
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassCanceled
+	}
+
+	if status := httpStatus(err); status > 0 {
+		return fmt.Sprintf("http_%d", status)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorClassConnection
+	}
+	if errors.Is(err, openai.ErrTooManyEmptyStreamMessages) {
+		return ErrorClassStream
+	}
+	return ErrorClassOther
+}
+
+// httpStatus returns the HTTP status carried by an API error, or 0.
+func httpStatus(err error) int {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.HTTPStatusCode
+	}
+	return 0
+}
+
+// IsTimeout reports whether err was caused by one of the RequestTimeouts.
+func IsTimeout(err error) bool {
+	var timeout *TimeoutError
+	return errors.As(err, &timeout)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// phaseTrace records connection-phase timestamps of a single request. Dial callbacks
+// may run on a different goroutine than the request, so access is guarded by a mutex.
+type phaseTrace struct {
+	mu        sync.Mutex
+	dnsStart  time.Time
+	dns       time.Duration
+	connStart time.Time
+	connect   time.Duration
+	tlsStart  time.Time
+	tls       time.Duration
+	reused    bool
+	gotConnAt time.Time
+	headersAt time.Time
+	dog       *watchdog // Optional, told when the connection is ready
+}
+
+func (trace *phaseTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			trace.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			if !trace.dnsStart.IsZero() {
+				trace.dns = time.Since(trace.dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			trace.connStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			// With several addresses only the successful dial counts
+			if err == nil && !trace.connStart.IsZero() {
+				trace.connect = time.Since(trace.connStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			trace.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			if err == nil && !trace.tlsStart.IsZero() {
+				trace.tls = time.Since(trace.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			trace.gotConnAt = time.Now()
+			trace.reused = info.Reused
+			if trace.dog != nil {
+				trace.dog.connected()
+			}
+		},
+		GotFirstResponseByte: func() {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			trace.headersAt = time.Now()
+		},
+	}
+}
+
+// apply copies the recorded phases into stats. Setup phases are left at zero for reused
+// connections, where any dial that was started belonged to a request that never used it.
+func (trace *phaseTrace) apply(stats *RequestStats) {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	stats.SentAt = trace.gotConnAt
+	stats.HeadersAt = trace.headersAt
+	stats.ConnReused = trace.reused
+	if !trace.reused {
+		stats.DNS = trace.dns
+		stats.Connect = trace.connect
+		stats.TLS = trace.tls
+	}
+}
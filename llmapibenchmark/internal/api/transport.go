@@ -0,0 +1,183 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"llmapibenchmark/internal/tracing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Supported values of TransportConfig.HTTPVersion
+const (
+	HTTPVersionAuto = ""    // HTTP/2 when the server offers it over TLS, else HTTP/1.1
+	HTTPVersion1    = "1.1" // HTTP/1.1 only, one stream per connection
+	HTTPVersion2    = "2"   // HTTP/2 only, including prior-knowledge HTTP/2 over plain http://
+)
+
+// DefaultMaxIdleConnsPerHost keeps enough idle connections for the highest concurrency
+// levels, so later requests and levels reuse connections instead of dialing again.
+const DefaultMaxIdleConnsPerHost = 256
+
+// TransportConfig describes the HTTP transport used to reach a model endpoint.
+// The zero value gives a pooled transport with Go's default TLS and proxy settings.
+type TransportConfig struct {
+	MaxIdleConnsPerHost   int    // 0 uses DefaultMaxIdleConnsPerHost
+	HTTPVersion           string // HTTPVersionAuto, HTTPVersion1 or HTTPVersion2
+	ProxyURL              string // Empty uses HTTP_PROXY/HTTPS_PROXY/NO_PROXY from the environment
+	CAFile                string // PEM bundle trusted in addition to the system roots
+	ClientCertFile        string // PEM client certificate for mTLS, requires ClientKeyFile
+	ClientKeyFile         string
+	InsecureSkipTLSVerify bool
+	DialTimeout           time.Duration // 0 uses 30s
+	TLSHandshakeTimeout   time.Duration // 0 uses 10s
+	ResponseHeaderTimeout time.Duration // 0 waits as long as the request context allows
+	IdleConnTimeout       time.Duration // 0 uses 90s
+}
+
+// Validate checks the settings that can be checked without touching the filesystem.
+func (config TransportConfig) Validate() error {
+	switch config.HTTPVersion {
+	case HTTPVersionAuto, HTTPVersion1, HTTPVersion2:
+	default:
+		return fmt.Errorf("unsupported HTTP version %q (use \"1.1\" or \"2\")", config.HTTPVersion)
+	}
+	if config.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("max idle connections per host must not be negative, got %d", config.MaxIdleConnsPerHost)
+	}
+	if (config.ClientCertFile == "") != (config.ClientKeyFile == "") {
+		return fmt.Errorf("client certificate and key must be set together")
+	}
+	if config.ProxyURL != "" {
+		if _, err := url.Parse(config.ProxyURL); err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+	}
+	if config.DialTimeout < 0 || config.TLSHandshakeTimeout < 0 || config.ResponseHeaderTimeout < 0 || config.IdleConnTimeout < 0 {
+		return fmt.Errorf("transport timeouts must not be negative")
+	}
+	return nil
+}
+
+// NewTransport builds an http.Transport from the configuration.
+func NewTransport(config TransportConfig) (*http.Transport, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipTLSVerify}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		proxyURL, _ := url.Parse(config.ProxyURL)
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   orDefault(config.DialTimeout, 30*time.Second),
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   orDefault(config.TLSHandshakeTimeout, 10*time.Second),
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		IdleConnTimeout:       orDefault(config.IdleConnTimeout, 90*time.Second),
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          0, // No global cap, the per-host limit applies
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		ForceAttemptHTTP2:     true,
+	}
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+
+	switch config.HTTPVersion {
+	case HTTPVersion1:
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP1(true)
+	case HTTPVersion2:
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return transport, nil
+}
+
+func orDefault(value time.Duration, fallback time.Duration) time.Duration {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
+
+// TransportFactory hands out one shared transport per distinct configuration, so every
+// client created for the same endpoint settings draws from the same connection pool.
+type TransportFactory struct {
+	mu         sync.Mutex
+	transports map[TransportConfig]*http.Transport
+}
+
+// SharedTransports is the process-wide factory used by NewClient.
+var SharedTransports = &TransportFactory{}
+
+// Transport returns the shared transport for the configuration, building it on first use.
+func (factory *TransportFactory) Transport(config TransportConfig) (*http.Transport, error) {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+
+	if transport, ok := factory.transports[config]; ok {
+		return transport, nil
+	}
+	transport, err := NewTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	if factory.transports == nil {
+		factory.transports = make(map[TransportConfig]*http.Transport)
+	}
+	factory.transports[config] = transport
+	return transport, nil
+}
+
+// NewClient creates an OpenAI client for baseURL that uses the shared transport for config.
+func NewClient(baseURL string, apiKey string, config TransportConfig) (*openai.Client, error) {
+	transport, err := SharedTransports.Transport(config)
+	if err != nil {
+		return nil, fmt.Errorf("configuring HTTP transport: %w", err)
+	}
+
+	clientConfig := openai.DefaultConfig(apiKey)
+	clientConfig.BaseURL = baseURL
+	clientConfig.HTTPClient = &http.Client{Transport: tracing.Transport(transport)} // Propagates traceparent
+	return openai.NewClientWithConfig(clientConfig), nil
+}
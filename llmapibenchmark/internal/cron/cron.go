@@ -0,0 +1,163 @@
+// Package cron parses standard five-field cron expressions and computes their run times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. Each field is a bit set of the allowed values.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // "*" in the day fields, see Next
+	location                      *time.Location
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// Parse parses "minute hour day-of-month month day-of-week" or one of the @yearly,
+// @monthly, @weekly, @daily and @hourly macros. Fields accept *, lists, ranges, steps
+// and month or day names; day of week 7 is Sunday. Times are evaluated in location,
+// UTC when nil.
+func Parse(expression string, location *time.Location) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if macro, ok := macros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expression, len(fields))
+	}
+	if location == nil {
+		location = time.UTC
+	}
+
+	schedule := &Schedule{location: location}
+	var err error
+	if schedule.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if schedule.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if schedule.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if schedule.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if schedule.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1 // 7 is Sunday as well
+	}
+	schedule.domAny = fields[2] == "*" || fields[2] == "?"
+	schedule.dowAny = fields[4] == "*" || fields[4] == "?"
+	return schedule, nil
+}
+
+// parseField parses a comma-separated list of values, ranges and steps into a bit set
+func parseField(field string, min int, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if slash := strings.Index(part, "/"); slash >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[slash+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:slash]
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := parseValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			low, high = value, value
+			if strings.Contains(part, "/") {
+				high = max // "5/15" means from 5 to the end in steps of 15
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, names map[string]int) (int, error) {
+	if named, ok := names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return number, nil
+}
+
+// Next returns the first run time strictly after t, or the zero time when the
+// expression never matches (e.g. February 30th). As in Vixie cron, a restricted day of
+// month and day of week match when either does.
+func (schedule *Schedule) Next(t time.Time) time.Time {
+	next := t.In(schedule.location).Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		switch {
+		case schedule.month&(1<<uint(next.Month())) == 0:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, schedule.location)
+		case !schedule.dayMatches(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, schedule.location)
+		case schedule.hour&(1<<uint(next.Hour())) == 0:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, schedule.location)
+		case schedule.minute&(1<<uint(next.Minute())) == 0:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+func (schedule *Schedule) dayMatches(t time.Time) bool {
+	domMatch := schedule.dom&(1<<uint(t.Day())) != 0
+	dowMatch := schedule.dow&(1<<uint(t.Weekday())) != 0
+	if schedule.domAny || schedule.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
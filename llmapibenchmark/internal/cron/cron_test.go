@@ -0,0 +1,47 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	from := time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC) // A Friday
+	for _, test := range []struct {
+		expression string
+		location   *time.Location
+		want       time.Time
+	}{
+		{"@daily", nil, time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", nil, time.Date(2025, 3, 14, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", nil, time.Date(2025, 3, 15, 10, 30, 0, 0, time.UTC)}, // Strictly after
+		{"0 2 * * mon-wed", nil, time.Date(2025, 3, 17, 2, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", nil, time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 1", nil, time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)}, // Day of month or Monday
+		{"0 6 * feb 7", nil, time.Date(2026, 2, 1, 6, 0, 0, 0, time.UTC)},
+		{"5/20 3 * * *", nil, time.Date(2025, 3, 15, 3, 5, 0, 0, time.UTC)},
+		{"0 3 * * *", berlin, time.Date(2025, 3, 15, 2, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", nil, time.Time{}},
+	} {
+		schedule, err := Parse(test.expression, test.location)
+		if err != nil {
+			t.Errorf("%s: %v", test.expression, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(test.want) {
+			t.Errorf("%s: expected %v, got %v", test.expression, test.want, got)
+		}
+	}
+}
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "0 0 * foo *", "5-1 * * * *"} {
+		if _, err := Parse(expression, nil); err == nil {
+			t.Errorf("expected %q to be rejected", expression)
+		}
+	}
+}
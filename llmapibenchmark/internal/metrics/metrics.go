@@ -0,0 +1,378 @@
+// Package metrics implements the counters, gauges and histograms the benchmark exposes
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is a metric family that can write itself in the text format
+type collector interface {
+	name() string
+	write(w io.Writer) error
+}
+
+// Registry holds metric families and renders them for scraping.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+// Default is the registry the benchmark metrics are registered on.
+var Default = NewRegistry()
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.collectors[c.name()]; exists {
+		panic(fmt.Sprintf("metrics: %s registered twice", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// WriteText writes every metric family in the Prometheus text format, sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registry for Prometheus scrapes.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// family holds the label names and the children of a labelled metric
+type family[T any] struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+	newChild   func() *T
+
+	mu       sync.Mutex
+	children map[string]*T
+	values   map[string][]string
+}
+
+func (f *family[T]) name() string { return f.metricName }
+
+// with returns the child for the label values, creating it on first use
+func (f *family[T]) with(values []string) *T {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	child, exists := f.children[key]
+	if !exists {
+		child = f.newChild()
+		f.children[key] = child
+		f.values[key] = append([]string(nil), values...)
+	}
+	return child
+}
+
+// each calls fn for every child in label order
+func (f *family[T]) each(fn func(labels string, child *T) error) error {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.children))
+	for key := range f.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]*T, len(keys))
+	labels := make([]string, len(keys))
+	for i, key := range keys {
+		children[i] = f.children[key]
+		labels[i] = formatLabels(f.labels, f.values[key])
+	}
+	f.mu.Unlock()
+
+	for i := range keys {
+		if err := fn(labels[i], children[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *family[T]) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.metricName, escapeHelp(f.help), f.metricName, f.kind)
+	return err
+}
+
+func newFamily[T any](name, help, kind string, labels []string, newChild func() *T) *family[T] {
+	return &family[T]{
+		metricName: name,
+		help:       help,
+		kind:       kind,
+		labels:     labels,
+		newChild:   newChild,
+		children:   make(map[string]*T),
+		values:     make(map[string][]string),
+	}
+}
+
+// value is a float64 guarded by a mutex
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) add(delta float64) {
+	v.mu.Lock()
+	v.v += delta
+	v.mu.Unlock()
+}
+
+func (v *value) set(x float64) {
+	v.mu.Lock()
+	v.v = x
+	v.mu.Unlock()
+}
+
+func (v *value) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+// Counter is a value that only goes up.
+type Counter struct{ value }
+
+// Inc adds one.
+func (c *Counter) Inc() { c.add(1) }
+
+// Add adds delta, which must not be negative.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.add(delta)
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct{ *family[Counter] }
+
+// NewCounterVec registers a counter family on the registry.
+func NewCounterVec(registry *Registry, name, help string, labels ...string) *CounterVec {
+	vec := &CounterVec{newFamily(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	registry.register(vec)
+	return vec
+}
+
+// With returns the counter for the label values.
+func (vec *CounterVec) With(values ...string) *Counter { return vec.with(values) }
+
+func (vec *CounterVec) write(w io.Writer) error {
+	if err := vec.writeHeader(w); err != nil {
+		return err
+	}
+	return vec.each(func(labels string, c *Counter) error {
+		_, err := fmt.Fprintf(w, "%s%s %s\n", vec.metricName, labels, formatFloat(c.get()))
+		return err
+	})
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct{ value }
+
+// Inc adds one.
+func (g *Gauge) Inc() { g.add(1) }
+
+// Dec subtracts one.
+func (g *Gauge) Dec() { g.add(-1) }
+
+// Set replaces the value.
+func (g *Gauge) Set(x float64) { g.set(x) }
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct{ *family[Gauge] }
+
+// NewGaugeVec registers a gauge family on the registry.
+func NewGaugeVec(registry *Registry, name, help string, labels ...string) *GaugeVec {
+	vec := &GaugeVec{newFamily(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	registry.register(vec)
+	return vec
+}
+
+// With returns the gauge for the label values.
+func (vec *GaugeVec) With(values ...string) *Gauge { return vec.with(values) }
+
+func (vec *GaugeVec) write(w io.Writer) error {
+	if err := vec.writeHeader(w); err != nil {
+		return err
+	}
+	return vec.each(func(labels string, g *Gauge) error {
+		_, err := fmt.Fprintf(w, "%s%s %s\n", vec.metricName, labels, formatFloat(g.get()))
+		return err
+	})
+}
+
+// gaugeFunc is a gauge family computed at scrape time
+type gaugeFunc struct {
+	metricName string
+	help       string
+	label      string
+	collect    func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge family whose values are computed on every scrape. collect
+// returns the value per value of label; with an empty label it returns a single value under "".
+func NewGaugeFunc(registry *Registry, name, help, label string, collect func() map[string]float64) {
+	registry.register(&gaugeFunc{metricName: name, help: help, label: label, collect: collect})
+}
+
+func (g *gaugeFunc) name() string { return g.metricName }
+
+func (g *gaugeFunc) write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.metricName, escapeHelp(g.help), g.metricName); err != nil {
+		return err
+	}
+	values := g.collect()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		labels := ""
+		if g.label != "" {
+			labels = formatLabels([]string{g.label}, []string{key})
+		}
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", g.metricName, labels, formatFloat(values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []float64
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(x float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, bound := range h.bounds {
+		if x <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += x
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct{ *family[Histogram] }
+
+// NewHistogramVec registers a histogram family with the given upper bucket bounds.
+func NewHistogramVec(registry *Registry, name, help string, bounds []float64, labels ...string) *HistogramVec {
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+	newChild := func() *Histogram {
+		return &Histogram{bounds: bounds, buckets: make([]uint64, len(bounds))}
+	}
+	vec := &HistogramVec{newFamily(name, help, "histogram", labels, newChild)}
+	registry.register(vec)
+	return vec
+}
+
+// With returns the histogram for the label values.
+func (vec *HistogramVec) With(values ...string) *Histogram { return vec.with(values) }
+
+func (vec *HistogramVec) write(w io.Writer) error {
+	if err := vec.writeHeader(w); err != nil {
+		return err
+	}
+	return vec.each(func(labels string, h *Histogram) error {
+		h.mu.Lock()
+		bounds, buckets, count, sum := h.bounds, append([]uint64(nil), h.buckets...), h.count, h.sum
+		h.mu.Unlock()
+
+		for i, bound := range bounds {
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", vec.metricName, withLabel(labels, "le", formatFloat(bound)), buckets[i]); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			vec.metricName, withLabel(labels, "le", "+Inf"), count,
+			vec.metricName, labels, formatFloat(sum),
+			vec.metricName, labels, count)
+		return err
+	})
+}
+
+// formatLabels renders {name="value",...}, or "" without labels
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLabel appends one label to rendered labels
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string { return labelEscaper.Replace(value) }
+
+func escapeHelp(help string) string { return helpEscaper.Replace(help) }
+
+func formatFloat(x float64) string {
+	switch {
+	case math.IsInf(x, 1):
+		return "+Inf"
+	case math.IsInf(x, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	registry := NewRegistry()
+	requests := NewCounterVec(registry, "test_requests_total", "Requests sent.", "model")
+	inFlight := NewGaugeVec(registry, "test_in_flight", "Requests in flight.")
+	latency := NewHistogramVec(registry, "test_latency_seconds", "Latency.", []float64{1, 0.5}, "model")
+	NewGaugeFunc(registry, "test_jobs", "Jobs by status.", "status", func() map[string]float64 {
+		return map[string]float64{"running": 2, "failed": 0}
+	})
+
+	requests.With(`a"b`).Add(3)
+	requests.With("gpt").Inc()
+	inFlight.With().Inc()
+	latency.With("gpt").Observe(0.2)
+	latency.With("gpt").Observe(0.7)
+	latency.With("gpt").Observe(3)
+
+	var output strings.Builder
+	if err := registry.WriteText(&output); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP test_in_flight Requests in flight.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_jobs Jobs by status.
+# TYPE test_jobs gauge
+test_jobs{status="failed"} 0
+test_jobs{status="running"} 2
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{model="gpt",le="0.5"} 1
+test_latency_seconds_bucket{model="gpt",le="1"} 2
+test_latency_seconds_bucket{model="gpt",le="+Inf"} 3
+test_latency_seconds_sum{model="gpt"} 3.9
+test_latency_seconds_count{model="gpt"} 3
+# HELP test_requests_total Requests sent.
+# TYPE test_requests_total counter
+test_requests_total{model="a\"b"} 3
+test_requests_total{model="gpt"} 1
+`
+	if output.String() != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", output.String(), expected)
+	}
+}
+
+func TestRegistryRejectsDuplicateNames(t *testing.T) {
+	registry := NewRegistry()
+	NewCounterVec(registry, "test_total", "First.")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a duplicate metric name")
+		}
+	}()
+	NewGaugeVec(registry, "test_total", "Second.")
+}
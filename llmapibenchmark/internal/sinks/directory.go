@@ -0,0 +1,53 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirectorySink writes every result to its own timestamped JSON file in a directory.
+type DirectorySink struct {
+	Directory string
+}
+
+func (sink *DirectorySink) Name() string { return "directory" }
+
+func (sink *DirectorySink) Write(_ context.Context, result Result) error {
+	if err := os.MkdirAll(sink.Directory, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so readers never see a partial result
+	name := fmt.Sprintf("%s-%s-%s.json", result.Timestamp.UTC().Format("20060102T150405.000Z"), result.Type, fileSafe(result.ID))
+	temporary, err := os.CreateTemp(sink.Directory, ".result-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(data); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), filepath.Join(sink.Directory, name))
+}
+
+// fileSafe keeps letters, digits, dashes and underscores
+func fileSafe(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, value)
+}
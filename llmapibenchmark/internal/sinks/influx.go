@@ -0,0 +1,76 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// influxMeasurement is the measurement every level is written to
+const influxMeasurement = "llm_benchmark"
+
+// InfluxSink writes one line-protocol point per level to an InfluxDB write endpoint.
+type InfluxSink struct {
+	URL    string // Full write URL including org/bucket or db query parameters
+	Token  string // Sent as "Authorization: Token <token>" when set
+	Client *http.Client
+}
+
+func (sink *InfluxSink) Name() string { return "influxdb" }
+
+func (sink *InfluxSink) Write(ctx context.Context, result Result) error {
+	header := http.Header{}
+	if sink.Token != "" {
+		header.Set("Authorization", "Token "+sink.Token)
+	}
+	url := sink.URL
+	if !strings.Contains(url, "precision=") {
+		separator := "?"
+		if strings.Contains(url, "?") {
+			separator = "&"
+		}
+		url += separator + "precision=ns"
+	}
+	return post(ctx, sink.Client, http.MethodPost, url, "text/plain; charset=utf-8", []byte(influxLines(result)), header)
+}
+
+// influxLines formats the levels of a result as line protocol, one point per level
+func influxLines(result Result) string {
+	var lines strings.Builder
+	timestamp := result.Timestamp.UnixNano()
+	for _, level := range result.Levels {
+		names := make([]string, 0, len(level.Metrics))
+		for name, value := range level.Metrics {
+			if !math.IsNaN(value) && !math.IsInf(value, 0) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = influxEscape(name) + "=" + strconv.FormatFloat(level.Metrics[name], 'g', -1, 64)
+		}
+		fmt.Fprintf(&lines, "%s,concurrency=%d,job_id=%s,job_type=%s,model=%s,source=%s %s %d\n",
+			influxMeasurement, level.Concurrency, influxEscape(result.ID), influxEscape(result.Type),
+			influxEscape(level.Model), influxEscape(result.Source), strings.Join(fields, ","), timestamp)
+	}
+	return lines.String()
+}
+
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// influxEscape escapes a tag key, tag value or field key
+func influxEscape(value string) string {
+	if value == "" {
+		return "unknown" // Empty tag values are not allowed
+	}
+	return influxEscaper.Replace(value)
+}
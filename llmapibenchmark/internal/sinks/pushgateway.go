@@ -0,0 +1,57 @@
+package sinks
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"llmapibenchmark/internal/metrics"
+)
+
+// PushgatewaySink pushes the levels of a result as gauges to a Prometheus Pushgateway.
+// Results are grouped by job type, so each push replaces the previous result of that type
+// and Prometheus records the history when it scrapes the Pushgateway.
+type PushgatewaySink struct {
+	URL    string // Base URL, e.g. http://pushgateway:9091
+	Client *http.Client
+}
+
+func (sink *PushgatewaySink) Name() string { return "pushgateway" }
+
+func (sink *PushgatewaySink) Write(ctx context.Context, result Result) error {
+	var body strings.Builder
+	if err := pushgatewayMetrics(result).WriteText(&body); err != nil {
+		return err
+	}
+	target := strings.TrimSuffix(sink.URL, "/") + "/metrics/job/llmapibenchmark/type/" + url.PathEscape(result.Type)
+	return post(ctx, sink.Client, http.MethodPost, target, "text/plain; version=0.0.4", []byte(body.String()), nil)
+}
+
+// pushgatewayMetrics renders a result as gauges named llmbench_result_<metric>
+func pushgatewayMetrics(result Result) *metrics.Registry {
+	registry := metrics.NewRegistry()
+	gauges := map[string]*metrics.GaugeVec{}
+	for _, level := range result.Levels {
+		names := make([]string, 0, len(level.Metrics))
+		for name := range level.Metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			gauge, exists := gauges[name]
+			if !exists {
+				gauge = metrics.NewGaugeVec(registry, "llmbench_result_"+name,
+					"Benchmark result metric "+name+" of the last completed "+result.Type+".", "model", "concurrency", "job_id", "source")
+				gauges[name] = gauge
+			}
+			gauge.With(level.Model, strconv.Itoa(level.Concurrency), result.ID, result.Source).Set(level.Metrics[name])
+		}
+	}
+	timestamp := metrics.NewGaugeVec(registry, "llmbench_result_timestamp_seconds",
+		"Completion time of the last pushed "+result.Type+".", "job_id", "source")
+	timestamp.With(result.ID, result.Source).Set(float64(result.Timestamp.UnixNano()) / 1e9)
+	return registry
+}
@@ -0,0 +1,163 @@
+// Package sinks pushes completed benchmark results to time-series and analytics systems.
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"llmapibenchmark/internal/utils"
+)
+
+// Result is a completed benchmark or search in the shape shared by every sink.
+type Result struct {
+	ID        string    `json:"id"`     // Job ID, or a generated ID for CLI runs
+	Type      string    `json:"type"`   // "benchmark" or "search"
+	Source    string    `json:"source"` // "server" or "cli"
+	Timestamp time.Time `json:"timestamp"`
+	Levels    []Level   `json:"levels"`
+	Payload   any       `json:"payload"` // The full result as returned by the API or the CLI
+}
+
+// Level holds the metrics of one measured concurrency level of a model.
+type Level struct {
+	Model       string             `json:"model"`
+	Concurrency int                `json:"concurrency"`
+	Metrics     map[string]float64 `json:"metrics"` // e.g. "generation_throughput", "p95_ttft"
+}
+
+// ResultSink receives completed results. Implementations must be safe for concurrent use.
+type ResultSink interface {
+	Name() string
+	Write(ctx context.Context, result Result) error
+}
+
+// SpeedMetrics returns the metrics of a measured level under the names used by all sinks.
+func SpeedMetrics(result utils.SpeedResult) map[string]float64 {
+	return map[string]float64{
+		"generation_throughput": result.GenerationSpeed,
+		"prompt_throughput":     result.PromptThroughput,
+		"min_ttft":              result.MinTtft,
+		"max_ttft":              result.MaxTtft,
+		"p95_ttft":              result.P95Ttft,
+		"per_user_throughput":   result.PerUserSpeed,
+		"requests_per_second":   result.RequestsPerSecond,
+		"goodput_ratio":         result.GoodputRatio,
+		"requests":              float64(result.Requests),
+		"failed_requests":       float64(result.FailedRequests),
+		"error_rate":            result.ErrorRate,
+		"prompt_tokens":         float64(result.PromptTokens),
+		"completion_tokens":     float64(result.CompletionTokens),
+	}
+}
+
+// Config selects the sinks; empty fields leave a sink out.
+type Config struct {
+	InfluxURL      string // InfluxDB write URL, e.g. http://influx:8086/api/v2/write?org=o&bucket=b
+	InfluxToken    string
+	PushgatewayURL string // Prometheus Pushgateway base URL
+	WebhookURL     string // Receives every result as a JSON POST
+	Directory      string // Local directory for timestamped JSON files
+	Retries        int    // Extra attempts per sink after a failure
+}
+
+// Dispatcher writes results to every configured sink, retrying failed writes.
+type Dispatcher struct {
+	Sinks   []ResultSink
+	Retries int
+	Backoff time.Duration // Delay before the first retry, doubled for each further one
+}
+
+// New returns a dispatcher for the sinks in config, or nil when none is configured.
+func New(config Config) (*Dispatcher, error) {
+	if config.Retries < 0 {
+		return nil, fmt.Errorf("result sink retries must not be negative, got %d", config.Retries)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	var resultSinks []ResultSink
+	if config.InfluxURL != "" {
+		resultSinks = append(resultSinks, &InfluxSink{URL: config.InfluxURL, Token: config.InfluxToken, Client: client})
+	}
+	if config.PushgatewayURL != "" {
+		resultSinks = append(resultSinks, &PushgatewaySink{URL: config.PushgatewayURL, Client: client})
+	}
+	if config.WebhookURL != "" {
+		resultSinks = append(resultSinks, &WebhookSink{URL: config.WebhookURL, Client: client})
+	}
+	if config.Directory != "" {
+		resultSinks = append(resultSinks, &DirectorySink{Directory: config.Directory})
+	}
+	if len(resultSinks) == 0 {
+		return nil, nil
+	}
+	return &Dispatcher{Sinks: resultSinks, Retries: config.Retries, Backoff: time.Second}, nil
+}
+
+// Publish writes the result to every sink in parallel and returns the errors of the sinks
+// that still failed after all retries. Failed attempts are logged.
+func (dispatcher *Dispatcher) Publish(ctx context.Context, result Result) error {
+	if dispatcher == nil {
+		return nil
+	}
+	errs := make([]error, len(dispatcher.Sinks))
+	done := make(chan struct{})
+	for i, sink := range dispatcher.Sinks {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			errs[i] = dispatcher.write(ctx, sink, result)
+		}()
+	}
+	for range dispatcher.Sinks {
+		<-done
+	}
+	return errors.Join(errs...)
+}
+
+func (dispatcher *Dispatcher) write(ctx context.Context, sink ResultSink, result Result) error {
+	backoff := dispatcher.Backoff
+	for attempt := 0; ; attempt++ {
+		err := sink.Write(ctx, result)
+		if err == nil {
+			return nil
+		}
+		if attempt >= dispatcher.Retries {
+			log.Printf("❌ Result sink %s failed for %s: %v", sink.Name(), result.ID, err)
+			return fmt.Errorf("%s: %w", sink.Name(), err)
+		}
+		log.Printf("⚠️ Result sink %s failed for %s (attempt %d/%d), retrying in %v: %v", sink.Name(), result.ID, attempt+1, dispatcher.Retries+1, backoff, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", sink.Name(), ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends a request body and treats any non-2xx status as an error
+func post(ctx context.Context, client *http.Client, method string, url string, contentType string, body []byte, header http.Header) error {
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s returned %s", method, url, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testResult() Result {
+	return Result{
+		ID:        "job 1",
+		Type:      "benchmark",
+		Source:    "server",
+		Timestamp: time.Unix(1700000000, 5),
+		Levels: []Level{{
+			Model:       "llama,3=8b",
+			Concurrency: 4,
+			Metrics:     map[string]float64{"p95_ttft": 0.25, "generation_throughput": 120.5},
+		}},
+	}
+}
+
+func TestInfluxLines(t *testing.T) {
+	want := `llm_benchmark,concurrency=4,job_id=job\ 1,job_type=benchmark,model=llama\,3\=8b,source=server generation_throughput=120.5,p95_ttft=0.25 1700000000000000005` + "\n"
+	if got := influxLines(testResult()); got != want {
+		t.Errorf("unexpected line protocol:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestDispatcherRetriesFailedWrites(t *testing.T) {
+	var attempts atomic.Int32
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if r.Header.Get("Authorization") != "Token secret" || r.URL.Query().Get("precision") != "ns" {
+			t.Errorf("unexpected request %s %v", r.URL, r.Header)
+		}
+	}))
+	defer server.Close()
+
+	dispatcher, err := New(Config{InfluxURL: server.URL + "/api/v2/write?bucket=b", InfluxToken: "secret", Retries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.Backoff = time.Millisecond
+	if err := dispatcher.Publish(context.Background(), testResult()); err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if attempts.Load() != 3 || !strings.HasPrefix(body, "llm_benchmark,") {
+		t.Errorf("expected 3 attempts ending in a write, got %d and %q", attempts.Load(), body)
+	}
+
+	dispatcher.Retries = 0
+	attempts.Store(0)
+	if err := dispatcher.Publish(context.Background(), testResult()); err == nil || !strings.Contains(err.Error(), "influxdb") {
+		t.Errorf("expected the sink error without retries, got %v", err)
+	}
+}
+
+func TestDirectorySinkWritesTimestampedFiles(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "results")
+	sink := &DirectorySink{Directory: directory}
+	if err := sink.Write(context.Background(), testResult()); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "20231114T221320.000Z-benchmark-job_1.json" {
+		t.Fatalf("unexpected files %v", entries)
+	}
+	data, _ := os.ReadFile(filepath.Join(directory, entries[0].Name()))
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil || result.Levels[0].Concurrency != 4 {
+		t.Errorf("unexpected file content %s (%v)", data, err)
+	}
+}
+
+func TestNewWithoutSinks(t *testing.T) {
+	dispatcher, err := New(Config{Retries: 3})
+	if err != nil || dispatcher != nil {
+		t.Fatalf("expected no dispatcher, got %v, %v", dispatcher, err)
+	}
+	if err := dispatcher.Publish(context.Background(), testResult()); err != nil {
+		t.Errorf("expected a nil dispatcher to publish nothing, got %v", err)
+	}
+}
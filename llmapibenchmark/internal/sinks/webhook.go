@@ -0,0 +1,24 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// WebhookSink posts every result as JSON.
+type WebhookSink struct {
+	URL    string
+	Header http.Header // Extra request headers, e.g. for authentication
+	Client *http.Client
+}
+
+func (sink *WebhookSink) Name() string { return "webhook" }
+
+func (sink *WebhookSink) Write(ctx context.Context, result Result) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return post(ctx, sink.Client, http.MethodPost, sink.URL, "application/json", body, sink.Header)
+}
@@ -0,0 +1,280 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config selects the OTLP/HTTP collector spans are sent to.
+type Config struct {
+	Endpoint      string            // Full URL of the traces endpoint, e.g. http://collector:4318/v1/traces
+	ServiceName   string            // service.name resource attribute
+	Headers       map[string]string // Extra request headers, e.g. for collector authentication
+	BatchSize     int               // Spans per export request (default 512)
+	FlushInterval time.Duration     // Maximum delay before queued spans are exported (default 5s)
+}
+
+// ConfigFromEnv reads the standard OpenTelemetry variables:
+//
+//	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT  traces URL, used as is
+//	OTEL_EXPORTER_OTLP_ENDPOINT         collector base URL; /v1/traces is appended
+//	OTEL_EXPORTER_OTLP_HEADERS          comma-separated key=value pairs
+//	OTEL_SERVICE_NAME                   service name (default "llmapibenchmark")
+//
+// Without an endpoint the returned config leaves tracing disabled.
+func ConfigFromEnv() Config {
+	config := Config{
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		Headers:     map[string]string{},
+	}
+	if config.Endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			config.Endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	for _, pair := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if found && strings.TrimSpace(key) != "" {
+			config.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return config
+}
+
+// Setup enables tracing with an exporter for config and returns a function that exports
+// the remaining spans and disables tracing again. Without an endpoint tracing stays
+// disabled and the returned function does nothing.
+func Setup(config Config) (shutdown func(context.Context) error, err error) {
+	if config.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if !strings.HasPrefix(config.Endpoint, "http://") && !strings.HasPrefix(config.Endpoint, "https://") {
+		return nil, fmt.Errorf("OTLP endpoint must be an http(s) URL, got %q", config.Endpoint)
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "llmapibenchmark"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 512
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+
+	exp := &exporter{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan *Span, 8*config.BatchSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go exp.run()
+	active.Store(exp)
+	return exp.shutdown, nil
+}
+
+// exporter batches ended spans and posts them to the collector as OTLP JSON
+type exporter struct {
+	config Config
+	client *http.Client
+	queue  chan *Span
+	stop   chan struct{} // Closed by shutdown
+	done   chan struct{} // Closed once the last batch is exported
+
+	dropMu  sync.Mutex
+	dropped int
+}
+
+// enqueue queues a span without blocking; spans are dropped while the queue is full
+func (exp *exporter) enqueue(span *Span) {
+	select {
+	case exp.queue <- span:
+	default:
+		exp.dropMu.Lock()
+		exp.dropped++
+		if exp.dropped == 1 || exp.dropped%1000 == 0 {
+			log.Printf("⚠️ Trace export queue full, %d spans dropped", exp.dropped)
+		}
+		exp.dropMu.Unlock()
+	}
+}
+
+func (exp *exporter) run() {
+	defer close(exp.done)
+	ticker := time.NewTicker(exp.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exp.config.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := exp.export(batch); err != nil {
+			log.Printf("⚠️ Failed to export %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	add := func(span *Span) {
+		batch = append(batch, span)
+		if len(batch) >= exp.config.BatchSize {
+			flush()
+		}
+	}
+	for {
+		select {
+		case span := <-exp.queue:
+			add(span)
+		case <-ticker.C:
+			flush()
+		case <-exp.stop:
+			for {
+				select {
+				case span := <-exp.queue:
+					add(span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown disables tracing, exports the queued spans and waits for the last request
+func (exp *exporter) shutdown(ctx context.Context) error {
+	if !active.CompareAndSwap(exp, nil) {
+		return nil
+	}
+	close(exp.stop)
+	select {
+	case <-exp.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (exp *exporter) export(spans []*Span) error {
+	body, err := json.Marshal(exp.request(spans))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, exp.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range exp.config.Headers {
+		request.Header.Set(key, value)
+	}
+	response, err := exp.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", response.Status)
+	}
+	return nil
+}
+
+// OTLP/JSON request shapes, see opentelemetry-proto ExportTraceServiceRequest
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+func (exp *exporter) request(spans []*Span) otlpRequest {
+	converted := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		span.mu.Lock()
+		converted[i] = otlpSpan{
+			TraceID:           span.traceID.String(),
+			SpanID:            span.spanID.String(),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.attributes),
+			Status:            otlpStatus{Code: span.statusCode, Message: span.statusMessage},
+		}
+		if span.parentID != (SpanID{}) {
+			converted[i].ParentSpanID = span.parentID.String()
+		}
+		span.mu.Unlock()
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes([]Attribute{String("service.name", exp.config.ServiceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "llmapibenchmark"}, Spans: converted}},
+	}}}
+}
+
+func otlpAttributes(attributes []Attribute) []otlpAttribute {
+	converted := make([]otlpAttribute, 0, len(attributes))
+	for _, attribute := range attributes {
+		var value map[string]any
+		switch v := attribute.Value.(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)} // int64 is a string in OTLP/JSON
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				value = map[string]any{"stringValue": strconv.FormatFloat(v, 'g', -1, 64)}
+			} else {
+				value = map[string]any{"doubleValue": v}
+			}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		converted = append(converted, otlpAttribute{Key: attribute.Key, Value: value})
+	}
+	return converted
+}
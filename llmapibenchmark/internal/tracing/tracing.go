@@ -0,0 +1,183 @@
+// Package tracing records benchmark jobs and LLM calls as OpenTelemetry spans, exports
+// them over OTLP/HTTP and propagates the trace context to the LLM endpoint with W3C
+// traceparent headers. Tracing is disabled until Setup is called with an endpoint; until
+// then Start returns nil spans, whose methods do nothing.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceID and SpanID identify spans as in the W3C trace context.
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// Span kinds, as defined by OTLP
+const (
+	KindInternal = 1
+	KindClient   = 3
+)
+
+// statusError is the OTLP status code of a failed span
+const statusError = 2
+
+// Attribute is a key and a string, bool, int64 or float64 value.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute { return Attribute{key, value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute { return Attribute{key, int64(value)} }
+
+// Float returns a floating point attribute.
+func Float(key string, value float64) Attribute { return Attribute{key, value} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+// Span is one timed operation. A nil *Span is valid and records nothing.
+type Span struct {
+	exporter *exporter
+	name     string
+	kind     int
+	traceID  TraceID
+	spanID   SpanID
+	parentID SpanID
+	start    time.Time
+
+	mu            sync.Mutex
+	end           time.Time
+	attributes    []Attribute
+	statusCode    int
+	statusMessage string
+	ended         bool
+}
+
+// active is the exporter set up by Setup, nil while tracing is disabled
+var active atomic.Pointer[exporter]
+
+// Enabled reports whether spans are recorded.
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+type spanKey struct{}
+
+// Start begins a span as a child of the span in ctx, or a new trace, and returns a
+// context carrying it.
+func Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal, attributes...)
+}
+
+// StartKind is Start with an explicit span kind, such as KindClient for outgoing calls.
+func StartKind(ctx context.Context, name string, kind int, attributes ...Attribute) (context.Context, *Span) {
+	exp := active.Load()
+	if exp == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		exporter:   exp,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the span carried by ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SetAttributes adds attributes to the span.
+func (span *Span) SetAttributes(attributes ...Attribute) {
+	if span == nil {
+		return
+	}
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.attributes = append(span.attributes, attributes...)
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (span *Span) RecordError(err error) {
+	if span == nil || err == nil {
+		return
+	}
+	span.mu.Lock()
+	defer span.mu.Unlock()
+	span.statusCode = statusError
+	span.statusMessage = err.Error()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+	span.mu.Lock()
+	if span.ended {
+		span.mu.Unlock()
+		return
+	}
+	span.ended = true
+	span.end = time.Now()
+	span.mu.Unlock()
+	span.exporter.enqueue(span)
+}
+
+// TraceID returns the trace the span belongs to, or "" for a nil span.
+func (span *Span) TraceID() string {
+	if span == nil {
+		return ""
+	}
+	return span.traceID.String()
+}
+
+// traceparent formats the W3C traceparent header of the span, always sampled
+func (span *Span) traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", span.traceID, span.spanID)
+}
+
+// Transport wraps an HTTP transport so outgoing requests carry the traceparent of the
+// span in their context.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return &propagatingTransport{base: base}
+}
+
+type propagatingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *propagatingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if span := FromContext(request.Context()); span != nil {
+		request = request.Clone(request.Context())
+		request.Header.Set("traceparent", span.traceparent())
+	}
+	return t.base.RoundTrip(request)
+}
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSpansAreExportedAndPropagated(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		exports []otlpRequest
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("expected the configured header, got %q", r.Header.Get("Authorization"))
+		}
+		var request otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid OTLP JSON: %v", err)
+		}
+		mu.Lock()
+		exports = append(exports, request)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	var traceparent string
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer endpoint.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL+"/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer secret")
+	config := ConfigFromEnv()
+	if config.Endpoint != collector.URL+"/v1/traces" {
+		t.Fatalf("unexpected endpoint %q", config.Endpoint)
+	}
+	shutdown, err := Setup(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, job := Start(context.Background(), "benchmark job", String("job.id", "42"))
+	requestCtx, request := StartKind(ctx, "chat completion", KindClient, Int("gen_ai.usage.output_tokens", 7))
+	httpRequest, _ := http.NewRequestWithContext(requestCtx, http.MethodGet, endpoint.URL, nil)
+	response, err := (&http.Client{Transport: Transport(http.DefaultTransport)}).Do(httpRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	request.RecordError(errors.New("stream error"))
+	request.End()
+	job.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if Enabled() {
+		t.Error("expected tracing to be disabled after shutdown")
+	}
+
+	if want := "00-" + job.TraceID() + "-" + request.spanID.String() + "-01"; traceparent != want {
+		t.Errorf("expected traceparent %q, got %q", want, traceparent)
+	}
+	if len(exports) != 1 || len(exports[0].ResourceSpans[0].ScopeSpans[0].Spans) != 2 {
+		t.Fatalf("expected one export with 2 spans, got %+v", exports)
+	}
+	spans := exports[0].ResourceSpans[0].ScopeSpans[0].Spans
+	child, root := spans[0], spans[1]
+	if child.TraceID != root.TraceID || child.ParentSpanID != root.SpanID || root.ParentSpanID != "" {
+		t.Errorf("expected the request span below the job span, got %+v and %+v", child, root)
+	}
+	if child.Kind != KindClient || child.Status.Code != statusError || !strings.Contains(child.Status.Message, "stream error") {
+		t.Errorf("unexpected request span %+v", child)
+	}
+	if value := child.Attributes[0].Value["intValue"]; value != "7" {
+		t.Errorf("expected an int attribute encoded as string, got %v", child.Attributes[0])
+	}
+}
+
+func TestDisabledTracingRecordsNothing(t *testing.T) {
+	shutdown, err := Setup(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	ctx, span := Start(context.Background(), "job")
+	if span != nil || FromContext(ctx) != nil {
+		t.Fatal("expected no span while tracing is disabled")
+	}
+	span.SetAttributes(String("key", "value")) // Nil spans are safe to use
+	span.RecordError(errors.New("ignored"))
+	span.End()
+}
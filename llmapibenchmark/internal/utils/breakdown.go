@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"time"
+
+	"llmapibenchmark/internal/api"
+)
+
+// TtftBreakdown splits the time to first token of a concurrency level into phases. All
+// values are mean milliseconds over the successful requests; DNS, connect and TLS are
+// averaged over new connections only and happen before the TTFT clock starts.
+type TtftBreakdown struct {
+	DnsMs             float64 `json:"dns_ms" yaml:"dns-ms"`
+	ConnectMs         float64 `json:"connect_ms" yaml:"connect-ms"`
+	TlsMs             float64 `json:"tls_ms" yaml:"tls-ms"`
+	HeadersMs         float64 `json:"headers_ms" yaml:"headers-ms"`         // Sent to first response header byte
+	FirstChunkMs      float64 `json:"first_chunk_ms" yaml:"first-chunk-ms"` // Headers to first stream chunk
+	FirstTokenMs      float64 `json:"first_token_ms" yaml:"first-token-ms"` // First chunk to first non-blank content
+	NewConnections    int     `json:"new_connections" yaml:"new-connections"`
+	ReusedConnections int     `json:"reused_connections" yaml:"reused-connections"`
+}
+
+// requestPhases holds the phase durations of one request in milliseconds.
+type requestPhases struct {
+	reused                          bool
+	dns, connect, tls               float64
+	headers, firstChunk, firstToken float64
+}
+
+func newRequestPhases(stats api.RequestStats) requestPhases {
+	return requestPhases{
+		reused:     stats.ConnReused,
+		dns:        durationMs(stats.DNS),
+		connect:    durationMs(stats.Connect),
+		tls:        durationMs(stats.TLS),
+		headers:    durationMs(stats.HeadersAt.Sub(stats.SentAt)),
+		firstChunk: durationMs(stats.FirstChunkAt.Sub(stats.HeadersAt)),
+		firstToken: durationMs(stats.FirstTokenAt.Sub(stats.FirstChunkAt)),
+	}
+}
+
+// durationMs converts a duration to milliseconds, clamping clock skew between the
+// trace and the stream reader to zero.
+func durationMs(d time.Duration) float64 {
+	if d < 0 {
+		return 0
+	}
+	return float64(d.Microseconds()) / 1000
+}
+
+// summarizeTtftBreakdown averages the phases of the successful requests of a pass.
+func summarizeTtftBreakdown(phases []requestPhases) *TtftBreakdown {
+	if len(phases) == 0 {
+		return nil
+	}
+
+	breakdown := &TtftBreakdown{}
+	for _, phase := range phases {
+		if phase.reused {
+			breakdown.ReusedConnections++
+		} else {
+			breakdown.NewConnections++
+			breakdown.DnsMs += phase.dns
+			breakdown.ConnectMs += phase.connect
+			breakdown.TlsMs += phase.tls
+		}
+		breakdown.HeadersMs += phase.headers
+		breakdown.FirstChunkMs += phase.firstChunk
+		breakdown.FirstTokenMs += phase.firstToken
+	}
+
+	if breakdown.NewConnections > 0 {
+		n := float64(breakdown.NewConnections)
+		breakdown.DnsMs = roundToTwoDecimals(breakdown.DnsMs / n)
+		breakdown.ConnectMs = roundToTwoDecimals(breakdown.ConnectMs / n)
+		breakdown.TlsMs = roundToTwoDecimals(breakdown.TlsMs / n)
+	}
+	n := float64(len(phases))
+	breakdown.HeadersMs = roundToTwoDecimals(breakdown.HeadersMs / n)
+	breakdown.FirstChunkMs = roundToTwoDecimals(breakdown.FirstChunkMs / n)
+	breakdown.FirstTokenMs = roundToTwoDecimals(breakdown.FirstTokenMs / n)
+	return breakdown
+}
+
+// mergeTtftBreakdowns combines the breakdowns of repeated runs, weighting every run by
+// the number of requests (or new connections) it averaged over.
+func mergeTtftBreakdowns(breakdowns []*TtftBreakdown) *TtftBreakdown {
+	merged := &TtftBreakdown{}
+	for _, breakdown := range breakdowns {
+		if breakdown == nil {
+			continue
+		}
+		newConns := float64(breakdown.NewConnections)
+		requests := float64(breakdown.NewConnections + breakdown.ReusedConnections)
+		merged.DnsMs += breakdown.DnsMs * newConns
+		merged.ConnectMs += breakdown.ConnectMs * newConns
+		merged.TlsMs += breakdown.TlsMs * newConns
+		merged.HeadersMs += breakdown.HeadersMs * requests
+		merged.FirstChunkMs += breakdown.FirstChunkMs * requests
+		merged.FirstTokenMs += breakdown.FirstTokenMs * requests
+		merged.NewConnections += breakdown.NewConnections
+		merged.ReusedConnections += breakdown.ReusedConnections
+	}
+
+	requests := merged.NewConnections + merged.ReusedConnections
+	if requests == 0 {
+		return nil
+	}
+	if merged.NewConnections > 0 {
+		n := float64(merged.NewConnections)
+		merged.DnsMs = roundToTwoDecimals(merged.DnsMs / n)
+		merged.ConnectMs = roundToTwoDecimals(merged.ConnectMs / n)
+		merged.TlsMs = roundToTwoDecimals(merged.TlsMs / n)
+	}
+	n := float64(requests)
+	merged.HeadersMs = roundToTwoDecimals(merged.HeadersMs / n)
+	merged.FirstChunkMs = roundToTwoDecimals(merged.FirstChunkMs / n)
+	merged.FirstTokenMs = roundToTwoDecimals(merged.FirstTokenMs / n)
+	return merged
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"llmapibenchmark/internal/api"
+)
+
+func TestNewRequestPhases(t *testing.T) {
+	sent := time.Unix(1000, 0)
+	stats := api.RequestStats{
+		SentAt:       sent,
+		HeadersAt:    sent.Add(200 * time.Millisecond),
+		FirstChunkAt: sent.Add(500 * time.Millisecond),
+		FirstTokenAt: sent.Add(550 * time.Millisecond),
+		DNS:          5 * time.Millisecond,
+		Connect:      10 * time.Millisecond,
+		TLS:          30 * time.Millisecond,
+	}
+
+	phases := newRequestPhases(stats)
+	if phases.headers != 200 || phases.firstChunk != 300 || phases.firstToken != 50 {
+		t.Errorf("unexpected phases %+v", phases)
+	}
+	if phases.dns != 5 || phases.connect != 10 || phases.tls != 30 {
+		t.Errorf("unexpected setup phases %+v", phases)
+	}
+}
+
+func TestSummarizeTtftBreakdown(t *testing.T) {
+	if summarizeTtftBreakdown(nil) != nil {
+		t.Fatal("expected no breakdown without requests")
+	}
+
+	breakdown := summarizeTtftBreakdown([]requestPhases{
+		{dns: 4, connect: 10, tls: 20, headers: 100, firstChunk: 300, firstToken: 10},
+		{reused: true, headers: 200, firstChunk: 500, firstToken: 20},
+	})
+	// Setup phases only average over the new connection
+	if breakdown.DnsMs != 4 || breakdown.ConnectMs != 10 || breakdown.TlsMs != 20 {
+		t.Errorf("unexpected setup phases %+v", breakdown)
+	}
+	if breakdown.HeadersMs != 150 || breakdown.FirstChunkMs != 400 || breakdown.FirstTokenMs != 15 {
+		t.Errorf("unexpected request phases %+v", breakdown)
+	}
+	if breakdown.NewConnections != 1 || breakdown.ReusedConnections != 1 {
+		t.Errorf("unexpected connection counts %+v", breakdown)
+	}
+}
+
+func TestMergeTtftBreakdownsWeightsByRequests(t *testing.T) {
+	merged := mergeTtftBreakdowns([]*TtftBreakdown{
+		{ConnectMs: 10, HeadersMs: 100, NewConnections: 1, ReusedConnections: 0},
+		{ConnectMs: 40, HeadersMs: 400, NewConnections: 1, ReusedConnections: 2},
+		nil,
+	})
+
+	if merged.ConnectMs != 25 {
+		t.Errorf("expected connect 25 ms, got %v", merged.ConnectMs)
+	}
+	if merged.HeadersMs != 325 {
+		t.Errorf("expected headers 325 ms, got %v", merged.HeadersMs)
+	}
+	if merged.NewConnections != 2 || merged.ReusedConnections != 2 {
+		t.Errorf("unexpected connection counts %+v", merged)
+	}
+	if mergeTtftBreakdowns([]*TtftBreakdown{nil}) != nil {
+		t.Error("expected no breakdown when no run had one")
+	}
+}
@@ -1,14 +1,21 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"sync"
 	"time"
 )
 
-// MeasureLatency tests the network latency to a given base URL.
-func MeasureLatency(baseURL string, attempts int) (float64, error) {
+// EstimateNetworkRTT estimates the round-trip time to the host of baseURL in milliseconds.
+// Each attempt opens a fresh connection and times the TCP handshake, which takes one round
+// trip. The HTTP response itself is ignored, so hosts that answer 404 or redirect are fine.
+// The estimate is informational only and is never subtracted from measurements.
+func EstimateNetworkRTT(ctx context.Context, baseURL string, attempts int) (float64, error) {
 	if baseURL == "" {
 		return 0, fmt.Errorf("empty base URL")
 	}
@@ -18,15 +25,61 @@ func MeasureLatency(baseURL string, attempts int) (float64, error) {
 		return 0, fmt.Errorf("invalid base URL: %w", err)
 	}
 
-	var totalLatency float64
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	var samples []float64
 	for i := 0; i < attempts; i++ {
-		start := time.Now()
-		conn, err := http.Get(parsedURL.Scheme + "://" + parsedURL.Host)
+		// The transport may race dials to several addresses on their own goroutines, so
+		// the callbacks are guarded and time each address separately
+		var mu sync.Mutex
+		connectStarts := make(map[string]time.Time)
+		var connectTime float64
+		trace := &httptrace.ClientTrace{
+			ConnectStart: func(network, addr string) {
+				mu.Lock()
+				defer mu.Unlock()
+				connectStarts[network+" "+addr] = time.Now()
+			},
+			ConnectDone: func(network, addr string, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if start, ok := connectStarts[network+" "+addr]; ok && err == nil && connectTime == 0 {
+					connectTime = float64(time.Since(start).Microseconds()) / 1000
+				}
+			},
+		}
+
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodHead, parsedURL.Scheme+"://"+parsedURL.Host, nil)
 		if err != nil {
-			return 0, fmt.Errorf("HTTP GET error: %w", err)
+			return 0, fmt.Errorf("invalid base URL: %w", err)
+		}
+		resp, err := client.Do(req)
+		mu.Lock()
+		measured := connectTime
+		mu.Unlock()
+		if err != nil && measured == 0 {
+			return 0, fmt.Errorf("connection error: %w", err)
 		}
-		conn.Body.Close()
-		totalLatency += float64(time.Since(start).Milliseconds())
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if measured > 0 {
+			samples = append(samples, measured)
+		}
+	}
+
+	if len(samples) == 0 {
+		return 0, fmt.Errorf("no connection timings recorded")
 	}
-	return totalLatency / float64(attempts), nil
+	return roundToTwoDecimals(Percentile(samples, 50)), nil
 }
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEstimateNetworkRTT(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	rtt, err := EstimateNetworkRTT(context.Background(), server.URL+"/v1", 3)
+	if err != nil || rtt <= 0 {
+		t.Errorf("expected a positive estimate, got %v: %v", rtt, err)
+	}
+	if _, err := EstimateNetworkRTT(context.Background(), "", 1); err == nil {
+		t.Error("expected an empty base URL to be rejected")
+	}
+}
@@ -0,0 +1,30 @@
+package utils
+
+// MeasurementModel documents how SpeedResult values are derived so results can be audited.
+type MeasurementModel struct {
+	Version  string            `json:"version" yaml:"version"`
+	Timing   string            `json:"timing" yaml:"timing"`
+	Formulas map[string]string `json:"formulas" yaml:"formulas"`
+	// Median TCP handshake time to the API host, when requested. Informational only.
+	NetworkRttMs float64 `json:"network_rtt_ms,omitempty" yaml:"network-rtt-ms,omitempty"`
+}
+
+// DescribeMeasurement returns the measurement model used by SpeedMeasurement.
+func DescribeMeasurement() MeasurementModel {
+	return MeasurementModel{
+		Version: "2",
+		Timing: "Each request is timed from the moment its connection is acquired and the request is written " +
+			"(sent) to the first non-blank content chunk (first token) and to the last stream chunk (done). " +
+			"DNS, TCP and TLS setup happen before sent and are excluded. Only successful requests count.",
+		Formulas: map[string]string{
+			"ttft":                "first_token - sent, per request",
+			"generation_speed":    "sum(completion_tokens) / (max(done) - min(sent))",
+			"prompt_throughput":   "sum(prompt_tokens) / (max(first_token) - min(sent))",
+			"per_user_speed":      "(completion_tokens - 1) / (done - first_token), per request; mean and percentiles across requests",
+			"requests_per_second": "completed_requests / (max(done) - min(sent))",
+			"goodput_ratio":       "requests meeting the TTFT and TPOT targets / all requests",
+			"goodput_rate":        "requests meeting the TTFT and TPOT targets / (max(done) - min(sent))",
+			"error_rate":          "failed_requests / all requests",
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"llmapibenchmark/internal/api"
+	"llmapibenchmark/internal/metrics"
+)
+
+// Metrics of the measurement engine, exposed by the server on /metrics
+var (
+	requestsSent = metrics.NewCounterVec(metrics.Default, "llmbench_requests_total",
+		"Benchmark requests sent to a model.", "model")
+	requestsInFlight = metrics.NewGaugeVec(metrics.Default, "llmbench_requests_in_flight",
+		"Benchmark requests currently waiting for or streaming a response.", "model")
+	requestErrors = metrics.NewCounterVec(metrics.Default, "llmbench_request_errors_total",
+		"Failed benchmark requests by error class, such as ttft_timeout or http_429.", "model", "class")
+	requestTtft = metrics.NewHistogramVec(metrics.Default, "llmbench_ttft_seconds",
+		"Time to first token of successful benchmark requests.",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}, "model")
+	requestLatency = metrics.NewHistogramVec(metrics.Default, "llmbench_request_duration_seconds",
+		"Time from sending a successful benchmark request to its last stream chunk.",
+		[]float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}, "model")
+	completionTokens = metrics.NewCounterVec(metrics.Default, "llmbench_completion_tokens_total",
+		"Tokens generated by successful benchmark requests.", "model")
+	promptTokens = metrics.NewCounterVec(metrics.Default, "llmbench_prompt_tokens_total",
+		"Prompt tokens of successful benchmark requests.", "model")
+)
+
+// requestStarted counts a request that is about to be sent.
+func requestStarted(model string) {
+	requestsSent.With(model).Inc()
+	requestsInFlight.With(model).Inc()
+}
+
+// requestFinished records the outcome of a request counted by requestStarted.
+func requestFinished(model string, stats api.RequestStats, err error) {
+	requestsInFlight.With(model).Dec()
+	if err != nil {
+		requestErrors.With(model, api.ClassifyError(err)).Inc()
+		return
+	}
+	requestTtft.With(model).Observe(stats.Ttft())
+	requestLatency.With(model).Observe(stats.Duration())
+	completionTokens.With(model).Add(float64(stats.CompletionTokens))
+	promptTokens.With(model).Add(float64(stats.PromptTokens))
+}
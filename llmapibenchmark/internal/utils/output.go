@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
 // PrintBenchmarkHeader prints the benchmark header with details about the test.
-func PrintBenchmarkHeader(modelName string, inputTokens int, maxTokens int, latency float64) {
+// A network RTT of 0 means no estimate was requested and is left out.
+func PrintBenchmarkHeader(modelName string, inputTokens int, maxTokens int, networkRttMs float64) {
 	banner :=
 		`
 ################################################################################################################
@@ -21,11 +24,14 @@ func PrintBenchmarkHeader(modelName string, inputTokens int, maxTokens int, late
 	fmt.Printf("Input Tokens: %d\n", inputTokens)
 	fmt.Printf("Output Tokens: %d\n", maxTokens)
 	fmt.Printf("Test Model: %s\n", modelName)
-	fmt.Printf("Latency: %.2f ms\n\n", latency)
+	if networkRttMs > 0 {
+		fmt.Printf("Network RTT: %.2f ms\n", networkRttMs)
+	}
+	fmt.Println()
 }
 
 // SaveResultsToMD saves the benchmark results to a Markdown file.
-func SaveResultsToMD(results [][]interface{}, modelName string, inputTokens int, maxTokens int, latency float64) {
+func SaveResultsToMD(results []SpeedResult, modelName string, inputTokens int, maxTokens int, networkRttMs float64) {
 	filename := fmt.Sprintf("API_Throughput_%s.md", modelName)
 	file, err := os.Create(filename)
 	if err != nil {
@@ -37,23 +43,149 @@ func SaveResultsToMD(results [][]interface{}, modelName string, inputTokens int,
 	file.WriteString(fmt.Sprintf("```\nInput Tokens: %d\n", inputTokens))
 	file.WriteString(fmt.Sprintf("Output Tokens: %d\n", maxTokens))
 	file.WriteString(fmt.Sprintf("Test Model: %s\n", modelName))
-	file.WriteString(fmt.Sprintf("Latency: %.2f ms\n```\n\n", latency))
+	if networkRttMs > 0 {
+		file.WriteString(fmt.Sprintf("Network RTT: %.2f ms\n", networkRttMs))
+	}
+	file.WriteString("```\n\n")
 	file.WriteString("| Concurrency | Generation Throughput (tokens/s) |  Prompt Throughput (tokens/s) | Min TTFT (s) | Max TTFT (s) |\n")
 	file.WriteString("|-------------|----------------------------------|-------------------------------|--------------|--------------|\n")
 
 	for _, result := range results {
-		concurrency := result[0].(int)
-		generationSpeed := result[1].(float64)
-		promptThroughput := result[2].(float64)
-		minTTFT := result[3].(float64)
-		maxTTFT := result[4].(float64)
 		file.WriteString(fmt.Sprintf("| %11d | %32.2f | %29.2f | %12.2f | %12.2f |\n",
-			concurrency,
-			generationSpeed,
-			promptThroughput,
-			minTTFT,
-			maxTTFT))
+			result.Concurrency,
+			result.GenerationSpeed,
+			result.PromptThroughput,
+			result.MinTtft,
+			result.MaxTtft))
+	}
+
+	file.WriteString("\n## Per-Request Metrics\n\n")
+	file.WriteString(FormatRequestMetricsTable(results))
+
+	if table := FormatTtftBreakdownTable(results); table != "" {
+		file.WriteString("\n## TTFT Breakdown (mean ms per request)\n\n")
+		file.WriteString(table)
+	}
+
+	if table := FormatErrorClassesTable(results); table != "" {
+		file.WriteString("\n## Failed Requests\n\n")
+		file.WriteString(table)
+	}
+
+	if table := FormatRunStatsTable(results); table != "" {
+		file.WriteString("\n## Run Statistics (generation throughput, tokens/s)\n\n")
+		file.WriteString(table)
 	}
 
 	fmt.Printf("Results saved to: %s\n\n", filename)
 }
+
+// FormatRequestMetricsTable renders the per-request throughput, request rate, goodput and
+// error rate of each concurrency level as a Markdown table.
+func FormatRequestMetricsTable(results []SpeedResult) string {
+	var table strings.Builder
+	table.WriteString("| Concurrency | Requests/s | P95 TTFT (s) | Per-User Mean (tokens/s) |   P50 |   P90 |   P99 | Goodput (%) | Goodput (req/s) | Error Rate (%) |\n")
+	table.WriteString("|-------------|------------|--------------|--------------------------|-------|-------|-------|-------------|-----------------|----------------|\n")
+	for _, result := range results {
+		table.WriteString(fmt.Sprintf("| %11d | %10.2f | %12.2f | %24.2f | %5.1f | %5.1f | %5.1f | %11.1f | %15.2f | %14.1f |\n",
+			result.Concurrency,
+			result.RequestsPerSecond,
+			result.P95Ttft,
+			result.PerUserSpeed,
+			result.PerUserSpeedP50,
+			result.PerUserSpeedP90,
+			result.PerUserSpeedP99,
+			result.GoodputRatio*100,
+			result.GoodputRate,
+			result.ErrorRate*100))
+	}
+	return table.String()
+}
+
+// FormatTtftBreakdownTable renders the TTFT phase breakdown of each concurrency level as
+// a Markdown table. It returns an empty string when no level has a breakdown.
+func FormatTtftBreakdownTable(results []SpeedResult) string {
+	var rows strings.Builder
+	for _, result := range results {
+		breakdown := result.TtftBreakdown
+		if breakdown == nil {
+			continue
+		}
+		rows.WriteString(fmt.Sprintf("| %11d | %8.2f | %12.2f | %8.2f | %12.2f | %16.2f | %16.2f | %9d | %12d |\n",
+			result.Concurrency,
+			breakdown.DnsMs,
+			breakdown.ConnectMs,
+			breakdown.TlsMs,
+			breakdown.HeadersMs,
+			breakdown.FirstChunkMs,
+			breakdown.FirstTokenMs,
+			breakdown.NewConnections,
+			breakdown.ReusedConnections))
+	}
+	if rows.Len() == 0 {
+		return ""
+	}
+
+	return "| Concurrency | DNS (ms) | Connect (ms) | TLS (ms) | Headers (ms) | First Chunk (ms) | First Token (ms) | New Conns | Reused Conns |\n" +
+		"|-------------|----------|--------------|----------|--------------|------------------|------------------|-----------|--------------|\n" +
+		rows.String()
+}
+
+// FormatErrorClassesTable renders the failed requests of each concurrency level by error
+// class as a Markdown table. It returns an empty string when no request failed.
+func FormatErrorClassesTable(results []SpeedResult) string {
+	var rows strings.Builder
+	for _, result := range results {
+		classes := make([]string, 0, len(result.ErrorClasses))
+		for class := range result.ErrorClasses {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			rows.WriteString(fmt.Sprintf("| %11d | %-16s | %8d |\n", result.Concurrency, class, result.ErrorClasses[class]))
+		}
+	}
+	if rows.Len() == 0 {
+		return ""
+	}
+
+	return "| Concurrency | Error Class      | Requests |\n" +
+		"|-------------|------------------|----------|\n" +
+		rows.String()
+}
+
+// FormatRunStatsTable renders the per-level generation throughput statistics of repeated
+// runs as a Markdown table. It returns an empty string when no level was repeated.
+func FormatRunStatsTable(results []SpeedResult) string {
+	var rows strings.Builder
+	for _, result := range results {
+		stats := result.GenerationSpeedStats
+		if stats == nil {
+			continue
+		}
+		outliers := "-"
+		if len(result.OutlierRuns) > 0 {
+			runs := make([]string, len(result.OutlierRuns))
+			for i, idx := range result.OutlierRuns {
+				runs[i] = fmt.Sprintf("#%d", idx+1)
+			}
+			outliers = strings.Join(runs, ", ")
+		}
+		rows.WriteString(fmt.Sprintf("| %11d | %4d | %10.2f | %10.2f | %10.2f | %10.2f | %6.1f%% | %-12s |\n",
+			result.Concurrency,
+			result.Runs,
+			stats.Mean,
+			stats.Median,
+			stats.Min,
+			stats.Max,
+			stats.CV*100,
+			outliers))
+	}
+	if rows.Len() == 0 {
+		return ""
+	}
+
+	return "| Concurrency | Runs |       Mean |     Median |        Min |        Max |      CV | Outlier Runs |\n" +
+		"|-------------|------|------------|------------|------------|------------|---------|--------------|\n" +
+		rows.String()
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit bounds the requests and tokens sent to an endpoint per minute. Zero leaves a
+// bound unchecked.
+type RateLimit struct {
+	RequestsPerMinute float64 `json:"requestsPerMinute,omitempty" yaml:"requests-per-minute,omitempty"`
+	TokensPerMinute   float64 `json:"tokensPerMinute,omitempty" yaml:"tokens-per-minute,omitempty"` // Prompt and completion tokens
+}
+
+// Enabled reports whether the limit bounds anything
+func (limit RateLimit) Enabled() bool {
+	return limit.RequestsPerMinute > 0 || limit.TokensPerMinute > 0
+}
+
+// rateLimitBurst is how many seconds of a rate a full bucket holds
+const rateLimitBurst = 10
+
+// tokenBucket refills at rate per second up to capacity. Reservations may take it below
+// zero; the reserving request then waits until the debt is repaid.
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	level    float64
+	updated  time.Time
+}
+
+func newTokenBucket(perMinute float64, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	rate := perMinute / 60
+	return &tokenBucket{rate: rate, capacity: rate * rateLimitBurst, level: rate * rateLimitBurst, updated: now}
+}
+
+// reserve takes amount from the bucket and returns how long the caller has to wait for it
+func (bucket *tokenBucket) reserve(amount float64, now time.Time) time.Duration {
+	if bucket == nil {
+		return 0
+	}
+	bucket.refill(now)
+	bucket.level -= amount
+	if bucket.level >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.level / bucket.rate * float64(time.Second))
+}
+
+// give returns amount to the bucket, e.g. of a cancelled reservation
+func (bucket *tokenBucket) give(amount float64, now time.Time) {
+	if bucket == nil {
+		return
+	}
+	bucket.refill(now)
+	bucket.level = math.Min(bucket.level+amount, bucket.capacity)
+}
+
+func (bucket *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(bucket.updated).Seconds(); elapsed > 0 {
+		bucket.level = math.Min(bucket.level+elapsed*bucket.rate, bucket.capacity)
+		bucket.updated = now
+	}
+}
+
+// RateLimiter spaces out requests to one endpoint with a token bucket for requests and one
+// for tokens. Requests wait in the order they arrive; a full bucket allows a burst of ten
+// seconds' worth. A nil RateLimiter never waits.
+type RateLimiter struct {
+	limit    RateLimit
+	mutex    sync.Mutex
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+// NewRateLimiter returns a limiter for limit, or nil when the limit bounds nothing
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if !limit.Enabled() {
+		return nil
+	}
+	now := time.Now()
+	return &RateLimiter{
+		limit:    limit,
+		requests: newTokenBucket(limit.RequestsPerMinute, now),
+		tokens:   newTokenBucket(limit.TokensPerMinute, now),
+	}
+}
+
+// Limit returns the configured limit
+func (limiter *RateLimiter) Limit() RateLimit {
+	if limiter == nil {
+		return RateLimit{}
+	}
+	return limiter.limit
+}
+
+// Wait blocks until a request expected to use tokens may be sent and returns how long it
+// waited. On cancellation the reservation is given back.
+func (limiter *RateLimiter) Wait(ctx context.Context, tokens int) (time.Duration, error) {
+	if limiter == nil {
+		return 0, nil
+	}
+	start := time.Now()
+	limiter.mutex.Lock()
+	delay := max(limiter.requests.reserve(1, start), limiter.tokens.reserve(float64(tokens), start))
+	limiter.mutex.Unlock()
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		now := time.Now()
+		limiter.mutex.Lock()
+		limiter.requests.give(1, now)
+		limiter.tokens.give(float64(tokens), now)
+		limiter.mutex.Unlock()
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// Settle corrects the tokens reserved by Wait once a request reported the tokens it used
+func (limiter *RateLimiter) Settle(reserved int, used int) {
+	if limiter == nil || limiter.tokens == nil || used == reserved {
+		return
+	}
+	now := time.Now()
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if used < reserved {
+		limiter.tokens.give(float64(reserved-used), now)
+	} else {
+		limiter.tokens.reserve(float64(used-reserved), now)
+	}
+}
+
+var (
+	sharedRateLimiters      = make(map[string]*RateLimiter)
+	sharedRateLimitersMutex sync.Mutex
+)
+
+// SharedRateLimiter returns the process-wide limiter of an endpoint key, e.g. a base URL
+// or service ID, so that concurrent measurements share one budget. A changed limit
+// replaces the limiter. It returns nil when limit bounds nothing.
+func SharedRateLimiter(key string, limit RateLimit) *RateLimiter {
+	sharedRateLimitersMutex.Lock()
+	defer sharedRateLimitersMutex.Unlock()
+	if !limit.Enabled() {
+		delete(sharedRateLimiters, key)
+		return nil
+	}
+	if limiter, ok := sharedRateLimiters[key]; ok && limiter.limit == limit {
+		return limiter
+	}
+	limiter := NewRateLimiter(limit)
+	sharedRateLimiters[key] = limiter
+	return limiter
+}
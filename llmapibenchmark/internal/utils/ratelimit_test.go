@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWaitsForTheBucketToRefill(t *testing.T) {
+	// 600 requests per minute hold a burst of 100 and refill one every 100ms
+	limiter := NewRateLimiter(RateLimit{RequestsPerMinute: 600})
+	for i := 0; i < 100; i++ {
+		if waited, err := limiter.Wait(context.Background(), 0); err != nil || waited != 0 {
+			t.Fatalf("expected request %d of the burst to pass, waited %v: %v", i, waited, err)
+		}
+	}
+	waited, err := limiter.Wait(context.Background(), 0)
+	if err != nil || waited < 50*time.Millisecond {
+		t.Errorf("expected the request after the burst to wait about 100ms, waited %v: %v", waited, err)
+	}
+
+	if NewRateLimiter(RateLimit{}) != nil {
+		t.Error("expected no limiter without a limit")
+	}
+	var unlimited *RateLimiter
+	if waited, err := unlimited.Wait(context.Background(), 1000); waited != 0 || err != nil {
+		t.Errorf("expected a nil limiter never to wait, waited %v: %v", waited, err)
+	}
+}
+
+func TestRateLimiterSettlesAndRefundsTokens(t *testing.T) {
+	// 6000 tokens per minute hold a burst of 1000
+	limiter := NewRateLimiter(RateLimit{TokensPerMinute: 6000})
+	if waited, _ := limiter.Wait(context.Background(), 1000); waited != 0 {
+		t.Fatalf("expected the burst to pass, waited %v", waited)
+	}
+	limiter.Settle(1000, 100)
+	if waited, _ := limiter.Wait(context.Background(), 800); waited != 0 {
+		t.Errorf("expected the unused tokens to be given back, waited %v", waited)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Wait(ctx, 5000); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to be cancelled, got %v", err)
+	}
+	if waited, _ := limiter.Wait(context.Background(), 50); waited != 0 {
+		t.Errorf("expected a cancelled reservation to be given back, waited %v", waited)
+	}
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	limit := RateLimit{RequestsPerMinute: 60}
+	first := SharedRateLimiter("test-endpoint", limit)
+	if second := SharedRateLimiter("test-endpoint", limit); second != first {
+		t.Error("expected the same limiter for the same endpoint")
+	}
+	if other := SharedRateLimiter("other-endpoint", limit); other == first {
+		t.Error("expected endpoints to have their own limiters")
+	}
+	if changed := SharedRateLimiter("test-endpoint", RateLimit{RequestsPerMinute: 120}); changed == first || changed.Limit().RequestsPerMinute != 120 {
+		t.Error("expected a changed limit to replace the limiter")
+	}
+	if SharedRateLimiter("test-endpoint", RateLimit{}) != nil {
+		t.Error("expected no limiter once the limit is removed")
+	}
+}
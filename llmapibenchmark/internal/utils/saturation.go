@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SLO holds the service level objectives a concurrency level must meet to count as sustainable.
+// Zero values leave the corresponding constraint unchecked.
+type SLO struct {
+	MaxP95Ttft      float64 `json:"max_p95_ttft,omitempty" yaml:"max-p95-ttft,omitempty"`             // Seconds
+	MinPerUserSpeed float64 `json:"min_per_user_speed,omitempty" yaml:"min-per-user-speed,omitempty"` // Tokens/s
+	MaxErrorRate    float64 `json:"max_error_rate" yaml:"max-error-rate"`                             // Fraction of requests, 0 means no failures allowed
+}
+
+// Violations lists the objectives that a measurement does not meet.
+func (slo SLO) Violations(result SpeedResult) []string {
+	var violations []string
+	if slo.MaxP95Ttft > 0 && result.P95Ttft > slo.MaxP95Ttft {
+		violations = append(violations, fmt.Sprintf("p95 TTFT %.2fs > %.2fs", result.P95Ttft, slo.MaxP95Ttft))
+	}
+	if slo.MinPerUserSpeed > 0 && result.PerUserSpeed < slo.MinPerUserSpeed {
+		violations = append(violations, fmt.Sprintf("per-user speed %.2f tokens/s < %.2f tokens/s", result.PerUserSpeed, slo.MinPerUserSpeed))
+	}
+	if result.ErrorRate > slo.MaxErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.2f%% > %.2f%%", result.ErrorRate*100, slo.MaxErrorRate*100))
+	}
+	return violations
+}
+
+// Search phases reported in SaturationStep
+const (
+	PhaseRamp   = "ramp"
+	PhaseBisect = "bisect"
+)
+
+// SaturationStep is one measured concurrency level of a saturation search.
+type SaturationStep struct {
+	Phase       string      `json:"phase" yaml:"phase"`
+	Concurrency int         `json:"concurrency" yaml:"concurrency"`
+	Passed      bool        `json:"passed" yaml:"passed"`
+	Violations  []string    `json:"violations,omitempty" yaml:"violations,omitempty"`
+	Result      SpeedResult `json:"result" yaml:"result"`
+}
+
+// SaturationResult is the outcome of a saturation search.
+type SaturationResult struct {
+	SLO SLO `json:"slo" yaml:"slo"`
+	// Highest concurrency that met every objective, 0 when even the start level failed
+	MaxSustainableConcurrency int `json:"max_sustainable_concurrency" yaml:"max-sustainable-concurrency"`
+	// Generation throughput (tokens/s) at MaxSustainableConcurrency. All of its requests met the SLO.
+	Goodput float64 `json:"goodput" yaml:"goodput"`
+	// True when the search stopped at the concurrency cap without finding a failing level
+	LimitReached bool             `json:"limit_reached" yaml:"limit-reached"`
+	Best         *SpeedResult     `json:"best,omitempty" yaml:"best,omitempty"`
+	Steps        []SaturationStep `json:"steps" yaml:"steps"`
+}
+
+// SaturationSearch finds the highest concurrency that meets an SLO. It doubles the
+// concurrency from StartConcurrency until a level fails (or MaxConcurrency is reached),
+// then bisects between the last passing and the first failing level.
+type SaturationSearch struct {
+	SLO              SLO
+	StartConcurrency int // Defaults to 1
+	MaxConcurrency   int // Defaults to 256
+
+	// Measure runs one concurrency level. Errors other than cancellation are recorded
+	// as a failed step rather than aborting the search.
+	Measure func(ctx context.Context, concurrency int) (SpeedResult, error)
+
+	// OnStep is called after every measured level, e.g. to report progress. Optional.
+	OnStep func(step SaturationStep)
+}
+
+// Run executes the search.
+func (search *SaturationSearch) Run(ctx context.Context) (SaturationResult, error) {
+	start := max(search.StartConcurrency, 1)
+	limit := search.MaxConcurrency
+	if limit <= 0 {
+		limit = 256
+	}
+	if start > limit {
+		return SaturationResult{}, fmt.Errorf("start concurrency %d exceeds max concurrency %d", start, limit)
+	}
+
+	result := SaturationResult{SLO: search.SLO}
+	measure := func(phase string, concurrency int) (bool, error) {
+		step := SaturationStep{Phase: phase, Concurrency: concurrency}
+		measurement, err := search.Measure(ctx, concurrency)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+				return false, err
+			}
+			step.Violations = []string{err.Error()}
+		} else {
+			step.Result = measurement
+			step.Violations = search.SLO.Violations(measurement)
+			step.Passed = len(step.Violations) == 0
+		}
+		if step.Passed && concurrency > result.MaxSustainableConcurrency {
+			best := measurement
+			result.MaxSustainableConcurrency = concurrency
+			result.Goodput = measurement.GenerationSpeed
+			result.Best = &best
+		}
+		result.Steps = append(result.Steps, step)
+		if search.OnStep != nil {
+			search.OnStep(step)
+		}
+		return step.Passed, nil
+	}
+
+	// Exponential ramp
+	lastPass, firstFail := 0, 0
+	for concurrency := start; ; concurrency = min(concurrency*2, limit) {
+		passed, err := measure(PhaseRamp, concurrency)
+		if err != nil {
+			return result, err
+		}
+		if !passed {
+			firstFail = concurrency
+			break
+		}
+		lastPass = concurrency
+		if concurrency == limit {
+			result.LimitReached = true
+			return result, nil
+		}
+	}
+
+	// Binary search between the last passing and the first failing level
+	for firstFail-lastPass > 1 {
+		mid := (lastPass + firstFail) / 2
+		passed, err := measure(PhaseBisect, mid)
+		if err != nil {
+			return result, err
+		}
+		if passed {
+			lastPass = mid
+		} else {
+			firstFail = mid
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeCapacity simulates a backend whose p95 TTFT exceeds 2s above the given concurrency.
+func fakeCapacity(capacity int, measured *[]int) func(context.Context, int) (SpeedResult, error) {
+	return func(_ context.Context, concurrency int) (SpeedResult, error) {
+		*measured = append(*measured, concurrency)
+		result := SpeedResult{Concurrency: concurrency, GenerationSpeed: float64(concurrency * 10), P95Ttft: 1}
+		if concurrency > capacity {
+			result.P95Ttft = 3
+		}
+		return result, nil
+	}
+}
+
+func TestSaturationSearch_RampThenBisect(t *testing.T) {
+	var measured []int
+	search := SaturationSearch{
+		SLO:            SLO{MaxP95Ttft: 2},
+		MaxConcurrency: 256,
+		Measure:        fakeCapacity(21, &measured),
+	}
+
+	result, err := search.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MaxSustainableConcurrency != 21 || result.Goodput != 210 || result.LimitReached {
+		t.Errorf("expected max sustainable 21 with goodput 210, got %+v", result)
+	}
+
+	// 1,2,4,8,16,32 ramp, then bisect 24,20,22,21
+	expected := []int{1, 2, 4, 8, 16, 32, 24, 20, 22, 21}
+	if len(measured) != len(expected) {
+		t.Fatalf("expected levels %v, got %v", expected, measured)
+	}
+	for i := range expected {
+		if measured[i] != expected[i] {
+			t.Fatalf("expected levels %v, got %v", expected, measured)
+		}
+	}
+}
+
+func TestSaturationSearch_LimitReached(t *testing.T) {
+	var measured []int
+	search := SaturationSearch{
+		SLO:            SLO{MaxP95Ttft: 2},
+		MaxConcurrency: 12,
+		Measure:        fakeCapacity(100, &measured),
+	}
+
+	result, err := search.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MaxSustainableConcurrency != 12 || !result.LimitReached {
+		t.Errorf("expected the cap of 12 to be reached, got %+v", result)
+	}
+}
+
+func TestSaturationSearch_MeasureErrorFailsStep(t *testing.T) {
+	search := SaturationSearch{
+		Measure: func(_ context.Context, concurrency int) (SpeedResult, error) {
+			if concurrency > 4 {
+				return SpeedResult{}, errors.New("backend unavailable")
+			}
+			return SpeedResult{Concurrency: concurrency}, nil
+		},
+	}
+
+	result, err := search.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MaxSustainableConcurrency != 4 {
+		t.Errorf("expected max sustainable 4, got %d", result.MaxSustainableConcurrency)
+	}
+}
+
+func TestSLOViolations(t *testing.T) {
+	slo := SLO{MaxP95Ttft: 2, MinPerUserSpeed: 20, MaxErrorRate: 0.01}
+	if violations := slo.Violations(SpeedResult{P95Ttft: 1.5, PerUserSpeed: 25, ErrorRate: 0}); len(violations) != 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+	if violations := slo.Violations(SpeedResult{P95Ttft: 2.5, PerUserSpeed: 15, ErrorRate: 0.05}); len(violations) != 3 {
+		t.Errorf("expected three violations, got %v", violations)
+	}
+}
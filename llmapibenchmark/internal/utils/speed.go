@@ -10,8 +10,8 @@ import (
 	"time"
 
 	"llmapibenchmark/internal/api"
+	"llmapibenchmark/internal/tracing"
 
-	"github.com/sashabaranov/go-openai"
 	"github.com/schollz/progressbar/v3"
 )
 
@@ -23,26 +23,116 @@ type SpeedMeasurement struct {
 	UseRandomInput bool
 	NumWords       int
 	MaxTokens      int
-	Latency        float64
 	Concurrency    int
+	Repetitions    int           // Number of runs of this level; values below 2 mean a single run
+	Cooldown       time.Duration // Pause between repeated runs
+	TolerateErrors bool          // Count failed requests in ErrorRate instead of failing the level
+	GoodputTargets GoodputTargets
+	Transport      api.TransportConfig // Connection pool, protocol, proxy and TLS settings
+	Timeouts       api.RequestTimeouts // Per-request deadlines; timed-out requests count as failed
+	Trace          TraceSink           // Optional, receives one record per request
+	TraceJobID     string              // Job ID written to trace records
+	Limiter        *RateLimiter        // Optional, shared by the measurements of one endpoint
+}
+
+// GoodputTargets are the per-request latency targets a request must meet to count towards
+// goodput. Zero values leave the corresponding target unchecked.
+type GoodputTargets struct {
+	Ttft float64 `json:"ttft,omitempty" yaml:"ttft,omitempty"` // Seconds to first token
+	Tpot float64 `json:"tpot,omitempty" yaml:"tpot,omitempty"` // Seconds per output token after the first
 }
 
 type SpeedResult struct {
-	Concurrency      int     `json:"concurrency" yaml:"concurrency"`
-	GenerationSpeed  float64 `json:"generation_speed" yaml:"generation-speed"`
-	PromptThroughput float64 `json:"prompt_throughput" yaml:"prompt-throughput"`
-	MaxTtft          float64 `json:"max_ttft" yaml:"max-ttft"`
-	MinTtft          float64 `json:"min_ttft" yaml:"min-ttft"`
+	Concurrency       int     `json:"concurrency" yaml:"concurrency"`
+	GenerationSpeed   float64 `json:"generation_speed" yaml:"generation-speed"`
+	PromptThroughput  float64 `json:"prompt_throughput" yaml:"prompt-throughput"`
+	MaxTtft           float64 `json:"max_ttft" yaml:"max-ttft"`
+	MinTtft           float64 `json:"min_ttft" yaml:"min-ttft"`
+	P95Ttft           float64 `json:"p95_ttft" yaml:"p95-ttft"`
+	PerUserSpeed      float64 `json:"per_user_speed" yaml:"per-user-speed"` // Mean decode tokens/s of a single request
+	PerUserSpeedP50   float64 `json:"per_user_speed_p50" yaml:"per-user-speed-p50"`
+	PerUserSpeedP90   float64 `json:"per_user_speed_p90" yaml:"per-user-speed-p90"`
+	PerUserSpeedP99   float64 `json:"per_user_speed_p99" yaml:"per-user-speed-p99"`
+	RequestsPerSecond float64 `json:"requests_per_second" yaml:"requests-per-second"` // Completed requests per second
+	GoodRequests      int     `json:"good_requests" yaml:"good-requests"`             // Completed requests meeting the goodput targets
+	GoodputRatio      float64 `json:"goodput_ratio" yaml:"goodput-ratio"`             // GoodRequests / Requests
+	GoodputRate       float64 `json:"goodput_rate" yaml:"goodput-rate"`               // GoodRequests per second
+	Requests          int     `json:"requests" yaml:"requests"`
+	FailedRequests    int     `json:"failed_requests" yaml:"failed-requests"`
+	ErrorRate         float64 `json:"error_rate" yaml:"error-rate"`               // FailedRequests / Requests
+	PromptTokens      int     `json:"prompt_tokens" yaml:"prompt-tokens"`         // Of the completed requests
+	CompletionTokens  int     `json:"completion_tokens" yaml:"completion-tokens"` // Of the completed requests
+	DurationSeconds   float64 `json:"duration_seconds" yaml:"duration-seconds"`   // Measurement window, summed over runs
+
+	// Time requests waited for the rate limiter before they were sent, not part of TTFT
+	LimitedRequests int     `json:"limited_requests,omitempty" yaml:"limited-requests,omitempty"`   // Requests that waited
+	LimiterWaitMean float64 `json:"limiter_wait_mean,omitempty" yaml:"limiter-wait-mean,omitempty"` // Seconds, over all requests
+	LimiterWaitMax  float64 `json:"limiter_wait_max,omitempty" yaml:"limiter-wait-max,omitempty"`   // Seconds
+
+	ErrorClasses  map[string]int `json:"error_classes,omitempty" yaml:"error-classes,omitempty"` // Failed requests by cause, e.g. "ttft_timeout"
+	TtftBreakdown *TtftBreakdown `json:"ttft_breakdown,omitempty" yaml:"ttft-breakdown,omitempty"`
+
+	// Populated only when the level was repeated (Repetitions > 1)
+	Runs                   int       `json:"runs,omitempty" yaml:"runs,omitempty"`
+	GenerationSpeedStats   *RunStats `json:"generation_speed_stats,omitempty" yaml:"generation-speed-stats,omitempty"`
+	PromptThroughputStats  *RunStats `json:"prompt_throughput_stats,omitempty" yaml:"prompt-throughput-stats,omitempty"`
+	GenerationSpeedSamples []float64 `json:"generation_speed_samples,omitempty" yaml:"generation-speed-samples,omitempty"`
+	OutlierRuns            []int     `json:"outlier_runs,omitempty" yaml:"outlier-runs,omitempty"` // Indexes into GenerationSpeedSamples
 }
 
 func roundToTwoDecimals(f float64) float64 {
 	return math.Round(f*100) / 100
 }
 
-// Run measures API generation throughput and TTFT.
-func (setup *SpeedMeasurement) Run(ctx context.Context, bar *progressbar.ProgressBar) (SpeedResult, error) {
-	config := openai.DefaultConfig(setup.ApiKey)
-	
+// Run measures API generation throughput and TTFT. When Repetitions is above one the level
+// is measured that many times, with an optional cool-down between runs, and the runs are
+// aggregated with AggregateSpeedResults.
+func (setup *SpeedMeasurement) Run(ctx context.Context, bar *progressbar.ProgressBar) (result SpeedResult, err error) {
+	ctx, span := tracing.Start(ctx, "concurrency level",
+		tracing.String("gen_ai.request.model", setup.ModelName),
+		tracing.Int("llm.concurrency", setup.Concurrency),
+		tracing.Int("llm.repetitions", max(setup.Repetitions, 1)))
+	defer func() {
+		span.SetAttributes(
+			tracing.Int("llm.requests", result.Requests),
+			tracing.Int("llm.failed_requests", result.FailedRequests),
+			tracing.Float("llm.generation_tokens_per_second", result.GenerationSpeed),
+			tracing.Float("llm.p95_ttft_seconds", result.P95Ttft))
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if setup.Repetitions <= 1 {
+		return setup.runOnce(ctx, bar, 0)
+	}
+
+	runs := make([]SpeedResult, 0, setup.Repetitions)
+	for i := 0; i < setup.Repetitions; i++ {
+		if i > 0 && setup.Cooldown > 0 {
+			select {
+			case <-ctx.Done():
+				return SpeedResult{}, ctx.Err()
+			case <-time.After(setup.Cooldown):
+			}
+		}
+
+		result, err := setup.runOnce(ctx, bar, i)
+		if err != nil {
+			return SpeedResult{}, fmt.Errorf("run %d/%d: %w", i+1, setup.Repetitions, err)
+		}
+		runs = append(runs, result)
+	}
+
+	aggregated := AggregateSpeedResults(runs)
+	if len(aggregated.OutlierRuns) > 0 {
+		log.Printf("⚠️ Concurrency %d: outlier runs %v (generation speeds %v)", setup.Concurrency, aggregated.OutlierRuns, aggregated.GenerationSpeedSamples)
+	}
+	return aggregated, nil
+}
+
+// runOnce performs a single measurement pass at the configured concurrency. run is the
+// repetition index recorded in traces.
+func (setup *SpeedMeasurement) runOnce(ctx context.Context, bar *progressbar.ProgressBar, run int) (SpeedResult, error) {
 	// Ensure Cloud Foundry GenAI services have the correct /v1 path
 	baseURL := setup.BaseUrl
 	if strings.Contains(baseURL, "genai-proxy") && !strings.Contains(baseURL, "/v1") {
@@ -57,17 +147,16 @@ func (setup *SpeedMeasurement) Run(ctx context.Context, bar *progressbar.Progres
 			log.Printf("🔧 Adjusted base URL for Cloud Foundry multi-model service: %s", baseURL)
 		}
 	}
-	
-	config.BaseURL = baseURL
-	client := openai.NewClientWithConfig(config)
 
-	var wg sync.WaitGroup
-	var responseTokens sync.Map
-	var promptTokens sync.Map
-	var ttfts sync.Map
-	var threadErrors sync.Map
+	// Clients share one transport per configuration, so connections outlive the pass
+	client, err := api.NewClient(baseURL, setup.ApiKey, setup.Transport)
+	if err != nil {
+		return SpeedResult{}, err
+	}
 
-	start := time.Now()
+	var wg sync.WaitGroup
+	samples := make([]requestSample, setup.Concurrency)
+	var traces traceWriter
 
 	// Check for cancellation before starting goroutines
 	select {
@@ -77,87 +166,262 @@ func (setup *SpeedMeasurement) Run(ctx context.Context, bar *progressbar.Progres
 	}
 
 	// Send requests concurrently (restored from debugging version)
+	passStart := time.Now()
 	for i := 0; i < setup.Concurrency; i++ {
 		wg.Add(1)
 		go func(index int) {
 			defer wg.Done()
-			
+
 			// Check for cancellation in each goroutine before making API call
 			select {
 			case <-ctx.Done():
-				threadErrors.Store(index, ctx.Err())
+				samples[index].err = ctx.Err()
 				return
 			default:
 			}
-			
-			var ttft float64
-			var completionTokens, inputTokens int
-			var err error
+
+			reserved := setup.expectedTokens()
+			waited, err := setup.Limiter.Wait(ctx, reserved)
+			samples[index].limiterWait = waited
+			if err != nil {
+				samples[index].err = err
+				return
+			}
+
+			var stats api.RequestStats
+			requestStarted(setup.ModelName)
 			if setup.UseRandomInput {
-				ttft, completionTokens, inputTokens, err = api.AskOpenAiRandomInput(ctx, client, setup.ModelName, setup.NumWords, setup.MaxTokens, bar)
+				stats, err = api.AskOpenAiRandomInput(ctx, client, setup.ModelName, setup.NumWords, setup.MaxTokens, setup.Timeouts, bar)
 			} else {
-				ttft, completionTokens, inputTokens, err = api.AskOpenAi(ctx, client, setup.ModelName, setup.Prompt, setup.MaxTokens, bar)
+				stats, err = api.AskOpenAi(ctx, client, setup.ModelName, setup.Prompt, setup.MaxTokens, setup.Timeouts, bar)
 			}
-			if err != nil {
-				threadErrors.Store(index, err)
-				return
+			requestFinished(setup.ModelName, stats, err)
+			if used := stats.PromptTokens + stats.CompletionTokens; used > 0 {
+				setup.Limiter.Settle(reserved, used)
 			}
-			ttfts.Store(index, ttft)
-			responseTokens.Store(index, completionTokens)
-			promptTokens.Store(index, inputTokens)
+			samples[index] = newRequestSample(stats, err)
+			samples[index].limiterWait = waited
+			setup.writeTrace(&traces, passStart, run, index, samples[index])
 		}(i)
 	}
 
 	wg.Wait()
-	duration := time.Since(start)
 
-	// Check if any errors occurred
+	// Check if any errors occurred. Timed-out requests are always counted rather than
+	// failing the level, even when every request timed out, other errors only when
+	// TolerateErrors is set and some request completed.
 	var errSlice []error
-	threadErrors.Range(func(key, value interface{}) bool {
-		errSlice = append(errSlice, value.(error))
-		return true
-	})
-	if len(errSlice) > 0 {
+	var errorClasses map[string]int
+	untolerated := 0
+	for _, sample := range samples {
+		if sample.err == nil {
+			continue
+		}
+		errSlice = append(errSlice, sample.err)
+		if errorClasses == nil {
+			errorClasses = make(map[string]int)
+		}
+		errorClasses[api.ClassifyError(sample.err)]++
+		if !api.IsTimeout(sample.err) {
+			untolerated++
+		}
+	}
+	if ctx.Err() != nil {
+		return SpeedResult{}, ctx.Err()
+	}
+	if untolerated > 0 && (len(errSlice) == len(samples) || !setup.TolerateErrors) {
 		return SpeedResult{}, fmt.Errorf("error measuring speed: %v", errSlice)
 	}
 
-	// Calculate total tokens
+	// Calculate totals and the measurement window over the successful requests
 	totalResponseTokens := 0
-	responseTokens.Range(func(_, value interface{}) bool {
-		totalResponseTokens += value.(int)
-		return true
-	})
-
 	totalPromptTokens := 0
-	promptTokens.Range(func(_, value interface{}) bool {
-		totalPromptTokens += value.(int)
-		return true
-	})
+	var ttfts, userSpeeds []float64
+	var phases []requestPhases
+	var windowStart, prefillEnd, windowEnd time.Time
+	completed, good := 0, 0
+	for _, sample := range samples {
+		if sample.err != nil {
+			continue
+		}
+		completed++
+		totalResponseTokens += sample.completionTokens
+		totalPromptTokens += sample.promptTokens
+		ttfts = append(ttfts, sample.ttft)
+		phases = append(phases, sample.phases)
+		if speed, ok := sample.userSpeed(); ok {
+			userSpeeds = append(userSpeeds, speed)
+		}
+		if sample.meets(setup.GoodputTargets) {
+			good++
+		}
+		if windowStart.IsZero() || sample.sentAt.Before(windowStart) {
+			windowStart = sample.sentAt
+		}
+		if sample.firstTokenAt.After(prefillEnd) {
+			prefillEnd = sample.firstTokenAt
+		}
+		if sample.doneAt.After(windowEnd) {
+			windowEnd = sample.doneAt
+		}
+	}
+	wallTime := windowEnd.Sub(windowStart).Seconds()
+	prefillTime := prefillEnd.Sub(windowStart).Seconds()
 
 	measurement := SpeedResult{}
 	measurement.Concurrency = setup.Concurrency
+	measurement.Requests = len(samples)
+	measurement.FailedRequests = len(errSlice)
+	measurement.ErrorRate = math.Round(float64(len(errSlice))/float64(len(samples))*10000) / 10000
+	measurement.ErrorClasses = errorClasses
+	measurement.PromptTokens = totalPromptTokens
+	measurement.CompletionTokens = totalResponseTokens
+	measurement.DurationSeconds = wallTime
+	measurement.LimitedRequests, measurement.LimiterWaitMean, measurement.LimiterWaitMax = summarizeLimiterWaits(samples)
 
-	// Calculate max and min TTFT
-	measurement.MaxTtft = 0.0
-	measurement.MinTtft = math.Inf(1)
-	ttfts.Range(func(_, value interface{}) bool {
-		ttft := value.(float64)
-		if ttft > measurement.MaxTtft {
-			measurement.MaxTtft = ttft
-		}
-		if ttft < measurement.MinTtft {
-			measurement.MinTtft = ttft
-		}
-		return true
-	})
-	measurement.MaxTtft = roundToTwoDecimals(measurement.MaxTtft)
-	measurement.MinTtft = roundToTwoDecimals(measurement.MinTtft)
+	// Calculate TTFT extremes and tail
+	ttftStats := SummarizeRuns(ttfts)
+	measurement.MaxTtft = ttftStats.Max
+	measurement.MinTtft = ttftStats.Min
+	measurement.P95Ttft = roundToTwoDecimals(Percentile(ttfts, 95))
+	measurement.TtftBreakdown = summarizeTtftBreakdown(phases)
+
+	// Calculate the mean decode speed seen by a single user (tokens/second)
+	measurement.PerUserSpeed = SummarizeRuns(userSpeeds).Mean
+	measurement.PerUserSpeedP50 = roundToTwoDecimals(Percentile(userSpeeds, 50))
+	measurement.PerUserSpeedP90 = roundToTwoDecimals(Percentile(userSpeeds, 90))
+	measurement.PerUserSpeedP99 = roundToTwoDecimals(Percentile(userSpeeds, 99))
+
+	// Calculate request rate and goodput
+	measurement.RequestsPerSecond = safeRate(float64(completed), wallTime)
+	measurement.GoodRequests = good
+	measurement.GoodputRatio = math.Round(float64(good)/float64(len(samples))*10000) / 10000
+	measurement.GoodputRate = safeRate(float64(good), wallTime)
 
-	// Calculate speed (tokens/second)
-	measurement.GenerationSpeed = roundToTwoDecimals(float64(totalResponseTokens) / (duration.Seconds() - setup.Latency/1000))
+	// Calculate speed (tokens/second) over the wall time of the pass
+	measurement.GenerationSpeed = safeRate(float64(totalResponseTokens), wallTime)
 
-	// Calculate Prompt Throughput
-	measurement.PromptThroughput = roundToTwoDecimals(float64(totalPromptTokens) / (measurement.MaxTtft - setup.Latency/1000))
+	// Calculate Prompt Throughput over the time until the last request saw its first token
+	measurement.PromptThroughput = safeRate(float64(totalPromptTokens), prefillTime)
 
 	return measurement, nil
 }
+
+// traceWriter serializes the trace records of a pass, which are written as its requests finish
+type traceWriter struct {
+	mutex  sync.Mutex
+	failed bool
+}
+
+// writeTrace hands the record of one request of a pass to the trace sink as soon as the
+// request finishes. Write errors are logged once and do not affect the measurement.
+func (setup *SpeedMeasurement) writeTrace(writer *traceWriter, passStart time.Time, run int, index int, sample requestSample) {
+	if setup.Trace == nil || (sample.stats.SentAt.IsZero() && sample.err != nil) {
+		return // Never sent, e.g. the request could not be built
+	}
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if writer.failed {
+		return
+	}
+	record := newTraceRecord(passStart, sample.stats, sample.err)
+	record.JobID = setup.TraceJobID
+	record.Model = setup.ModelName
+	record.Concurrency = setup.Concurrency
+	record.Run = run
+	record.RequestIndex = index
+	record.LimiterWaitMs = durationMs(sample.limiterWait)
+	if err := setup.Trace.WriteTrace(record); err != nil {
+		log.Printf("⚠️ Failed to write request trace: %v", err)
+		writer.failed = true
+	}
+}
+
+// safeRate divides a count by a duration in seconds, returning 0 for an empty window.
+func safeRate(count float64, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return roundToTwoDecimals(count / seconds)
+}
+
+// requestSample holds the outcome of one request in a measurement pass.
+type requestSample struct {
+	sentAt           time.Time
+	firstTokenAt     time.Time
+	doneAt           time.Time
+	ttft             float64 // seconds
+	duration         float64 // seconds, from sending the request to the end of the stream
+	completionTokens int
+	promptTokens     int
+	phases           requestPhases
+	stats            api.RequestStats // Raw timeline, also for failed requests
+	limiterWait      time.Duration    // Before the request was sent
+	err              error
+}
+
+func newRequestSample(stats api.RequestStats, err error) requestSample {
+	if err != nil {
+		return requestSample{stats: stats, err: err}
+	}
+	return requestSample{
+		sentAt:           stats.SentAt,
+		firstTokenAt:     stats.FirstTokenAt,
+		doneAt:           stats.DoneAt,
+		ttft:             stats.Ttft(),
+		duration:         stats.Duration(),
+		completionTokens: stats.CompletionTokens,
+		promptTokens:     stats.PromptTokens,
+		phases:           newRequestPhases(stats),
+		stats:            stats,
+	}
+}
+
+// expectedTokens returns the tokens the rate limiter reserves for a request: the prompt at
+// about 4 characters or 0.75 words per token, plus the maximum output
+func (setup *SpeedMeasurement) expectedTokens() int {
+	if setup.UseRandomInput {
+		return setup.NumWords*4/3 + setup.MaxTokens
+	}
+	return (len(setup.Prompt)+3)/4 + setup.MaxTokens
+}
+
+// summarizeLimiterWaits returns the requests that waited for the rate limiter and the mean
+// and longest wait in seconds
+func summarizeLimiterWaits(samples []requestSample) (int, float64, float64) {
+	limited := 0
+	var total, longest time.Duration
+	for _, sample := range samples {
+		if sample.limiterWait > 0 {
+			limited++
+			total += sample.limiterWait
+			longest = max(longest, sample.limiterWait)
+		}
+	}
+	if limited == 0 {
+		return 0, 0, 0
+	}
+	return limited, roundToTwoDecimals(total.Seconds() / float64(len(samples))), roundToTwoDecimals(longest.Seconds())
+}
+
+// userSpeed returns the decode speed of the request: output tokens after the first one,
+// divided by the time spent streaming them. It is undefined for one-token responses.
+func (sample requestSample) userSpeed() (float64, bool) {
+	decode := sample.duration - sample.ttft
+	if sample.completionTokens < 2 || decode <= 0 {
+		return 0, false
+	}
+	return float64(sample.completionTokens-1) / decode, true
+}
+
+// meets reports whether a completed request satisfies the goodput targets. The time per
+// output token is only checked for responses with more than one token.
+func (sample requestSample) meets(targets GoodputTargets) bool {
+	if targets.Ttft > 0 && sample.ttft > targets.Ttft {
+		return false
+	}
+	if speed, ok := sample.userSpeed(); ok && targets.Tpot > 0 && 1/speed > targets.Tpot {
+		return false
+	}
+	return true
+}
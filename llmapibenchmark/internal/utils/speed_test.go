@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"llmapibenchmark/internal/api"
+)
+
+func TestRequestSampleMeetsGoodputTargets(t *testing.T) {
+	// 1s to first token, then 100 more tokens in 2s: 50 tokens/s, TPOT 20ms
+	sample := requestSample{ttft: 1, duration: 3, completionTokens: 101}
+
+	if speed, ok := sample.userSpeed(); !ok || speed != 50 {
+		t.Fatalf("expected per-user speed 50, got %v (ok=%v)", speed, ok)
+	}
+
+	testCases := []struct {
+		targets  GoodputTargets
+		expected bool
+	}{
+		{GoodputTargets{}, true},
+		{GoodputTargets{Ttft: 2, Tpot: 0.05}, true},
+		{GoodputTargets{Ttft: 0.5}, false},
+		{GoodputTargets{Tpot: 0.01}, false},
+	}
+	for _, tc := range testCases {
+		if got := sample.meets(tc.targets); got != tc.expected {
+			t.Errorf("targets %+v: expected %v, got %v", tc.targets, tc.expected, got)
+		}
+	}
+}
+
+func TestRunCountsStalledStreamsAsTimeouts(t *testing.T) {
+	// Every second request stalls after its first chunk
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stall := requests.Add(1)%2 == 0
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hello\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		if stall {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":1}}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	setup := SpeedMeasurement{
+		BaseUrl:     server.URL,
+		ModelName:   "test",
+		Prompt:      "hi",
+		MaxTokens:   8,
+		Concurrency: 4,
+		Timeouts:    api.RequestTimeouts{Idle: 100 * time.Millisecond},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := setup.Run(ctx, nil)
+	if err != nil {
+		t.Fatalf("expected the level to finish, got %v", err)
+	}
+	if result.FailedRequests != 2 || result.ErrorClasses["idle_timeout"] != 2 {
+		t.Errorf("expected 2 idle timeouts, got %d failed with classes %v", result.FailedRequests, result.ErrorClasses)
+	}
+}
+
+func TestRunFinishesWhenEveryRequestTimesOut(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hello\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	setup := SpeedMeasurement{
+		BaseUrl:     server.URL,
+		ModelName:   "test",
+		Prompt:      "hi",
+		MaxTokens:   8,
+		Concurrency: 3,
+		Timeouts:    api.RequestTimeouts{Idle: 100 * time.Millisecond},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := setup.Run(ctx, nil)
+	if err != nil {
+		t.Fatalf("expected the level to finish, got %v", err)
+	}
+	if result.FailedRequests != 3 || result.ErrorRate != 1 || result.ErrorClasses["idle_timeout"] != 3 {
+		t.Errorf("expected 3 idle timeouts, got %d failed (rate %v) with classes %v", result.FailedRequests, result.ErrorRate, result.ErrorClasses)
+	}
+	if result.GenerationSpeed != 0 || result.PromptThroughput != 0 || result.RequestsPerSecond != 0 {
+		t.Errorf("expected no throughput, got %+v", result)
+	}
+}
@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// RunStats summarizes one metric across repeated runs of the same concurrency level.
+type RunStats struct {
+	Mean   float64 `json:"mean" yaml:"mean"`
+	Median float64 `json:"median" yaml:"median"`
+	Min    float64 `json:"min" yaml:"min"`
+	Max    float64 `json:"max" yaml:"max"`
+	StdDev float64 `json:"stddev" yaml:"stddev"`
+	CV     float64 `json:"cv" yaml:"cv"` // Coefficient of variation (stddev / mean), 0 when mean is 0
+}
+
+// SummarizeRuns computes mean, median, min, max, sample standard deviation and CV.
+func SummarizeRuns(values []float64) RunStats {
+	if len(values) == 0 {
+		return RunStats{}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mean, stddev := meanAndStdDev(sorted)
+
+	var median float64
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		median = (sorted[mid-1] + sorted[mid]) / 2
+	} else {
+		median = sorted[mid]
+	}
+
+	var cv float64
+	if mean != 0 {
+		cv = stddev / mean
+	}
+
+	return RunStats{
+		Mean:   roundToTwoDecimals(mean),
+		Median: roundToTwoDecimals(median),
+		Min:    roundToTwoDecimals(sorted[0]),
+		Max:    roundToTwoDecimals(sorted[len(sorted)-1]),
+		StdDev: roundToTwoDecimals(stddev),
+		CV:     math.Round(cv*10000) / 10000,
+	}
+}
+
+// Percentile returns the p-th percentile (0-100) of values using linear interpolation
+// between closest ranks. It returns 0 for an empty slice.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower < 0 {
+		return sorted[0]
+	}
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// meanAndStdDev returns the unrounded mean and sample standard deviation of values.
+func meanAndStdDev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
+
+// FlagOutlierRuns returns the indexes of runs whose value deviates from the median by more
+// than 3 scaled median absolute deviations. At least three runs are needed to flag anything.
+func FlagOutlierRuns(values []float64) []int {
+	if len(values) < 3 {
+		return nil
+	}
+
+	median := SummarizeRuns(values).Median
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	mad := SummarizeRuns(deviations).Median * 1.4826 // Consistent with stddev for normal data
+	if mad == 0 {
+		return nil
+	}
+
+	var outliers []int
+	for i, d := range deviations {
+		if d/mad > 3 {
+			outliers = append(outliers, i)
+		}
+	}
+	return outliers
+}
+
+// AggregateSpeedResults folds repeated measurements of one concurrency level into a single
+// SpeedResult. Throughput, rate, P95 TTFT and per-user speed fields carry the mean across
+// runs, TTFT fields carry the extremes, request counts are summed and the TTFT breakdown is
+// weighted by requests.
+func AggregateSpeedResults(runs []SpeedResult) SpeedResult {
+	if len(runs) == 0 {
+		return SpeedResult{}
+	}
+	if len(runs) == 1 {
+		return runs[0]
+	}
+
+	generation := make([]float64, len(runs))
+	prompt := make([]float64, len(runs))
+	aggregated := SpeedResult{
+		Concurrency: runs[0].Concurrency,
+		MinTtft:     math.Inf(1),
+		Runs:        len(runs),
+	}
+	p95Ttft := make([]float64, len(runs))
+	userSpeed := make([]float64, len(runs))
+	userP50 := make([]float64, len(runs))
+	userP90 := make([]float64, len(runs))
+	userP99 := make([]float64, len(runs))
+	requestRate := make([]float64, len(runs))
+	goodputRate := make([]float64, len(runs))
+	breakdowns := make([]*TtftBreakdown, len(runs))
+	limiterWait := make([]float64, len(runs))
+	for i, run := range runs {
+		generation[i] = run.GenerationSpeed
+		prompt[i] = run.PromptThroughput
+		p95Ttft[i] = run.P95Ttft
+		userSpeed[i] = run.PerUserSpeed
+		userP50[i] = run.PerUserSpeedP50
+		userP90[i] = run.PerUserSpeedP90
+		userP99[i] = run.PerUserSpeedP99
+		requestRate[i] = run.RequestsPerSecond
+		goodputRate[i] = run.GoodputRate
+		breakdowns[i] = run.TtftBreakdown
+		aggregated.GoodRequests += run.GoodRequests
+		aggregated.MaxTtft = math.Max(aggregated.MaxTtft, run.MaxTtft)
+		aggregated.MinTtft = math.Min(aggregated.MinTtft, run.MinTtft)
+		aggregated.Requests += run.Requests
+		aggregated.FailedRequests += run.FailedRequests
+		aggregated.PromptTokens += run.PromptTokens
+		aggregated.CompletionTokens += run.CompletionTokens
+		aggregated.DurationSeconds += run.DurationSeconds
+		aggregated.LimitedRequests += run.LimitedRequests
+		aggregated.LimiterWaitMax = math.Max(aggregated.LimiterWaitMax, run.LimiterWaitMax)
+		limiterWait[i] = run.LimiterWaitMean
+		for class, count := range run.ErrorClasses {
+			if aggregated.ErrorClasses == nil {
+				aggregated.ErrorClasses = make(map[string]int)
+			}
+			aggregated.ErrorClasses[class] += count
+		}
+	}
+	if aggregated.Requests > 0 {
+		aggregated.ErrorRate = math.Round(float64(aggregated.FailedRequests)/float64(aggregated.Requests)*10000) / 10000
+		aggregated.GoodputRatio = math.Round(float64(aggregated.GoodRequests)/float64(aggregated.Requests)*10000) / 10000
+	}
+	aggregated.P95Ttft = SummarizeRuns(p95Ttft).Mean
+	aggregated.PerUserSpeed = SummarizeRuns(userSpeed).Mean
+	aggregated.PerUserSpeedP50 = SummarizeRuns(userP50).Mean
+	aggregated.PerUserSpeedP90 = SummarizeRuns(userP90).Mean
+	aggregated.PerUserSpeedP99 = SummarizeRuns(userP99).Mean
+	aggregated.RequestsPerSecond = SummarizeRuns(requestRate).Mean
+	aggregated.GoodputRate = SummarizeRuns(goodputRate).Mean
+	aggregated.LimiterWaitMean = SummarizeRuns(limiterWait).Mean
+	aggregated.TtftBreakdown = mergeTtftBreakdowns(breakdowns)
+
+	generationStats := SummarizeRuns(generation)
+	promptStats := SummarizeRuns(prompt)
+	aggregated.GenerationSpeed = generationStats.Mean
+	aggregated.PromptThroughput = promptStats.Mean
+	aggregated.GenerationSpeedStats = &generationStats
+	aggregated.PromptThroughputStats = &promptStats
+	aggregated.GenerationSpeedSamples = generation
+	aggregated.OutlierRuns = FlagOutlierRuns(generation)
+
+	return aggregated
+}
+
+// WelchTest returns the t statistic and the two-sided p-value of Welch's t-test for the
+// means of two independent samples with unequal variances. The p-value is 1 when either
+// sample has fewer than two values.
+func WelchTest(a, b []float64) (t, p float64) {
+	if len(a) < 2 || len(b) < 2 {
+		return 0, 1
+	}
+
+	meanA, stdA := meanAndStdDev(a)
+	meanB, stdB := meanAndStdDev(b)
+	varA := stdA * stdA / float64(len(a))
+	varB := stdB * stdB / float64(len(b))
+	if varA+varB == 0 {
+		// No spread at all: any difference in means is systematic
+		if meanA != meanB {
+			return 0, 0
+		}
+		return 0, 1
+	}
+
+	t = (meanA - meanB) / math.Sqrt(varA+varB)
+
+	// Welch–Satterthwaite degrees of freedom
+	df := (varA + varB) * (varA + varB) /
+		(varA*varA/float64(len(a)-1) + varB*varB/float64(len(b)-1))
+
+	return t, regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// HolmAdjust applies the Holm–Bonferroni correction to p-values of a family of tests and
+// returns the adjusted p-values in the same order. An adjusted p-value below alpha rejects
+// its hypothesis with a family-wise error rate of at most alpha.
+func HolmAdjust(pValues []float64) []float64 {
+	order := make([]int, len(pValues))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return pValues[order[i]] < pValues[order[j]] })
+
+	adjusted := make([]float64, len(pValues))
+	var running float64
+	for rank, index := range order {
+		running = math.Max(running, math.Min(1, float64(len(pValues)-rank)*pValues[index]))
+		adjusted[index] = running
+	}
+	return adjusted
+}
+
+// regularizedIncompleteBeta returns I_x(a, b), evaluated with the continued fraction of
+// Numerical Recipes (betacf).
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	lgammaAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly only below the mean of the distribution
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(1-x, b, a)/b
+	}
+	return front * betaContinuedFraction(x, a, b) / a
+}
+
+func betaContinuedFraction(x, a, b float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.0; m <= 300; m++ {
+		// Even step
+		numerator := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// Odd step
+		numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-12 {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeRuns(t *testing.T) {
+	stats := SummarizeRuns([]float64{10, 12, 14, 16})
+
+	expected := RunStats{Mean: 13, Median: 13, Min: 10, Max: 16, StdDev: 2.58, CV: 0.1986}
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestFlagOutlierRuns(t *testing.T) {
+	if outliers := FlagOutlierRuns([]float64{100, 101, 99, 100, 40}); !reflect.DeepEqual(outliers, []int{4}) {
+		t.Errorf("expected run 4 to be flagged, got %v", outliers)
+	}
+	if outliers := FlagOutlierRuns([]float64{100, 40}); outliers != nil {
+		t.Errorf("expected no outliers with fewer than three runs, got %v", outliers)
+	}
+}
+
+func TestAggregateSpeedResults(t *testing.T) {
+	runs := []SpeedResult{
+		{Concurrency: 4, GenerationSpeed: 100, PromptThroughput: 50, MinTtft: 0.2, MaxTtft: 0.5},
+		{Concurrency: 4, GenerationSpeed: 110, PromptThroughput: 60, MinTtft: 0.1, MaxTtft: 0.4},
+		{Concurrency: 4, GenerationSpeed: 120, PromptThroughput: 70, MinTtft: 0.3, MaxTtft: 0.9},
+	}
+
+	result := AggregateSpeedResults(runs)
+	if result.Runs != 3 || result.GenerationSpeed != 110 || result.PromptThroughput != 60 {
+		t.Errorf("unexpected aggregate: %+v", result)
+	}
+	if result.MinTtft != 0.1 || result.MaxTtft != 0.9 {
+		t.Errorf("expected TTFT extremes 0.1/0.9, got %v/%v", result.MinTtft, result.MaxTtft)
+	}
+	if !reflect.DeepEqual(result.GenerationSpeedSamples, []float64{100, 110, 120}) {
+		t.Errorf("unexpected samples: %v", result.GenerationSpeedSamples)
+	}
+}
+
+func TestWelchTestPValue(t *testing.T) {
+	// t = -2 with 8 degrees of freedom
+	tStat, p := WelchTest([]float64{1, 2, 3, 4, 5}, []float64{3, 4, 5, 6, 7})
+	if math.Abs(tStat+2) > 1e-9 || math.Abs(p-0.0805) > 0.0005 {
+		t.Errorf("expected t=-2 and p=0.0805, got t=%v p=%v", tStat, p)
+	}
+	if _, p := WelchTest([]float64{100}, []float64{200}); p != 1 {
+		t.Errorf("expected single samples never to differ, got p=%v", p)
+	}
+	if _, p := WelchTest([]float64{100, 100}, []float64{120, 120}); p != 0 {
+		t.Errorf("expected a difference without spread to be systematic, got p=%v", p)
+	}
+}
+
+func TestHolmAdjust(t *testing.T) {
+	adjusted := HolmAdjust([]float64{0.01, 0.04, 0.03})
+	for i, expected := range []float64{0.03, 0.06, 0.06} {
+		if math.Abs(adjusted[i]-expected) > 1e-9 {
+			t.Errorf("expected adjusted p-values [0.03 0.06 0.06], got %v", adjusted)
+			break
+		}
+	}
+}
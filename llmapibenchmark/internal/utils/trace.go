@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"llmapibenchmark/internal/api"
+)
+
+// TraceRecord describes one request of a measurement pass. It is written as one JSON line
+// with a fixed set of keys, so a trace file loads directly into pandas or DuckDB. Times are
+// in milliseconds; start offsets are relative to the start of the pass and chunk offsets
+// to the moment the request was sent.
+type TraceRecord struct {
+	JobID            string    `json:"job_id"`
+	Model            string    `json:"model"`
+	Concurrency      int       `json:"concurrency"`
+	Run              int       `json:"run"` // Repetition of the concurrency level, from 0
+	RequestIndex     int       `json:"request_index"`
+	StartOffsetMs    float64   `json:"start_offset_ms"`
+	TtftMs           *float64  `json:"ttft_ms"` // null when no token arrived
+	DurationMs       float64   `json:"duration_ms"`
+	ChunkOffsetsMs   []float64 `json:"chunk_offsets_ms"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	FinishReason     string    `json:"finish_reason"`
+	HTTPStatus       int       `json:"http_status"`
+	ConnReused       bool      `json:"conn_reused"`
+	LimiterWaitMs    float64   `json:"limiter_wait_ms"` // Rate limiter wait before StartOffsetMs
+	Error            string    `json:"error"`
+	ErrorClass       string    `json:"error_class"`
+}
+
+// TraceSink receives one record per request. Implementations must be safe for concurrent use.
+type TraceSink interface {
+	WriteTrace(record TraceRecord) error
+}
+
+// newTraceRecord builds the trace record of a request sent in a pass that started at passStart.
+func newTraceRecord(passStart time.Time, stats api.RequestStats, err error) TraceRecord {
+	record := TraceRecord{
+		StartOffsetMs:    durationMs(stats.SentAt.Sub(passStart)),
+		DurationMs:       durationMs(stats.DoneAt.Sub(stats.SentAt)),
+		ChunkOffsetsMs:   make([]float64, len(stats.ChunkTimes)),
+		PromptTokens:     stats.PromptTokens,
+		CompletionTokens: stats.CompletionTokens,
+		FinishReason:     stats.FinishReason,
+		HTTPStatus:       stats.HTTPStatus,
+		ConnReused:       stats.ConnReused,
+	}
+	for i, chunkTime := range stats.ChunkTimes {
+		record.ChunkOffsetsMs[i] = durationMs(chunkTime.Sub(stats.SentAt))
+	}
+	if len(stats.ChunkTimes) > 0 {
+		ttft := durationMs(stats.FirstTokenAt.Sub(stats.SentAt))
+		record.TtftMs = &ttft
+	}
+	if err != nil {
+		record.Error = err.Error()
+		record.ErrorClass = api.ClassifyError(err)
+	}
+	return record
+}
+
+// JSONLTraceWriter writes trace records as JSON lines. Every record is written as it
+// arrives, so a trace file stays usable when a benchmark is interrupted.
+type JSONLTraceWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+// NewJSONLTraceWriter writes trace records to w.
+func NewJSONLTraceWriter(w io.Writer) *JSONLTraceWriter {
+	return &JSONLTraceWriter{encoder: json.NewEncoder(w)}
+}
+
+// CreateJSONLTraceFile creates (or truncates) a trace file at path.
+func CreateJSONLTraceFile(path string) (*JSONLTraceWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := NewJSONLTraceWriter(file)
+	writer.closer = file
+	return writer, nil
+}
+
+// WriteTrace appends one record.
+func (writer *JSONLTraceWriter) WriteTrace(record TraceRecord) error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	return writer.encoder.Encode(record)
+}
+
+// Close closes the file, if the writer owns one.
+func (writer *JSONLTraceWriter) Close() error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if writer.closer == nil {
+		return nil
+	}
+	return writer.closer.Close()
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunWritesOneTraceRecordPerRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hello\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" world\"},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2}}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+	setup := SpeedMeasurement{
+		BaseUrl:     server.URL,
+		ModelName:   "test",
+		Prompt:      "hi",
+		MaxTokens:   8,
+		Concurrency: 3,
+		Repetitions: 2,
+		Trace:       NewJSONLTraceWriter(&output),
+		TraceJobID:  "job-1",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := setup.Run(ctx, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every line carries the same keys, so it loads as one table
+	perRun := map[int]int{}
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		var fields map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		if len(fields) != 17 {
+			t.Errorf("expected 17 keys, got %d in %s", len(fields), scanner.Text())
+		}
+
+		var record TraceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		perRun[record.Run]++
+		if record.JobID != "job-1" || record.Model != "test" || record.Concurrency != 3 {
+			t.Errorf("unexpected identity fields: %+v", record)
+		}
+		if record.TtftMs == nil || len(record.ChunkOffsetsMs) != 2 || *record.TtftMs != record.ChunkOffsetsMs[0] {
+			t.Errorf("expected a TTFT matching the first of 2 chunks, got %+v", record)
+		}
+		if record.FinishReason != "stop" || record.HTTPStatus != 200 || record.PromptTokens != 3 || record.CompletionTokens != 2 || record.Error != "" {
+			t.Errorf("unexpected outcome fields: %+v", record)
+		}
+	}
+	if perRun[0] != 3 || perRun[1] != 3 {
+		t.Errorf("expected 3 records for each of 2 runs, got %v", perRun)
+	}
+}
+
+func TestTraceRecordOfFailedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"slow down"}}`)
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+	setup := SpeedMeasurement{
+		BaseUrl:        server.URL,
+		ModelName:      "test",
+		Prompt:         "hi",
+		MaxTokens:      8,
+		Concurrency:    1,
+		TolerateErrors: true,
+		Trace:          NewJSONLTraceWriter(&output),
+	}
+	setup.Run(context.Background(), nil) // Fails, every request was rejected
+
+	var record TraceRecord
+	if err := json.Unmarshal(output.Bytes(), &record); err != nil {
+		t.Fatalf("expected one trace record, got %q: %v", output.String(), err)
+	}
+	if record.HTTPStatus != 429 || record.ErrorClass != "http_429" || record.Error == "" {
+		t.Errorf("expected a rejected request, got %+v", record)
+	}
+	if record.TtftMs != nil || record.ChunkOffsetsMs == nil || len(record.ChunkOffsetsMs) != 0 {
+		t.Errorf("expected null TTFT and no chunks, got %+v", record)
+	}
+}
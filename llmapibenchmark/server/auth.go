@@ -0,0 +1,629 @@
+package server
+
+import (
+	"crypto"
+	"crypto/rsa"
+	_ "crypto/sha256" // Hashes of RS256, RS384 and RS512 tokens
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role grants access to the API. Each role includes the roles before it.
+type Role int
+
+// Roles, from least to most privileged
+const (
+	RoleNone   Role = iota
+	RoleViewer      // Reads jobs, results, schedules and metrics
+	RoleRunner      // Starts jobs and controls its own jobs and schedules
+	RoleAdmin       // Controls every job and schedule
+)
+
+var roleNames = map[Role]string{RoleNone: "none", RoleViewer: "viewer", RoleRunner: "runner", RoleAdmin: "admin"}
+
+func (role Role) String() string {
+	return roleNames[role]
+}
+
+// MarshalText writes the role's name
+func (role Role) MarshalText() ([]byte, error) {
+	return []byte(role.String()), nil
+}
+
+// parseRole returns the role with the given name
+func parseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if strings.EqualFold(strings.TrimSpace(name), roleName) {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q (viewer, runner or admin)", name)
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string `json:"subject"`
+	Role    Role   `json:"role"`
+	Method  string `json:"method"` // "token", "oidc", "anonymous" or "none" while auth is disabled
+}
+
+// Errors returned by authentication and authorization
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// errUnknownToken tells the chain to try the next Authenticator
+var errUnknownToken = errors.New("token not recognized")
+
+// Authenticator verifies bearer tokens. It returns errUnknownToken for tokens it does not
+// handle.
+type Authenticator interface {
+	Authenticate(token string) (*Principal, error)
+}
+
+// staticToken is an API token from AUTH_TOKENS or the auth service binding
+type staticToken struct {
+	Name  string
+	Role  Role
+	Token string
+}
+
+// staticTokenAuthenticator accepts a fixed set of API tokens
+type staticTokenAuthenticator struct {
+	tokens []staticToken
+}
+
+// Authenticate compares the token with every configured token in constant time
+func (a *staticTokenAuthenticator) Authenticate(token string) (*Principal, error) {
+	var match *staticToken
+	for i := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(a.tokens[i].Token), []byte(token)) == 1 {
+			match = &a.tokens[i]
+		}
+	}
+	if match == nil {
+		return nil, errUnknownToken
+	}
+	return &Principal{Subject: match.Name, Role: match.Role, Method: "token"}, nil
+}
+
+// oidcSettings configures the validation of JWTs from an OIDC issuer such as CF UAA
+type oidcSettings struct {
+	Issuer     string
+	Audience   string // Required in the aud claim when set
+	JWKSURL    string // Default: jwks_uri of the issuer's discovery document
+	RolesClaim string // Claim with the roles or scopes, default "scope"
+	RolePrefix string // Prefix of role scopes, default "llmbench."
+}
+
+// jwtHashes are the supported signature algorithms
+var jwtHashes = map[string]crypto.Hash{"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512}
+
+const (
+	jwtClockSkew       = time.Minute
+	jwksRefreshMinimum = time.Minute // Limits refetches for unknown key IDs
+)
+
+// oidcAuthenticator validates RSA-signed JWTs with the issuer's published keys
+type oidcAuthenticator struct {
+	settings  oidcSettings
+	client    *http.Client
+	mutex     sync.Mutex
+	keys      map[string]*rsa.PublicKey // By key ID
+	fetchedAt time.Time
+}
+
+func newOIDCAuthenticator(settings oidcSettings) *oidcAuthenticator {
+	if settings.RolesClaim == "" {
+		settings.RolesClaim = "scope"
+	}
+	return &oidcAuthenticator{settings: settings, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Authenticate verifies the token's signature, issuer, audience and lifetime and maps its
+// role scopes to the highest role
+func (a *oidcAuthenticator) Authenticate(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errUnknownToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	hash, ok := jwtHashes[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+	key, err := a.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	digest := hash.New()
+	digest.Write([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, hash, digest.Sum(nil), signature); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err := a.validateClaims(claims, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	principal := &Principal{Method: "oidc"}
+	for _, name := range []string{"user_name", "preferred_username", "email", "client_id", "sub"} {
+		if subject, _ := claims[name].(string); subject != "" {
+			principal.Subject = subject
+			break
+		}
+	}
+	for _, value := range claimValues(claims[a.settings.RolesClaim]) {
+		if !strings.HasPrefix(value, a.settings.RolePrefix) {
+			continue
+		}
+		if role, err := parseRole(strings.TrimPrefix(value, a.settings.RolePrefix)); err == nil && role > principal.Role {
+			principal.Role = role
+		}
+	}
+	return principal, nil
+}
+
+// validateClaims checks the registered claims of a token
+func (a *oidcAuthenticator) validateClaims(claims map[string]interface{}, now time.Time) error {
+	if issuer, _ := claims["iss"].(string); issuer != a.settings.Issuer {
+		return fmt.Errorf("unexpected issuer %q", issuer)
+	}
+	expires, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("missing exp claim")
+	}
+	if now.After(time.Unix(int64(expires), 0).Add(jwtClockSkew)) {
+		return errors.New("token expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(jwtClockSkew).Before(time.Unix(int64(notBefore), 0)) {
+		return errors.New("token not yet valid")
+	}
+	if a.settings.Audience == "" {
+		return nil
+	}
+	for _, audience := range claimValues(claims["aud"]) {
+		if audience == a.settings.Audience {
+			return nil
+		}
+	}
+	return fmt.Errorf("token is not for audience %q", a.settings.Audience)
+}
+
+// key returns the issuer's key with the given ID, fetching the keys when it is unknown
+func (a *oidcAuthenticator) key(kid string) (*rsa.PublicKey, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	find := func() *rsa.PublicKey {
+		if kid == "" && len(a.keys) == 1 {
+			for _, key := range a.keys {
+				return key
+			}
+		}
+		return a.keys[kid]
+	}
+	if key := find(); key != nil {
+		return key, nil
+	}
+	if time.Since(a.fetchedAt) >= jwksRefreshMinimum {
+		keys, err := a.fetchKeys()
+		a.fetchedAt = time.Now()
+		if err != nil {
+			AppLogger.Error("Failed to fetch OIDC signing keys: %v", err)
+			return nil, fmt.Errorf("%w: signing keys unavailable", ErrInvalidToken)
+		}
+		a.keys = keys
+		if key := find(); key != nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// fetchKeys reads the RSA keys of the issuer's JWKS
+func (a *oidcAuthenticator) fetchKeys() (map[string]*rsa.PublicKey, error) {
+	jwksURL := a.settings.JWKSURL
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(strings.TrimSuffix(a.settings.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("discovery document has no jwks_uri")
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := a.getJSON(jwksURL, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			AppLogger.Warn("Ignoring malformed OIDC signing key %q", jwk.Kid)
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RSA keys at %s", jwksURL)
+	}
+	AppLogger.Info("Loaded %d OIDC signing keys from %s", len(keys), jwksURL)
+	return keys, nil
+}
+
+func (a *oidcAuthenticator) getJSON(url string, target interface{}) error {
+	response, err := a.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, response.StatusCode)
+	}
+	return json.NewDecoder(response.Body).Decode(target)
+}
+
+// decodeJWTSegment decodes a base64url JSON segment of a JWT
+func decodeJWTSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// claimValues returns a claim that is a space-separated string or a list of strings
+func claimValues(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// authSettings configures authentication. Auth is enabled when tokens or an OIDC issuer
+// are configured.
+type authSettings struct {
+	Tokens        []staticToken
+	OIDC          oidcSettings
+	AnonymousRole Role // Role of requests without a token, RoleNone rejects them
+}
+
+// defaultAuthServiceName is the user-provided service that can hold the auth settings
+const defaultAuthServiceName = "llmbench-auth"
+
+// authSettingsFromEnv reads AUTH_* and the credentials of the auth user-provided service
+// (AUTH_SERVICE_NAME, default "llmbench-auth"). Environment variables take precedence.
+func authSettingsFromEnv() (authSettings, error) {
+	settings := authSettings{
+		OIDC: oidcSettings{
+			Issuer:     os.Getenv("AUTH_OIDC_ISSUER"),
+			Audience:   os.Getenv("AUTH_OIDC_AUDIENCE"),
+			JWKSURL:    os.Getenv("AUTH_OIDC_JWKS_URL"),
+			RolesClaim: os.Getenv("AUTH_OIDC_ROLES_CLAIM"),
+			RolePrefix: os.Getenv("AUTH_OIDC_ROLE_PREFIX"),
+		},
+	}
+	if _, set := os.LookupEnv("AUTH_OIDC_ROLE_PREFIX"); !set {
+		settings.OIDC.RolePrefix = "llmbench."
+	}
+
+	tokens, err := parseStaticTokens(os.Getenv("AUTH_TOKENS"))
+	if err != nil {
+		return settings, fmt.Errorf("AUTH_TOKENS: %w", err)
+	}
+	settings.Tokens = tokens
+
+	credentials, err := authServiceCredentials()
+	if err != nil {
+		return settings, err
+	}
+	if credentials != nil {
+		tokens, err := credentialTokens(credentials["tokens"])
+		if err != nil {
+			return settings, fmt.Errorf("auth service tokens: %w", err)
+		}
+		settings.Tokens = append(settings.Tokens, tokens...)
+		for key, field := range map[string]*string{
+			"oidc_issuer":   &settings.OIDC.Issuer,
+			"oidc_audience": &settings.OIDC.Audience,
+			"oidc_jwks_url": &settings.OIDC.JWKSURL,
+		} {
+			if value, _ := credentials[key].(string); *field == "" {
+				*field = value
+			}
+		}
+	}
+
+	if value := os.Getenv("AUTH_ANONYMOUS_ROLE"); value != "" {
+		if settings.AnonymousRole, err = parseRole(value); err != nil {
+			return settings, fmt.Errorf("AUTH_ANONYMOUS_ROLE: %w", err)
+		}
+	}
+	return settings, nil
+}
+
+// parseStaticTokens parses comma-separated "name:role:token" entries
+func parseStaticTokens(value string) ([]staticToken, error) {
+	var tokens []staticToken
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fields := strings.SplitN(entry, ":", 3)
+		if len(fields) != 3 || fields[0] == "" || fields[2] == "" {
+			return nil, errors.New(`entries must be "name:role:token"`)
+		}
+		role, err := parseRole(fields[1])
+		if err != nil || role == RoleNone {
+			return nil, fmt.Errorf("token %q: unknown role %q", fields[0], fields[1])
+		}
+		tokens = append(tokens, staticToken{Name: fields[0], Role: role, Token: fields[2]})
+	}
+	return tokens, nil
+}
+
+// credentialTokens reads the tokens of the auth service: a "name:role:token" list or
+// objects with name, role and token
+func credentialTokens(value interface{}) ([]staticToken, error) {
+	switch tokens := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return parseStaticTokens(tokens)
+	case []interface{}:
+		var entries []string
+		for _, item := range tokens {
+			object, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("tokens must be objects with name, role and token")
+			}
+			name, _ := object["name"].(string)
+			role, _ := object["role"].(string)
+			token, _ := object["token"].(string)
+			entries = append(entries, name+":"+role+":"+token)
+		}
+		return parseStaticTokens(strings.Join(entries, ","))
+	}
+	return nil, errors.New("tokens must be a string or a list")
+}
+
+// authServiceCredentials returns the credentials of the bound auth user-provided
+// service, matched by name or tag, or nil when it is not bound
+func authServiceCredentials() (map[string]interface{}, error) {
+	vcapServices := os.Getenv("VCAP_SERVICES")
+	if vcapServices == "" {
+		return nil, nil
+	}
+	name := os.Getenv("AUTH_SERVICE_NAME")
+	if name == "" {
+		name = defaultAuthServiceName
+	}
+	var services map[string][]VCAPService
+	if err := json.Unmarshal([]byte(vcapServices), &services); err != nil {
+		return nil, fmt.Errorf("failed to parse VCAP_SERVICES: %w", err)
+	}
+	for _, service := range services["user-provided"] {
+		if service.Name == name || service.InstanceName == name || containsString(service.Tags, name) {
+			return service.Credentials, nil
+		}
+	}
+	return nil, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// authState is the active authentication configuration
+type authState struct {
+	enabled        bool
+	authenticators []Authenticator
+	anonymousRole  Role
+}
+
+// newAuthState builds the authenticator chain of the settings
+func newAuthState(settings authSettings) *authState {
+	state := &authState{anonymousRole: settings.AnonymousRole}
+	if len(settings.Tokens) > 0 {
+		state.authenticators = append(state.authenticators, &staticTokenAuthenticator{tokens: settings.Tokens})
+	}
+	if settings.OIDC.Issuer != "" {
+		state.authenticators = append(state.authenticators, newOIDCAuthenticator(settings.OIDC))
+	}
+	state.enabled = len(state.authenticators) > 0
+	return state
+}
+
+// authConfig returns the authentication configuration, read once. Invalid settings keep
+// auth enabled and reject every token rather than opening the API.
+var authConfig = sync.OnceValue(func() *authState {
+	settings, err := authSettingsFromEnv()
+	if err != nil {
+		AppLogger.Error("Invalid authentication settings, rejecting all tokens: %v", err)
+		return &authState{enabled: true}
+	}
+	state := newAuthState(settings)
+	if !state.enabled {
+		AppLogger.Warn("Authentication is disabled: anyone can start benchmarks. Set AUTH_TOKENS or AUTH_OIDC_ISSUER.")
+	} else {
+		AppLogger.Info("Authentication enabled with %d static tokens, OIDC issuer %q, anonymous role %s",
+			len(settings.Tokens), settings.OIDC.Issuer, settings.AnonymousRole)
+	}
+	return state
+})
+
+// disabledAuthPrincipal is the caller of every request while auth is disabled
+var disabledAuthPrincipal = &Principal{Subject: "anonymous", Role: RoleAdmin, Method: "none"}
+
+// authenticate returns the caller of a request. The token comes from the Authorization
+// header or, for EventSource and WebSocket clients, the access_token query parameter.
+func (state *authState) authenticate(r *http.Request) (*Principal, error) {
+	if !state.enabled {
+		return disabledAuthPrincipal, nil
+	}
+	token := ""
+	if header := r.Header.Get("Authorization"); len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		token = strings.TrimSpace(header[7:])
+	} else {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		if state.anonymousRole == RoleNone {
+			return nil, ErrUnauthenticated
+		}
+		return &Principal{Subject: "anonymous", Role: state.anonymousRole, Method: "anonymous"}, nil
+	}
+
+	for _, authenticator := range state.authenticators {
+		principal, err := authenticator.Authenticate(token)
+		if errors.Is(err, errUnknownToken) {
+			continue
+		}
+		return principal, err
+	}
+	return nil, ErrInvalidToken
+}
+
+const principalKey = "principal"
+
+// RequireRole authenticates the request and rejects callers below role
+func RequireRole(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := authConfig().authenticate(c.Request)
+		if err != nil {
+			AppLogger.WarnWithFields("Rejected unauthenticated request", map[string]interface{}{
+				"path":     c.Request.URL.Path,
+				"clientIP": c.ClientIP(),
+				"error":    err.Error(),
+			})
+			c.Header("WWW-Authenticate", `Bearer realm="llmbench"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+				Error:   "Unauthorized",
+				Message: err.Error(),
+				Code:    http.StatusUnauthorized,
+			})
+			return
+		}
+		if principal.Role < role {
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+				Error:   "Forbidden",
+				Message: fmt.Sprintf("%s role required, %s has %s", role, principal.Subject, principal.Role),
+				Code:    http.StatusForbidden,
+			})
+			return
+		}
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// principalFrom returns the caller stored by RequireRole. Handlers mounted without it,
+// e.g. in tests, act for the disabled-auth principal.
+func principalFrom(c *gin.Context) *Principal {
+	if value, exists := c.Get(principalKey); exists {
+		return value.(*Principal)
+	}
+	return disabledAuthPrincipal
+}
+
+// WhoAmIHandler returns the caller of the request
+func WhoAmIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"principal":   principalFrom(c),
+		"authEnabled": authConfig().enabled,
+	})
+}
+
+// authorizeOwner allows admins and the owning runner
+func authorizeOwner(principal *Principal, owner string) error {
+	switch {
+	case principal.Role >= RoleAdmin:
+		return nil
+	case principal.Role < RoleRunner:
+		return fmt.Errorf("%w: runner role required", ErrForbidden)
+	case owner != principal.Subject:
+		return fmt.Errorf("%w: owned by another user", ErrForbidden)
+	}
+	return nil
+}
+
+// AuthorizeJob checks that principal may control a job: admins control every job,
+// runners their own ones
+func (jm *SimpleJobManager) AuthorizeJob(jobID string, principal *Principal) error {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	job, exists := jm.jobs[jobID]
+	if !exists {
+		return ErrJobNotFound
+	}
+	return authorizeOwner(principal, job.Owner)
+}
+
+// setJobOwner records who started a job
+func (jm *SimpleJobManager) setJobOwner(jobID string, owner string) {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+	if job, exists := jm.jobs[jobID]; exists {
+		job.Owner = owner
+	}
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useAuth replaces the authentication configuration for a test
+func useAuth(t *testing.T, settings authSettings) {
+	previous := authConfig
+	state := newAuthState(settings)
+	authConfig = func() *authState { return state }
+	t.Cleanup(func() { authConfig = previous })
+}
+
+// serveAs sends a JSON request with a bearer token
+func serveAs(router *gin.Engine, method string, path string, token string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	request := httptest.NewRequest(method, path, bytes.NewReader(data))
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestStaticTokensAndRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AUTH_TOKENS", "alice:runner:alice-token, dashboard:viewer:view-token")
+	t.Setenv("VCAP_SERVICES", `{"user-provided": [{"name": "llmbench-auth", "credentials": {
+		"tokens": [{"name": "ops", "role": "admin", "token": "ops-token"}]}}]}`)
+	settings, err := authSettingsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settings.Tokens) != 3 || settings.Tokens[2].Role != RoleAdmin {
+		t.Fatalf("expected tokens from the environment and the service, got %+v", settings.Tokens)
+	}
+	useAuth(t, settings)
+
+	router := gin.New()
+	router.GET("/api/auth/me", RequireRole(RoleViewer), WhoAmIHandler)
+	router.POST("/api/benchmark/async", RequireRole(RoleRunner), func(c *gin.Context) { c.Status(http.StatusAccepted) })
+
+	expected := []struct {
+		method, path, token string
+		code                int
+	}{
+		{http.MethodGet, "/api/auth/me", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/auth/me", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/api/auth/me", "view-token", http.StatusOK},
+		{http.MethodGet, "/api/auth/me?access_token=view-token", "", http.StatusOK},
+		{http.MethodPost, "/api/benchmark/async", "view-token", http.StatusForbidden},
+		{http.MethodPost, "/api/benchmark/async", "alice-token", http.StatusAccepted},
+		{http.MethodPost, "/api/benchmark/async", "ops-token", http.StatusAccepted},
+	}
+	for _, test := range expected {
+		if response := serveAs(router, test.method, test.path, test.token, nil); response.Code != test.code {
+			t.Errorf("%s %s with %q: expected %d, got %d: %s", test.method, test.path, test.token, test.code, response.Code, response.Body)
+		}
+	}
+
+	response := serveAs(router, http.MethodGet, "/api/auth/me", "alice-token", nil)
+	var me struct{ Principal Principal }
+	json.Unmarshal(response.Body.Bytes(), &me)
+	if me.Principal.Subject != "alice" || me.Principal.Method != "token" || !bytes.Contains(response.Body.Bytes(), []byte(`"role":"runner"`)) {
+		t.Errorf("unexpected principal %s", response.Body)
+	}
+
+	// Requests without a token can get a role of their own
+	settings.AnonymousRole = RoleViewer
+	useAuth(t, settings)
+	if response := serveAs(router, http.MethodGet, "/api/auth/me", "", nil); response.Code != http.StatusOK {
+		t.Errorf("expected anonymous viewers, got %d", response.Code)
+	}
+	if response := serveAs(router, http.MethodPost, "/api/benchmark/async", "", nil); response.Code != http.StatusForbidden {
+		t.Errorf("expected anonymous callers not to run benchmarks, got %d", response.Code)
+	}
+
+	t.Setenv("AUTH_TOKENS", "bob:superuser:token")
+	if _, err := authSettingsFromEnv(); err == nil {
+		t.Error("expected an unknown role to be rejected")
+	}
+}
+
+func TestOIDCTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(data []byte) string { return base64.RawURLEncoding.EncodeToString(data) }
+	var issuer string
+	uaa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/token_keys"})
+		case "/oauth/token/token_keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kty": "RSA", "kid": "key-1", "n": encode(key.N.Bytes()), "e": encode(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer uaa.Close()
+	issuer = uaa.URL + "/oauth/token"
+
+	sign := func(claims map[string]interface{}, kid string) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		signed := encode(header) + "." + encode(payload)
+		digest := sha256.Sum256([]byte(signed))
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		return signed + "." + encode(signature)
+	}
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss": issuer, "aud": []string{"llmbench", "openid"}, "sub": "1234", "user_name": "carol",
+			"exp": time.Now().Add(time.Hour).Unix(), "scope": []string{"openid", "llmbench.viewer", "llmbench.runner"},
+		}
+		for name, value := range changes {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	authenticator := newOIDCAuthenticator(oidcSettings{Issuer: issuer, Audience: "llmbench", RolePrefix: "llmbench."})
+	principal, err := authenticator.Authenticate(sign(claims(nil), "key-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Subject != "carol" || principal.Role != RoleRunner || principal.Method != "oidc" {
+		t.Errorf("unexpected principal %+v", principal)
+	}
+	if principal, _ := authenticator.Authenticate(sign(claims(map[string]interface{}{"scope": "openid"}), "key-1")); principal == nil || principal.Role != RoleNone {
+		t.Errorf("expected a token without role scopes to have no role, got %+v", principal)
+	}
+
+	rejected := map[string]string{
+		"expired":        sign(claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}), "key-1"),
+		"other issuer":   sign(claims(map[string]interface{}{"iss": "https://evil.example.com"}), "key-1"),
+		"other audience": sign(claims(map[string]interface{}{"aud": "cloud_controller"}), "key-1"),
+		"unknown key":    sign(claims(nil), "key-2"),
+		"bad signature":  sign(claims(nil), "key-1")[:40] + "x" + sign(claims(nil), "key-1")[41:],
+	}
+	for name, token := range rejected {
+		if _, err := authenticator.Authenticate(token); err == nil {
+			t.Errorf("%s: expected the token to be rejected", name)
+		}
+	}
+	if _, err := authenticator.Authenticate("static-token"); err != errUnknownToken {
+		t.Errorf("expected other tokens to be passed on, got %v", err)
+	}
+}
+
+func TestJobOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("API_KEY", "test")
+	t.Setenv("BASE_URL", "https://genai.example.com")
+	useAuth(t, authSettings{Tokens: []staticToken{
+		{Name: "alice", Role: RoleRunner, Token: "alice-token"},
+		{Name: "bob", Role: RoleRunner, Token: "bob-token"},
+		{Name: "dashboard", Role: RoleViewer, Token: "view-token"},
+		{Name: "ops", Role: RoleAdmin, Token: "ops-token"},
+	}})
+	jm := NewSimpleJobManager()
+	handlers := NewSimpleHandlers(jm)
+	router := gin.New()
+	router.POST("/api/jobs/:jobId/pause", RequireRole(RoleRunner), handlers.PauseJob)
+	router.POST("/api/jobs/:jobId/cancel", RequireRole(RoleRunner), handlers.CancelJob)
+	router.POST("/api/jobs/:jobId/rerun", RequireRole(RoleRunner), handlers.RerunJob)
+
+	jobID := jm.CreateJob(newExecutorRequest("https://genai.example.com", 1))
+	jm.setJobOwner(jobID, "alice")
+	jm.Enqueue(jobID, func() {})
+	path := "/api/jobs/" + jobID
+
+	expected := []struct {
+		path, token string
+		code        int
+	}{
+		{path + "/pause", "bob-token", http.StatusForbidden},
+		{path + "/cancel", "bob-token", http.StatusForbidden},
+		{path + "/rerun", "bob-token", http.StatusForbidden}, // The clone would keep alice's API keys
+		{path + "/cancel", "view-token", http.StatusForbidden},
+		{path + "/pause", "alice-token", http.StatusOK},
+		{path + "/cancel", "ops-token", http.StatusOK},
+		{"/api/jobs/missing/cancel", "bob-token", http.StatusNotFound},
+	}
+	for _, test := range expected {
+		if response := serveAs(router, http.MethodPost, test.path, test.token, nil); response.Code != test.code {
+			t.Errorf("%s with %q: expected %d, got %d: %s", test.path, test.token, test.code, response.Code, response.Body)
+		}
+	}
+
+	// Derived jobs belong to the caller
+	response := serveAs(router, http.MethodPost, path+"/rerun", "alice-token", nil)
+	var started struct{ JobID string }
+	json.Unmarshal(response.Body.Bytes(), &started)
+	if job, exists := jm.GetJob(started.JobID); !exists || job.Owner != "alice" {
+		t.Errorf("expected alice to own the rerun, got %d: %s", response.Code, response.Body)
+	}
+}
@@ -73,8 +73,8 @@ type ServiceInfo struct {
 
 // fetchModelsFromConfig fetches models from a config URL for multi-plan services
 func fetchModelsFromConfig(configURL, apiKey string) ([]AdvertisedModel, error) {
-	// Create HTTP client
-	client := &http.Client{}
+	// Create HTTP client with the shared transport settings
+	client := newServiceHTTPClient()
 
 	req, err := http.NewRequest("GET", configURL, nil)
 	if err != nil {
@@ -434,7 +434,6 @@ func GetAPIKeyForService(serviceID string) (string, error) {
 				AppLogger.DebugWithFields("Found API key from discovered service", map[string]interface{}{
 					"serviceID": serviceID,
 					"servicePlan": service.Plan,
-					"keyPreview": service.APIKey[:min(10, len(service.APIKey))]+"...",
 				})
 				return service.APIKey, nil
 			} else {
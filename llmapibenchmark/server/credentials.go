@@ -0,0 +1,245 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Requests never carry API keys. A model names its credential with secretId, or through
+// the service ID of a "serviceId|model" ID, and the key is looked up when the job runs,
+// so jobs, schedules and their API responses only ever hold the reference. A key is only
+// sent to the endpoint its credential belongs to, never to a base URL chosen by the caller.
+
+// ErrUnknownCredential is returned for a secret ID that names no credential
+var ErrUnknownCredential = errors.New("unknown credential")
+
+// ErrForeignEndpoint is returned for a credential used with a base URL it does not belong to
+var ErrForeignEndpoint = errors.New("credential does not belong to the endpoint")
+
+// credential is an API key and the base URLs it may be sent to
+type credential struct {
+	apiKey   string
+	baseURLs []string
+}
+
+// credentialURLKeys are the credentials of CredHub and user-provided services that hold
+// the base URL of their api_key
+var credentialURLKeys = []string{"api_base", "base_url", "baseUrl", "url"}
+
+// credentialServiceLabels are the VCAP_SERVICES labels searched for named secrets: bindings
+// of the CredHub service broker, whose credentials CF resolves from CredHub at startup,
+// and user-provided services
+var credentialServiceLabels = []string{"credhub", "user-provided"}
+
+// UnmarshalJSON decodes a model and remembers whether it carried a raw API key, which
+// validation rejects. The key itself is dropped.
+func (model *Model) UnmarshalJSON(data []byte) error {
+	type plainModel Model
+	var decoded struct {
+		plainModel
+		APIKey string `json:"apiKey"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*model = Model(decoded.plainModel)
+	model.rawAPIKey = decoded.APIKey != ""
+	return nil
+}
+
+// validateCredential rejects raw API keys, secret IDs that name no credential and secret
+// IDs whose credential does not belong to the model's base URL. field is the model's name
+// in error messages, e.g. "model1".
+func (model Model) validateCredential(field string) error {
+	if model.rawAPIKey {
+		return fmt.Errorf("%s.apiKey is not accepted: name a service binding or secret with %s.secretId", field, field)
+	}
+	if model.SecretID != "" {
+		if _, err := credentialForEndpoint(model.SecretID, model.BaseURL); err != nil {
+			return fmt.Errorf("%s.secretId: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// validateRequestCredentials checks the credentials of both models of a benchmark
+func validateRequestCredentials(request *BenchmarkRequest) error {
+	if err := request.Model1.validateCredential("model1"); err != nil {
+		return err
+	}
+	if request.Model2 != nil {
+		return request.Model2.validateCredential("model2")
+	}
+	return nil
+}
+
+// credentialForEndpoint returns the API key of secretID if it may be sent to baseURL: the
+// credential's own base URL, or one listed for it in CREDENTIAL_BASE_URLS.
+func credentialForEndpoint(secretID string, baseURL string) (string, error) {
+	resolved, err := resolveCredential(secretID)
+	if err != nil {
+		return "", err
+	}
+	configured, err := credentialBaseURLsFromEnv()
+	if err != nil {
+		return "", err
+	}
+	allowed := append(resolved.baseURLs, configured[secretID]...)
+	for _, candidate := range allowed {
+		if sameEndpoint(candidate, baseURL) {
+			return resolved.apiKey, nil
+		}
+	}
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("%w: %q has no base URL, list its endpoints in CREDENTIAL_BASE_URLS", ErrForeignEndpoint, secretID)
+	}
+	return "", fmt.Errorf("%w: %q may not be sent to %s", ErrForeignEndpoint, secretID, baseURL)
+}
+
+// credentialBaseURLsFromEnv reads CREDENTIAL_BASE_URLS, a JSON object of secret IDs and
+// the further base URLs their keys may be sent to, e.g. {"llm-key": ["https://vllm.example.com/v1"]}
+func credentialBaseURLsFromEnv() (map[string][]string, error) {
+	value := os.Getenv("CREDENTIAL_BASE_URLS")
+	if value == "" {
+		return nil, nil
+	}
+	var baseURLs map[string][]string
+	if err := json.Unmarshal([]byte(value), &baseURLs); err != nil {
+		return nil, fmt.Errorf("invalid CREDENTIAL_BASE_URLS: %w", err)
+	}
+	return baseURLs, nil
+}
+
+// sameEndpoint reports whether two base URLs have the same scheme, host and path
+func sameEndpoint(a, b string) bool {
+	parsedA, errA := url.Parse(strings.TrimSpace(a))
+	parsedB, errB := url.Parse(strings.TrimSpace(b))
+	if errA != nil || errB != nil || parsedA.Host == "" {
+		return false
+	}
+	return strings.EqualFold(parsedA.Scheme, parsedB.Scheme) && backendKey(a) == backendKey(b)
+}
+
+// resolveCredential returns the API key a secret ID names and its base URLs, looked up in
+// order as
+//
+//   - the ID of a bound GenAI service, as in the model IDs of /api/models
+//   - the name or tag of a bound CredHub or user-provided service with an api_key credential,
+//     whose api_base, base_url or url credential is the base URL
+//   - model1, model2 or generic for MODEL1_API_KEY, MODEL2_API_KEY or API_KEY, with
+//     MODEL1_BASE_URL, MODEL2_BASE_URL or BASE_URL (default https://api.openai.com/v1)
+func resolveCredential(secretID string) (credential, error) {
+	if IsVCAPServicesAvailable() {
+		if services, err := DiscoverServicesFromVCAP(); err == nil {
+			for _, service := range services {
+				if service.ID == secretID && service.APIKey != "" {
+					return credential{apiKey: service.APIKey, baseURLs: serviceBaseURLs(service)}, nil
+				}
+			}
+		}
+		bound, err := credentialFromBindings(secretID)
+		if err != nil {
+			return credential{}, err
+		}
+		if bound.apiKey != "" {
+			return bound, nil
+		}
+	}
+	if apiKey, err := GetAPIKeyForEnvironmentModel(secretID); err == nil {
+		resolved := credential{apiKey: apiKey}
+		environmentURL := map[string]string{"model1": "MODEL1_BASE_URL", "model2": "MODEL2_BASE_URL", "generic": "BASE_URL"}[secretID]
+		if baseURL := os.Getenv(environmentURL); baseURL != "" {
+			resolved.baseURLs = []string{baseURL}
+		} else if secretID == "generic" {
+			resolved.baseURLs = []string{"https://api.openai.com/v1"} // The default of parseGenericConfig
+		}
+		return resolved, nil
+	}
+	return credential{}, fmt.Errorf("%w %q", ErrUnknownCredential, secretID)
+}
+
+// serviceBaseURLs returns the base URLs of a GenAI service and its models
+func serviceBaseURLs(service ServiceInfo) []string {
+	var baseURLs []string
+	if service.BaseURL != "" {
+		baseURLs = append(baseURLs, service.BaseURL)
+	}
+	for _, model := range service.Models {
+		if model.BaseURL != "" {
+			baseURLs = append(baseURLs, model.BaseURL)
+		}
+	}
+	return baseURLs
+}
+
+// credentialFromBindings returns the api_key credential of the CredHub or user-provided
+// service bound under name, or an empty credential when there is none
+func credentialFromBindings(name string) (credential, error) {
+	var services map[string][]VCAPService
+	if err := json.Unmarshal([]byte(os.Getenv("VCAP_SERVICES")), &services); err != nil {
+		return credential{}, fmt.Errorf("failed to parse VCAP_SERVICES: %w", err)
+	}
+	for _, label := range credentialServiceLabels {
+		for _, service := range services[label] {
+			if service.Name != name && service.InstanceName != name && !containsString(service.Tags, name) {
+				continue
+			}
+			if _, unresolved := service.Credentials["credhub-ref"]; unresolved {
+				return credential{}, fmt.Errorf("credentials of %s have not been resolved from CredHub", name)
+			}
+			var bound credential
+			for _, key := range []string{"api_key", "apiKey"} {
+				if apiKey, ok := service.Credentials[key].(string); ok && apiKey != "" {
+					bound.apiKey = apiKey
+					break
+				}
+			}
+			if bound.apiKey == "" {
+				return credential{}, fmt.Errorf("service %s has no api_key credential", name)
+			}
+			for _, key := range credentialURLKeys {
+				if baseURL, ok := service.Credentials[key].(string); ok && baseURL != "" {
+					bound.baseURLs = append(bound.baseURLs, baseURL)
+				}
+			}
+			return bound, nil
+		}
+	}
+	return credential{}, nil
+}
+
+// secretPatterns match credentials in log lines. The first group of each is kept.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b((?:api[_-]?key|access[_-]?token|refresh[_-]?token|id[_-]?token|client[_-]?secret|password|authorization)"?\s*[:=]\s*"?)(?:bearer\s+)?[^\s",}&\[\]]+`),
+	regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+	regexp.MustCompile(`()\bsk-[A-Za-z0-9_\-]{16,}`),
+	regexp.MustCompile(`()\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`), // JWTs
+}
+
+// redactSecrets masks anything in s that looks like a bearer token or API key
+func redactSecrets(s string) string {
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}[REDACTED]")
+	}
+	return s
+}
+
+// redactingWriter masks secrets in everything written through it. The loggers write one
+// line per call, so no secret is split across writes.
+type redactingWriter struct {
+	out io.Writer
+}
+
+func (writer redactingWriter) Write(data []byte) (int, error) {
+	redacted := redactSecrets(string(data))
+	if _, err := writer.out.Write([]byte(redacted)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestsRejectRawAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jm := NewSimpleJobManager()
+	handlers := NewSimpleHandlers(jm)
+	router := gin.New()
+	router.POST("/api/benchmark/async", handlers.StartBenchmark)
+
+	request := map[string]interface{}{
+		"model1":            map[string]interface{}{"id": "a", "name": "model-a", "baseUrl": "https://genai.example.com", "apiKey": "sk-raw-key-in-request"},
+		"concurrencyLevels": []int{1},
+		"maxTokens":         8,
+		"prompt":            "hi",
+	}
+	response := serveJSON(router, http.MethodPost, "/api/benchmark/async", request)
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "model1.apiKey is not accepted") {
+		t.Fatalf("expected the raw key to be rejected, got %d: %s", response.Code, response.Body)
+	}
+	if len(jm.jobs) != 0 {
+		t.Error("expected no job for a rejected request")
+	}
+
+	delete(request["model1"].(map[string]interface{}), "apiKey")
+	request["model1"].(map[string]interface{})["secretId"] = "missing"
+	if response := serveJSON(router, http.MethodPost, "/api/benchmark/async", request); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "unknown credential") {
+		t.Errorf("expected an unknown secret ID to be rejected, got %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestResolveCredential(t *testing.T) {
+	t.Setenv("MODEL2_API_KEY", "sk-model2")
+	t.Setenv("MODEL2_BASE_URL", "https://model2.example.com/v1")
+	t.Setenv("VCAP_SERVICES", `{
+		"credhub": [{"name": "openai-key", "instance_name": "openai-key", "credentials": {"api_key": "sk-from-credhub", "api_base": "https://api.openai.com/v1"}}],
+		"user-provided": [
+			{"name": "vllm", "tags": ["llm-key"], "credentials": {"apiKey": "sk-user-provided"}},
+			{"name": "pending", "credentials": {"credhub-ref": "/c/broker/pending"}}
+		]
+	}`)
+
+	for secretID, expected := range map[string]string{"openai-key": "sk-from-credhub", "llm-key": "sk-user-provided", "model2": "sk-model2"} {
+		if resolved, err := resolveCredential(secretID); err != nil || resolved.apiKey != expected {
+			t.Errorf("expected %s to resolve to %s, got %q: %v", secretID, expected, resolved.apiKey, err)
+		}
+	}
+	if _, err := resolveCredential("pending"); err == nil || !strings.Contains(err.Error(), "CredHub") {
+		t.Errorf("expected an unresolved CredHub reference to fail, got %v", err)
+	}
+	if _, err := resolveCredential("missing"); !errors.Is(err, ErrUnknownCredential) {
+		t.Errorf("expected an unknown credential, got %v", err)
+	}
+
+	if apiKey := getAPIKeyForModel(Model{Name: "gpt", BaseURL: "https://api.openai.com/v1/", SecretID: "openai-key"}); apiKey != "sk-from-credhub" {
+		t.Errorf("expected the model's credential, got %q", apiKey)
+	}
+	data, _ := json.Marshal(Model{Name: "gpt", SecretID: "openai-key"})
+	if strings.Contains(string(data), "sk-") {
+		t.Errorf("expected only the reference in JSON, got %s", data)
+	}
+}
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	for input, leaked := range map[string]string{
+		"headers | Authorization=[Bearer abc.def-ghi]":   "abc.def-ghi",
+		`body={"apiKey": "plain-secret", "name": "gpt"}`: "plain-secret",
+		"url=https://host/stream?access_token=tok123":    "tok123",
+		"failed with key sk-abcdefghijklmnopqrstuvwx":    "sk-abcdefghijklmnopqrstuvwx",
+		"token eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJ4In0.sig": "eyJhbGciOiJSUzI1NiJ9",
+	} {
+		if redacted := redactSecrets(input); strings.Contains(redacted, leaked) || !strings.Contains(redacted, "[REDACTED]") {
+			t.Errorf("expected %q to be masked in %q", leaked, redacted)
+		}
+	}
+	if message := "Created job with maxTokens=256 for model gpt"; redactSecrets(message) != message {
+		t.Errorf("expected ordinary messages to be kept, got %q", redactSecrets(message))
+	}
+
+	var output bytes.Buffer
+	logger := NewLogger()
+	logger.info.SetOutput(redactingWriter{out: &output})
+	logger.InfoWithFields("Calling model", map[string]interface{}{"authorization": "Bearer secret-token"})
+	if strings.Contains(output.String(), "secret-token") {
+		t.Errorf("expected the logger to mask the token, got %q", output.String())
+	}
+}
+
+func TestCredentialsOnlyGoToTheirEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("API_KEY", "sk-generic")
+	t.Setenv("BASE_URL", "https://llm.example.com/v1")
+	t.Setenv("VCAP_SERVICES", `{"user-provided": [{"name": "vllm", "credentials": {"api_key": "sk-vllm"}}]}`)
+	router := gin.New()
+	router.POST("/api/benchmark/async", NewSimpleHandlers(NewSimpleJobManager()).StartBenchmark)
+
+	request := map[string]interface{}{
+		"model1":            map[string]interface{}{"id": "a", "name": "model-a", "baseUrl": "https://attacker.example.net/v1", "secretId": "generic"},
+		"concurrencyLevels": []int{1},
+		"maxTokens":         8,
+		"prompt":            "hi",
+	}
+	response := serveJSON(router, http.MethodPost, "/api/benchmark/async", request)
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "does not belong to the endpoint") {
+		t.Errorf("expected a foreign base URL to be rejected, got %d: %s", response.Code, response.Body)
+	}
+	if apiKey := getAPIKeyForModel(Model{Name: "model-a", BaseURL: "https://attacker.example.net/v1"}); apiKey != "" {
+		t.Errorf("expected no key for a foreign endpoint, got %q", apiKey)
+	}
+	if apiKey := getAPIKeyForModel(Model{Name: "model-a", BaseURL: "http://llm.example.com/v1"}); apiKey != "" {
+		t.Errorf("expected no key for another scheme, got %q", apiKey)
+	}
+	if apiKey := getAPIKeyForModel(Model{Name: "model-a", BaseURL: "https://LLM.example.com/v1/"}); apiKey != "sk-generic" {
+		t.Errorf("expected the generic key for its own endpoint, got %q", apiKey)
+	}
+
+	// A binding without a base URL needs CREDENTIAL_BASE_URLS
+	if _, err := credentialForEndpoint("vllm", "https://vllm.example.com/v1"); !errors.Is(err, ErrForeignEndpoint) {
+		t.Errorf("expected a credential without base URL to be refused, got %v", err)
+	}
+	t.Setenv("CREDENTIAL_BASE_URLS", `{"vllm": ["https://vllm.example.com/v1"]}`)
+	if apiKey, err := credentialForEndpoint("vllm", "https://vllm.example.com/v1"); err != nil || apiKey != "sk-vllm" {
+		t.Errorf("expected the configured endpoint to get the key, got %q: %v", apiKey, err)
+	}
+}
@@ -0,0 +1,518 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"llmapibenchmark/internal/utils"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// ExecutionEventType identifies the kind of an ExecutionEvent
+type ExecutionEventType string
+
+const (
+	EventLevelStarted ExecutionEventType = "level_started" // A (model, concurrency) level began
+	EventRequestDone  ExecutionEventType = "request_done"  // A single request of a level finished
+	EventLevelDone    ExecutionEventType = "level_done"    // A level finished and has a result
+	EventLevelSkipped ExecutionEventType = "level_skipped" // Stopped by ExecutionControl, not measured
+	EventPlanChanged  ExecutionEventType = "plan_changed"  // ExecutionControl changed the remaining levels
+	EventJobDone      ExecutionEventType = "job_done"      // The plan finished, failed or was cancelled
+)
+
+// ExecutionPlan is everything the Executor needs to run a benchmark
+type ExecutionPlan struct {
+	JobID       string
+	Request     BenchmarkRequest
+	Groups      [][]BenchmarkStep // Steps in a group run simultaneously
+	Schedule    ScheduleMetadata
+	Measurement utils.MeasurementModel
+	Finished    []LevelResult // Levels measured by an earlier attempt; they are not run again
+}
+
+// LevelResult is the result of one measured (model, concurrency) level
+type LevelResult struct {
+	ModelIndex int               `json:"modelIndex"`
+	LevelIndex int               `json:"levelIndex"` // Position in the request's ConcurrencyLevels
+	Result     ConcurrencyResult `json:"result"`
+}
+
+// NewExecutionPlan orders the levels of a request according to its schedule
+func NewExecutionPlan(jobID string, request BenchmarkRequest) ExecutionPlan {
+	seed := scheduleSeed(request)
+	groups := planBenchmarkSteps(request, seed)
+	return ExecutionPlan{
+		JobID:       jobID,
+		Request:     request,
+		Groups:      groups,
+		Schedule:    newScheduleMetadata(request, seed, groups),
+		Measurement: utils.DescribeMeasurement(),
+	}
+}
+
+// TotalSteps returns the number of levels in the plan
+func (plan ExecutionPlan) TotalSteps() int {
+	total := 0
+	for _, group := range plan.Groups {
+		total += len(group)
+	}
+	return total
+}
+
+// ExecutionEvent reports the progress of a plan. Completed and Total count levels.
+type ExecutionEvent struct {
+	Type      ExecutionEventType
+	JobID     string
+	Completed int
+	Total     int
+	Step      *BenchmarkStep     // level_started, request_done, level_done, level_skipped
+	Request   *utils.TraceRecord // request_done
+	Result    *ConcurrencyResult // level_done
+	Output    *ExecutionResult   // job_done, partial when Err is set, nil without any level
+	Err       error              // job_done, context.Canceled when cancelled
+	Levels    []int              // plan_changed, the new concurrency levels
+}
+
+// Cancelled reports whether a job_done event ended the plan early because of cancellation
+func (event ExecutionEvent) Cancelled() bool {
+	return errors.Is(event.Err, context.Canceled)
+}
+
+// ExecutionResult is the outcome of a plan. A partial result holds the levels that
+// finished before a failure or cancellation.
+type ExecutionResult struct {
+	Model1      *BenchmarkResult
+	Model2      *BenchmarkResult
+	Comparison  *Comparison
+	Levels      int // Concurrency levels per model
+	Schedule    ScheduleMetadata
+	Measurement utils.MeasurementModel
+	Finished    []LevelResult   // Every measured level, including those of earlier attempts
+	Skipped     []BenchmarkStep // Levels skipped while the plan ran
+	Partial     bool
+}
+
+// TotalLevels returns the number of levels of every model in the plan, without skipped ones
+func (result *ExecutionResult) TotalLevels() int {
+	if result.Model2 != nil {
+		return 2*result.Levels - len(result.Skipped)
+	}
+	return result.Levels - len(result.Skipped)
+}
+
+// Response returns the result in the shape of the synchronous benchmark endpoint
+func (result *ExecutionResult) Response() ComparisonResponse {
+	return ComparisonResponse{
+		Model1:     result.Model1,
+		Model2:     result.Model2,
+		Comparison: result.Comparison,
+	}
+}
+
+// JobResult returns the result in the shape stored on asynchronous jobs
+func (result *ExecutionResult) JobResult() map[string]interface{} {
+	schedule := result.Schedule
+	totalResults := len(result.Model1.Results)
+	jobResult := map[string]interface{}{
+		"model1": modelJobResult(result.Model1),
+		"model2": nil,
+		"metadata": JobMetadata{
+			ScheduleMetadata: &schedule,
+			Measurement:      result.Measurement,
+		},
+		"summary": map[string]interface{}{
+			"total_concurrency_levels": result.Levels,
+			"total_results":            totalResults,
+		},
+	}
+	if result.Partial {
+		jobResult["partial"] = true
+	}
+	if len(result.Skipped) > 0 {
+		skipped := make([]string, len(result.Skipped))
+		for i, step := range result.Skipped {
+			skipped[i] = step.Label()
+		}
+		jobResult["skippedLevels"] = skipped
+	}
+	if result.Model2 != nil {
+		jobResult["model2"] = modelJobResult(result.Model2)
+		jobResult["comparison"] = result.Comparison
+		jobResult["summary"].(map[string]interface{})["total_results"] = totalResults + len(result.Model2.Results)
+	}
+	return jobResult
+}
+
+// modelJobResult returns the results of one model in the shape stored on jobs
+func modelJobResult(result *BenchmarkResult) map[string]interface{} {
+	modelResult := map[string]interface{}{
+		"model":   result.Model,
+		"results": result.Results,
+	}
+	if result.Cost != nil {
+		modelResult["cost"] = result.Cost
+	}
+	return modelResult
+}
+
+// Executor runs benchmark plans. The SSE jobs, the WebSocket hub and the synchronous
+// endpoint are adapters that consume its events.
+type Executor struct {
+	Trace          utils.TraceSink  // Optional, receives every request record
+	ProgressOutput io.Writer        // Optional, draws a progress bar per level
+	Control        ExecutionControl // Optional, steers the plan while it runs
+}
+
+// ExecutionControl steers a running plan between and during groups of levels
+type ExecutionControl interface {
+	// BeforeGroup is called before each group. It may block, e.g. while the job is
+	// paused, and returns the concurrency levels to run instead of those not started
+	// yet, or nil to keep them. An error stops the plan like a cancellation.
+	BeforeGroup(ctx context.Context) (remaining []int, err error)
+	// GroupContext returns the context of a group's levels. Cancelling it skips them.
+	GroupContext(ctx context.Context) (context.Context, context.CancelFunc)
+}
+
+// Execute runs every level of the plan and returns the result. emit may be nil; it is
+// never called concurrently and always receives a final job_done event.
+func (executor *Executor) Execute(ctx context.Context, plan ExecutionPlan, emit func(ExecutionEvent)) (*ExecutionResult, error) {
+	var emitMutex sync.Mutex
+	send := func(event ExecutionEvent) {
+		if emit == nil {
+			return
+		}
+		event.JobID = plan.JobID
+		emitMutex.Lock()
+		defer emitMutex.Unlock()
+		emit(event)
+	}
+
+	result, err := executor.execute(ctx, plan, send)
+	if err != nil && len(result.Finished) == 0 {
+		result = nil
+	}
+	done := ExecutionEvent{Type: EventJobDone, Total: plan.TotalSteps(), Output: result, Err: err}
+	if result != nil {
+		done.Total = result.TotalLevels()
+	}
+	if err == nil {
+		done.Completed = done.Total
+	} else if ctx.Err() != nil {
+		done.Err = context.Canceled
+	}
+	send(done)
+	return result, done.Err
+}
+
+// execute runs the plan. On failure it returns the error together with a partial result.
+func (executor *Executor) execute(ctx context.Context, plan ExecutionPlan, send func(ExecutionEvent)) (*ExecutionResult, error) {
+	request := plan.Request
+	modelContexts, endModelSpans := startModelSpans(ctx, map[int]*Model{1: &request.Model1, 2: request.Model2})
+	defer endModelSpans()
+
+	// Levels are tracked by (model, concurrency) because the control may change the list
+	run := newRanLevels(plan)
+	finished := append([]LevelResult(nil), plan.Finished...)
+	var skipped []BenchmarkStep
+	completed := len(finished)
+	total := func() int { return plan.TotalSteps() - len(skipped) }
+
+	for g := 0; g < len(plan.Groups); g++ {
+		var pending []BenchmarkStep
+		for _, step := range plan.Groups[g] {
+			if !run.has(step) {
+				pending = append(pending, step)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			AppLogger.InfoWithContext(&LogContext{JobID: plan.JobID}, "Job cancelled before %s", pending[0].Label())
+			return newExecutionResult(plan, finished, skipped, true), err
+		}
+
+		groupCtx, skipGroup := ctx, context.CancelFunc(func() {})
+		if executor.Control != nil {
+			remaining, err := executor.Control.BeforeGroup(ctx)
+			if err != nil {
+				AppLogger.InfoWithContext(&LogContext{JobID: plan.JobID}, "Job stopped before %s: %v", pending[0].Label(), err)
+				return newExecutionResult(plan, finished, skipped, true), err
+			}
+			if remaining != nil {
+				plan, finished = replan(plan, run, finished, remaining)
+				AppLogger.InfoWithContext(&LogContext{JobID: plan.JobID}, "Concurrency levels changed to %v", plan.Request.ConcurrencyLevels)
+				send(ExecutionEvent{Type: EventPlanChanged, Completed: completed, Total: total(), Levels: plan.Request.ConcurrencyLevels})
+				g = -1 // Start over with the new groups; levels that ran are passed over
+				continue
+			}
+			groupCtx, skipGroup = executor.Control.GroupContext(ctx)
+		}
+		for i := range pending {
+			send(ExecutionEvent{Type: EventLevelStarted, Completed: completed, Total: total(), Step: &pending[i]})
+		}
+
+		results := make([]ConcurrencyResult, len(pending))
+		errs := make([]error, len(pending))
+		var wg sync.WaitGroup
+		for i := range pending {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				step := &pending[i]
+				trace := &eventTraceSink{next: executor.Trace, step: step, send: send, completed: completed, total: total()}
+				stepCtx := modelContexts[step.ModelIndex]
+				if groupCtx != ctx {
+					stepCtx = mergeCancel(stepCtx, groupCtx)
+				}
+				results[i], errs[i] = executor.runStep(stepCtx, plan, *step, trace)
+			}(i)
+		}
+		wg.Wait()
+
+		// A skipped group is dropped whatever its levels measured
+		skippedGroup := groupCtx.Err() != nil && ctx.Err() == nil
+		skipGroup()
+		if skippedGroup {
+			for i, step := range pending {
+				AppLogger.InfoWithContext(&LogContext{JobID: plan.JobID}, "Skipped %s", step.Label())
+				run.add(step)
+				skipped = append(skipped, step)
+				send(ExecutionEvent{Type: EventLevelSkipped, Completed: completed, Total: total(), Step: &pending[i]})
+			}
+			continue
+		}
+
+		// Keep the levels of a group that succeeded, even when another one failed
+		var failure error
+		for i, step := range pending {
+			if errs[i] != nil {
+				AppLogger.ErrorWithContext(&LogContext{JobID: plan.JobID}, "Benchmark failed for Model %d concurrency %d: %v", step.ModelIndex, step.Concurrency, errs[i])
+				if failure == nil {
+					failure = fmt.Errorf("model %d concurrency %d: %w", step.ModelIndex, step.Concurrency, errs[i])
+				}
+				continue
+			}
+			result := results[i]
+			run.add(step)
+			finished = append(finished, LevelResult{ModelIndex: step.ModelIndex, LevelIndex: step.LevelIndex, Result: result})
+			completed++
+			send(ExecutionEvent{Type: EventLevelDone, Completed: completed, Total: total(), Step: &pending[i], Result: &result})
+		}
+		if failure != nil {
+			return newExecutionResult(plan, finished, skipped, true), failure
+		}
+	}
+	return newExecutionResult(plan, finished, skipped, false), nil
+}
+
+// ranLevels records the (model, concurrency) levels that were measured or skipped
+type ranLevels map[[2]int]bool
+
+func newRanLevels(plan ExecutionPlan) ranLevels {
+	run := make(ranLevels)
+	for _, level := range plan.Finished {
+		if level.LevelIndex >= 0 && level.LevelIndex < len(plan.Request.ConcurrencyLevels) {
+			run[[2]int{level.ModelIndex, plan.Request.ConcurrencyLevels[level.LevelIndex]}] = true
+		}
+	}
+	return run
+}
+
+func (run ranLevels) has(step BenchmarkStep) bool {
+	return run[[2]int{step.ModelIndex, step.Concurrency}]
+}
+
+func (run ranLevels) add(step BenchmarkStep) {
+	run[[2]int{step.ModelIndex, step.Concurrency}] = true
+}
+
+// replan replaces the concurrency levels that no model has run yet with remaining. Levels
+// that ran for any model are kept for the others, so both models stay comparable.
+func replan(plan ExecutionPlan, run ranLevels, finished []LevelResult, remaining []int) (ExecutionPlan, []LevelResult) {
+	var levels []int
+	kept := make(map[int]bool)
+	for _, concurrency := range plan.Request.ConcurrencyLevels {
+		if run[[2]int{1, concurrency}] || run[[2]int{2, concurrency}] {
+			levels = append(levels, concurrency)
+			kept[concurrency] = true
+		}
+	}
+	for _, concurrency := range remaining {
+		if !kept[concurrency] {
+			levels = append(levels, concurrency)
+			kept[concurrency] = true
+		}
+	}
+
+	// Finished levels move to their position in the new list
+	positions := make(map[int]int, len(levels))
+	for i, concurrency := range levels {
+		positions[concurrency] = i
+	}
+	moved := make([]LevelResult, len(finished))
+	for i, level := range finished {
+		moved[i] = level
+		moved[i].LevelIndex = positions[plan.Request.ConcurrencyLevels[level.LevelIndex]]
+	}
+
+	plan.Request.ConcurrencyLevels = levels
+	plan.Groups = planBenchmarkSteps(plan.Request, plan.Schedule.Seed)
+	plan.Schedule = newScheduleMetadata(plan.Request, plan.Schedule.Seed, plan.Groups)
+	plan.Finished = moved
+	return plan, moved
+}
+
+// mergeCancel returns ctx, also cancelled when other is
+func mergeCancel(ctx context.Context, other context.Context) context.Context {
+	merged, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(other, cancel)
+	context.AfterFunc(merged, func() { stop() })
+	return merged
+}
+
+// newExecutionResult orders the finished levels by model and request position
+func newExecutionResult(plan ExecutionPlan, finished []LevelResult, skipped []BenchmarkStep, partial bool) *ExecutionResult {
+	request := plan.Request
+	model1Slots := make([]*ConcurrencyResult, len(request.ConcurrencyLevels))
+	model2Slots := make([]*ConcurrencyResult, len(request.ConcurrencyLevels))
+	for i := range finished {
+		level := &finished[i]
+		if level.LevelIndex < 0 || level.LevelIndex >= len(request.ConcurrencyLevels) {
+			continue
+		}
+		if level.ModelIndex == 1 {
+			model1Slots[level.LevelIndex] = &level.Result
+		} else {
+			model2Slots[level.LevelIndex] = &level.Result
+		}
+	}
+
+	result := &ExecutionResult{
+		Model1:      &BenchmarkResult{Model: request.Model1.Name, Results: collectConcurrencyResults(model1Slots), Timestamp: time.Now()},
+		Levels:      len(request.ConcurrencyLevels),
+		Schedule:    plan.Schedule,
+		Measurement: plan.Measurement,
+		Finished:    finished,
+		Skipped:     skipped,
+		Partial:     partial,
+	}
+	result.Model1.Cost = totalCost(result.Model1.Results)
+	if request.Model2 != nil {
+		result.Model2 = &BenchmarkResult{Model: request.Model2.Name, Results: collectConcurrencyResults(model2Slots), Timestamp: time.Now()}
+		result.Model2.Cost = totalCost(result.Model2.Results)
+		result.Comparison = compareResults(result.Model1, result.Model2)
+	}
+	return result
+}
+
+// collectConcurrencyResults drops empty slots and returns results in request order
+func collectConcurrencyResults(slots []*ConcurrencyResult) []ConcurrencyResult {
+	var results []ConcurrencyResult
+	for _, slot := range slots {
+		if slot != nil {
+			results = append(results, *slot)
+		}
+	}
+	return results
+}
+
+// runStep measures a single (model, concurrency) level
+func (executor *Executor) runStep(ctx context.Context, plan ExecutionPlan, step BenchmarkStep, trace utils.TraceSink) (ConcurrencyResult, error) {
+	request := plan.Request
+	model := step.Model
+
+	apiKey := getAPIKeyForModel(model)
+	if apiKey == "" {
+		AppLogger.ErrorWithContext(&LogContext{JobID: plan.JobID, Model: model.Name}, "No API key found for model")
+		return ConcurrencyResult{}, fmt.Errorf("no API key found for model %s", model.Name)
+	}
+
+	transport, err := transportConfigForModel(model)
+	if err != nil {
+		return ConcurrencyResult{}, err
+	}
+
+	var bar *progressbar.ProgressBar
+	if executor.ProgressOutput != nil {
+		expectedTokens := step.Concurrency * request.MaxTokens * max(request.Repetitions, 1)
+		bar = progressbar.NewOptions(expectedTokens,
+			progressbar.OptionSetWriter(executor.ProgressOutput),
+			progressbar.OptionSetDescription(fmt.Sprintf("Model%d Concurrency %d", step.ModelIndex, step.Concurrency)),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionShowCount(),
+			progressbar.OptionShowIts(),
+			progressbar.OptionSetItsString("tokens"),
+			progressbar.OptionSpinnerType(14),
+			progressbar.OptionSetRenderBlankState(true),
+		)
+		defer bar.Close()
+	}
+
+	setup := utils.SpeedMeasurement{
+		BaseUrl:        model.BaseURL,
+		ApiKey:         apiKey,
+		ModelName:      model.Name,
+		Prompt:         request.Prompt,
+		UseRandomInput: false, // We're using custom prompt
+		NumWords:       request.NumWords,
+		MaxTokens:      request.MaxTokens,
+		Concurrency:    step.Concurrency,
+		Repetitions:    request.Repetitions,
+		Cooldown:       time.Duration(request.CooldownSeconds * float64(time.Second)),
+		GoodputTargets: utils.GoodputTargets{
+			Ttft: request.GoodputTTFTSeconds,
+			Tpot: request.GoodputTPOTSeconds,
+		},
+		Transport:  transport,
+		Timeouts:   request.RequestTimeouts.requestTimeouts(),
+		Trace:      trace,
+		TraceJobID: plan.JobID,
+		Limiter:    rateLimiterForModel(model),
+	}
+
+	AppLogger.DebugWithContext(&LogContext{JobID: plan.JobID}, "Running benchmark for %s...", step.Label())
+	result, err := setup.Run(ctx, bar)
+	if err != nil {
+		return ConcurrencyResult{}, err
+	}
+
+	AppLogger.InfoWithFields(fmt.Sprintf("Model %d concurrency completed", step.ModelIndex), map[string]interface{}{
+		"jobId":            plan.JobID,
+		"concurrency":      step.Concurrency,
+		"generationSpeed":  result.GenerationSpeed,
+		"promptThroughput": result.PromptThroughput,
+		"minTtft":          result.MinTtft,
+		"maxTtft":          result.MaxTtft,
+		"runs":             result.Runs,
+	})
+
+	concurrencyResult := newConcurrencyResult(model.Name, result)
+	concurrencyResult.GenerationThroughput = sanitizeFloat(result.GenerationSpeed)
+	concurrencyResult.PromptThroughput = sanitizeFloat(result.PromptThroughput)
+	concurrencyResult.MinTTFT = sanitizeFloat(result.MinTtft)
+	concurrencyResult.MaxTTFT = sanitizeFloat(result.MaxTtft)
+	return concurrencyResult, nil
+}
+
+// eventTraceSink turns the trace records of a level into request_done events
+type eventTraceSink struct {
+	next      utils.TraceSink
+	step      *BenchmarkStep
+	send      func(ExecutionEvent)
+	completed int
+	total     int
+}
+
+func (sink *eventTraceSink) WriteTrace(record utils.TraceRecord) error {
+	sink.send(ExecutionEvent{Type: EventRequestDone, Completed: sink.completed, Total: sink.total, Step: sink.step, Request: &record})
+	if sink.next != nil {
+		return sink.next.WriteTrace(record)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// newFakeEndpoint serves streaming chat completions, failing every request when fail is set
+func newFakeEndpoint(t *testing.T, fail bool) *httptest.Server {
+	t.Setenv("API_KEY", "test") // The generic credential of newExecutorRequest's models
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"token \"}}]}\n\n")
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":3}}\n\ndata: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	allowGenericCredential(t, server.URL)
+	return server
+}
+
+// allowGenericCredential lets the generic test credential be sent to baseURL as well
+func allowGenericCredential(t *testing.T, baseURL string) {
+	var baseURLs map[string][]string
+	json.Unmarshal([]byte(os.Getenv("CREDENTIAL_BASE_URLS")), &baseURLs)
+	if baseURLs == nil {
+		baseURLs = make(map[string][]string)
+	}
+	baseURLs["generic"] = append(baseURLs["generic"], baseURL)
+	data, _ := json.Marshal(baseURLs)
+	t.Setenv("CREDENTIAL_BASE_URLS", string(data))
+}
+
+func newExecutorRequest(baseURL string, levels ...int) BenchmarkRequest {
+	return BenchmarkRequest{
+		Model1:            Model{ID: "a", Name: "model-a", BaseURL: baseURL, SecretID: "generic"},
+		Model2:            &Model{ID: "b", Name: "model-b", BaseURL: baseURL, SecretID: "generic"},
+		ConcurrencyLevels: levels,
+		MaxTokens:         8,
+		Prompt:            "hi",
+	}
+}
+
+func TestExecutorEmitsTypedEvents(t *testing.T) {
+	endpoint := newFakeEndpoint(t, false)
+	plan := NewExecutionPlan("job-1", newExecutorRequest(endpoint.URL, 1, 2))
+
+	counts := make(map[ExecutionEventType]int)
+	var last ExecutionEvent
+	result, err := (&Executor{}).Execute(context.Background(), plan, func(event ExecutionEvent) {
+		if event.JobID != "job-1" || event.Total != 4 {
+			t.Errorf("unexpected event %+v", event)
+		}
+		if event.Type == EventLevelDone && (event.Result == nil || event.Result.Concurrency != event.Step.Concurrency) {
+			t.Errorf("expected the level result with the event, got %+v", event.Result)
+		}
+		counts[event.Type]++
+		last = event
+	})
+	if err != nil {
+		t.Fatalf("expected the plan to finish, got %v", err)
+	}
+
+	// One request per concurrency slot: 1 + 2 for each model
+	expected := map[ExecutionEventType]int{EventLevelStarted: 4, EventRequestDone: 6, EventLevelDone: 4, EventJobDone: 1}
+	for eventType, count := range expected {
+		if counts[eventType] != count {
+			t.Errorf("expected %d %s events, got %d", count, eventType, counts[eventType])
+		}
+	}
+	if last.Type != EventJobDone || last.Output != result || last.Completed != 4 {
+		t.Errorf("expected job_done last with the result, got %+v", last)
+	}
+	if len(result.Model1.Results) != 2 || len(result.Model2.Results) != 2 || result.Comparison == nil {
+		t.Fatalf("expected two levels per model and a comparison, got %+v", result)
+	}
+
+	// The job result is valid JSON with the historical keys
+	data, err := json.Marshal(result.JobResult())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	for _, key := range []string{"model1", "model2", "metadata", "summary", "comparison"} {
+		if decoded[key] == nil {
+			t.Errorf("expected %q in the job result", key)
+		}
+	}
+}
+
+func TestExecutorReportsFailureAndCancellation(t *testing.T) {
+	failing := newFakeEndpoint(t, true)
+	var done ExecutionEvent
+	_, err := (&Executor{}).Execute(context.Background(), NewExecutionPlan("job-2", newExecutorRequest(failing.URL, 1)), func(event ExecutionEvent) {
+		done = event
+	})
+	if err == nil || done.Type != EventJobDone || done.Err == nil || done.Cancelled() || done.Output != nil {
+		t.Fatalf("expected a failed job_done, got %v and %+v", err, done)
+	}
+
+	// Cancelling after the first level stops the plan before the next one
+	endpoint := newFakeEndpoint(t, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := 0
+	_, err = (&Executor{}).Execute(ctx, NewExecutionPlan("job-3", newExecutorRequest(endpoint.URL, 1, 2, 4)), func(event ExecutionEvent) {
+		switch event.Type {
+		case EventLevelStarted:
+			started++
+		case EventLevelDone:
+			cancel()
+		case EventJobDone:
+			done = event
+		}
+	})
+	if !errors.Is(err, context.Canceled) || !done.Cancelled() {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if started != 1 {
+		t.Errorf("expected only the first level to start, got %d", started)
+	}
+}
+
+func TestProgressAdapterCompletesJob(t *testing.T) {
+	endpoint := newFakeEndpoint(t, false)
+	jm := NewSimpleJobManager()
+	request := newExecutorRequest(endpoint.URL, 1)
+	request.Schedule = ScheduleParallel
+	jobID := jm.CreateJob(request)
+	jm.Enqueue(jobID, func() {})
+
+	var messages []string
+	adapter := jm.progressAdapter(jobID)
+	(&Executor{}).Execute(context.Background(), NewExecutionPlan(jobID, request), func(event ExecutionEvent) {
+		adapter(event)
+		if event.Type == EventLevelStarted {
+			state, _ := jm.GetJobState(jobID)
+			messages = append(messages, state.Message)
+		}
+	})
+
+	if len(messages) != 2 || messages[1] != "Testing Model 1 and Model 2 concurrency 1 in parallel..." {
+		t.Errorf("expected a parallel group message, got %q", messages)
+	}
+	state, _ := jm.GetJobState(jobID)
+	if state.Status != "completed" || state.Progress != 100 {
+		t.Fatalf("expected a completed job, got %s at %d%%", state.Status, state.Progress)
+	}
+	job, _ := jm.GetJob(jobID)
+	if result, ok := job.Result.(map[string]interface{}); !ok || result["comparison"] == nil {
+		t.Errorf("expected the job result shape, got %T", job.Result)
+	}
+}
+
+func TestHubObserverBroadcastsProgress(t *testing.T) {
+	hub := NewHub()
+	hub.broadcast = make(chan []byte, 100) // Collect messages without running the hub
+
+	endpoint := newFakeEndpoint(t, false)
+	(&Executor{}).Execute(context.Background(), NewExecutionPlan("job-ws", newExecutorRequest(endpoint.URL, 1)), NewHubObserver(hub))
+	close(hub.broadcast)
+
+	var types []string
+	for data := range hub.broadcast {
+		var message WebSocketMessage
+		json.Unmarshal(data, &message)
+		if message.JobID != "job-ws" {
+			t.Fatalf("unexpected message %s", data)
+		}
+		types = append(types, message.Type)
+	}
+	if len(types) < 3 || types[0] != MessageTypeStatus || types[len(types)-1] != MessageTypeComplete {
+		t.Errorf("expected status, progress and completion messages, got %v", types)
+	}
+}
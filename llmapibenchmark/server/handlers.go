@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -53,7 +54,6 @@ func discoverModels() []Model {
 			Name:     model1Name, // Simple name for local
 			Provider: "Direct OpenAI Compatible",
 			BaseURL:  os.Getenv("MODEL1_BASE_URL"),
-			// APIKey is intentionally not included for security
 		})
 	}
 	
@@ -114,7 +114,6 @@ func discoverModels() []Model {
 					Name:     displayName,       // User-friendly display name
 					Provider: enhanced.Provider,
 					BaseURL:  enhanced.BaseURL,
-					// APIKey is intentionally not included for security
 				})
 			}
 		} else {
@@ -158,7 +157,6 @@ func discoverModelsLegacy() []Model {
 			Name:     model1Name,
 			Provider: "Direct OpenAI Compatible",
 			BaseURL:  os.Getenv("MODEL1_BASE_URL"),
-			// APIKey is intentionally not included for security
 		})
 	}
 	
@@ -250,83 +248,81 @@ func BenchmarkHandler(c *gin.Context) {
 	// Create job with cancellable context (Task 15.3 compliance)
 	jobManager := GetJobManager()
 	jobID := jobManager.CreateJob(req)
-	
-	// Create context and set it for cancellation support
-	ctx, cancelFunc := context.WithCancel(context.Background())
-	jobManager.SetJobContext(jobID, ctx, cancelFunc)
+	jobManager.setJobOwner(jobID, principalFrom(c).Subject)
+	
+	// Wait for the scheduler to admit the job, then use its cancellable context
+	admitted := make(chan struct{})
+	if err := jobManager.Enqueue(jobID, func() { close(admitted) }); err != nil {
+		if status := quotaErrorStatus(c, err); status != 0 {
+			c.JSON(status, ErrorResponse{
+				Error:   "Quota Exceeded",
+				Message: err.Error(),
+				Code:    status,
+			})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "Queue Full",
+			Message: err.Error(),
+			Code:    http.StatusServiceUnavailable,
+		})
+		return
+	}
+	select {
+	case <-admitted:
+	case <-c.Request.Context().Done():
+		jobManager.CancelJob(jobID)
+		return
+	}
+	ctx, cancelFunc := context.WithCancel(jobManager.jobContext(jobID))
+	defer cancelFunc()
 	
 	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Created job for synchronous benchmark")
-	AppLogger.InfoWithFields("Starting benchmark for model1", map[string]interface{}{
+	AppLogger.InfoWithFields("Starting synchronous benchmark", map[string]interface{}{
 		"jobId": jobID,
 		"model1": req.Model1.Name,
 		"concurrencyLevels": req.ConcurrencyLevels,
 		"maxTokens": req.MaxTokens,
 	})
-	if req.Model2 != nil {
-		AppLogger.InfoWithContext(&LogContext{JobID: jobID, Model: req.Model2.Name}, "Starting benchmark for model2")
-	}
 	
-	// Run benchmark for model1 with context
-	AppLogger.DebugWithFields("Starting benchmark for model1", map[string]interface{}{
-		"jobId": jobID,
-		"model1": req.Model1.Name,
-		"model1Id": req.Model1.ID,
-		"model1BaseURL": req.Model1.BaseURL,
-	})
-	result1, err := runSingleBenchmarkWithContext(ctx, req.Model1, req.ConcurrencyLevels, req.MaxTokens, req.Prompt)
-	if err != nil {
-		AppLogger.ErrorWithContext(&LogContext{JobID: jobID, Model: req.Model1.Name}, "Failed to benchmark model1: %v", err)
-		jobManager.FailJob(jobID, fmt.Sprintf("Failed to benchmark %s: %v", req.Model1.Name, err))
-		cancelFunc() // Clean up context
+	// Run the plan and collect its result; the job keeps the same shape as the response
+	live, stopLive := jobManager.startLiveEvents(jobID, req.Repetitions)
+	defer stopLive()
+	executor := &Executor{Trace: jobManager.traceSink(jobID), Control: jobManager.executionControl(jobID)}
+	result, err := executor.Execute(ctx, NewExecutionPlan(jobID, req), jobManager.executionEmitter(live))
+	if err != nil && result != nil {
+		// Keep the measured levels so that the job can be resumed
+		jobManager.PartialJob(jobID, result, fmt.Sprintf("Benchmark failed for %v", err))
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Benchmark Error",
-			Message: fmt.Sprintf("Failed to benchmark %s: %v", req.Model1.Name, err),
+			Message: fmt.Sprintf("Benchmark failed for %v; %d measured levels were kept on job %s", err, len(result.Finished), jobID),
 			Code:    http.StatusInternalServerError,
 		})
 		return
 	}
-	AppLogger.InfoWithContext(&LogContext{JobID: jobID, Model: req.Model1.Name}, "Successfully completed benchmark for model1")
-	
-	response := ComparisonResponse{
-		Model1: result1,
+	if errors.Is(err, context.Canceled) {
+		AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Synchronous benchmark cancelled")
+		jobManager.FailJob(jobID, "Job cancelled by user")
+		c.JSON(http.StatusRequestTimeout, ErrorResponse{
+			Error:   "Request Cancelled",
+			Message: "Benchmark was cancelled before completion",
+			Code:    http.StatusRequestTimeout,
+		})
+		return
 	}
-
-	// Run benchmark for model2 if provided
-	if req.Model2 != nil {
-		// Check for cancellation before starting model2
-		select {
-		case <-ctx.Done():
-			AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Job cancelled before model2 benchmark")
-			jobManager.FailJob(jobID, "Job cancelled by user")
-			c.JSON(http.StatusRequestTimeout, ErrorResponse{
-				Error:   "Request Cancelled",
-				Message: "Benchmark was cancelled before completion",
-				Code:    http.StatusRequestTimeout,
-			})
-			return
-		default:
-		}
-		
-		result2, err := runSingleBenchmarkWithContext(ctx, *req.Model2, req.ConcurrencyLevels, req.MaxTokens, req.Prompt)
-		if err != nil {
-			AppLogger.ErrorWithContext(&LogContext{JobID: jobID, Model: req.Model2.Name}, "Failed to benchmark model2: %v", err)
-			jobManager.FailJob(jobID, fmt.Sprintf("Failed to benchmark %s: %v", req.Model2.Name, err))
-			cancelFunc() // Clean up context
-			// Return error instead of partial results for consistency
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error:   "Benchmark Error",
-				Message: fmt.Sprintf("Failed to benchmark %s: %v", req.Model2.Name, err),
-				Code:    http.StatusInternalServerError,
-			})
-			return
-		}
-		response.Model2 = result2
-		response.Comparison = compareResults(result1, result2)
+	if err != nil {
+		jobManager.FailJob(jobID, fmt.Sprintf("Benchmark failed for %v", err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Benchmark Error",
+			Message: fmt.Sprintf("Benchmark failed for %v", err),
+			Code:    http.StatusInternalServerError,
+		})
+		return
 	}
+	response := result.Response()
 
 	// Complete the job successfully
 	jobManager.CompleteJob(jobID, response)
-	cancelFunc() // Clean up context
 	
 	AppLogger.DebugWithFields("Sending response with Model1", map[string]interface{}{
 		"model1": response.Model1,
@@ -404,6 +400,29 @@ func validateBenchmarkRequest(req *BenchmarkRequest) error {
 		return fmt.Errorf("prompt too long (max 10000 characters), got %d", len(req.Prompt))
 	}
 	
+	// Validate credential references
+	if err := validateRequestCredentials(req); err != nil {
+		return err
+	}
+	
+	// Validate execution schedule
+	if err := validateRequestSchedule(req); err != nil {
+		return err
+	}
+	
+	// Validate repeated runs
+	if err := validateRepetitions(req); err != nil {
+		return err
+	}
+	if err := req.RequestTimeouts.validate(); err != nil {
+		return err
+	}
+	
+	// Validate goodput targets
+	if req.GoodputTTFTSeconds < 0 || req.GoodputTPOTSeconds < 0 {
+		return fmt.Errorf("goodput targets must not be negative")
+	}
+	
 	// Validate numWords if using random prompt generation
 	if req.NumWords > 0 {
 		if req.NumWords < 10 {
@@ -417,177 +436,15 @@ func validateBenchmarkRequest(req *BenchmarkRequest) error {
 	return nil
 }
 
-// runSingleBenchmark runs benchmark for a single model across multiple concurrency levels
-func runSingleBenchmark(model Model, concurrencyLevels []int, maxTokens int, prompt string) (*BenchmarkResult, error) {
-	AppLogger.DebugWithFields("runSingleBenchmark called", map[string]interface{}{
-		"model": model.Name,
-		"modelId": model.ID,
-	})
-	
-	// Get API key from environment
-	apiKey := getAPIKeyForModel(model)
-	if apiKey == "" {
-		AppLogger.ErrorWithContext(&LogContext{Model: model.Name}, "No API key found for model")
-		return nil, fmt.Errorf("no API key found for model %s", model.Name)
-	}
-	AppLogger.DebugWithFields("Using API key for model", map[string]interface{}{
-		"model": model.Name,
-		"keyLength": len(apiKey),
-	})
-	
-	var results []ConcurrencyResult
-	
-	// Run benchmark for each concurrency level
-	for _, concurrency := range concurrencyLevels {
-		AppLogger.DebugWithFields("Running benchmark for concurrency level", map[string]interface{}{
-			"concurrency": concurrency,
-		})
-		
-		// Create speed measurement
-		speedMeasurement := utils.SpeedMeasurement{
-			BaseUrl:        model.BaseURL,
-			ApiKey:         apiKey,
-			ModelName:      model.ID,
-			Prompt:         prompt,
-			UseRandomInput: false,
-			MaxTokens:      maxTokens,
-			Latency:        0, // TODO: Measure actual latency
-			Concurrency:    concurrency,
-		}
-
-		AppLogger.DebugWithFields("SpeedMeasurement config", map[string]interface{}{
-			"baseURL": speedMeasurement.BaseUrl,
-			"modelName": speedMeasurement.ModelName,
-			"maxTokens": speedMeasurement.MaxTokens,
-		})
-
-		// Run benchmark (without progress bar for API)
-		result, err := speedMeasurement.Run(context.Background(), nil)
-		if err != nil {
-			AppLogger.ErrorWithFields("Benchmark failed for concurrency", map[string]interface{}{
-				"concurrency": concurrency,
-				"error": err,
-			})
-			return nil, fmt.Errorf("concurrency %d: %v", concurrency, err)
-		}
-		
-		AppLogger.DebugWithFields("Benchmark completed for concurrency", map[string]interface{}{
-			"concurrency": concurrency,
-			"generationSpeed": result.GenerationSpeed,
-			"promptThroughput": result.PromptThroughput,
-		})
-		
-		// Add result for this concurrency level
-		results = append(results, ConcurrencyResult{
-			Concurrency:          concurrency,
-			GenerationThroughput: sanitizeFloat(result.GenerationSpeed),
-			PromptThroughput:     sanitizeFloat(result.PromptThroughput),
-			MinTTFT:              sanitizeFloat(result.MinTtft),
-			MaxTTFT:              sanitizeFloat(result.MaxTtft),
-		})
-	}
-	
-	// Return complete benchmark result
-	return &BenchmarkResult{
-		Model:     model.Name,
-		Results:   results,
-		Timestamp: time.Now(),
-	}, nil
-}
-
-// runSingleBenchmarkWithContext runs a benchmark with context support for cancellation (Task 15.3)
-func runSingleBenchmarkWithContext(ctx context.Context, model Model, concurrencyLevels []int, maxTokens int, prompt string) (*BenchmarkResult, error) {
-	AppLogger.DebugWithFields("runSingleBenchmarkWithContext called", map[string]interface{}{
-		"model": model.Name,
-		"modelId": model.ID,
-	})
-	
-	// Check for cancellation before starting
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-	}
-	
-	// Get API key from environment
-	apiKey := getAPIKeyForModel(model)
-	if apiKey == "" {
-		AppLogger.ErrorWithContext(&LogContext{Model: model.Name}, "No API key found for model")
-		return nil, fmt.Errorf("no API key found for model %s", model.Name)
+// validateRepetitions checks the repeated-run settings of a request
+func validateRepetitions(req *BenchmarkRequest) error {
+	if req.Repetitions < 0 || req.Repetitions > 20 {
+		return fmt.Errorf("repetitions must be between 0 and 20 (0 means 1), got %d", req.Repetitions)
 	}
-	AppLogger.DebugWithFields("Using API key for model", map[string]interface{}{
-		"model": model.Name,
-		"keyLength": len(apiKey),
-	})
-	
-	var results []ConcurrencyResult
-	
-	// Run benchmark for each concurrency level
-	for _, concurrency := range concurrencyLevels {
-		// Check for cancellation before each concurrency level
-		select {
-		case <-ctx.Done():
-			AppLogger.InfoWithFields("Job cancelled at concurrency level", map[string]interface{}{
-				"concurrency": concurrency,
-			})
-			return nil, ctx.Err()
-		default:
-		}
-		
-		AppLogger.DebugWithFields("Running benchmark for concurrency level", map[string]interface{}{
-			"concurrency": concurrency,
-		})
-		
-		// Create speed measurement
-		speedMeasurement := utils.SpeedMeasurement{
-			BaseUrl:        model.BaseURL,
-			ApiKey:         apiKey,
-			ModelName:      model.ID,
-			Prompt:         prompt,
-			UseRandomInput: false,
-			MaxTokens:      maxTokens,
-			Latency:        0, // TODO: Measure actual latency
-			Concurrency:    concurrency,
-		}
-
-		AppLogger.DebugWithFields("SpeedMeasurement config", map[string]interface{}{
-			"baseURL": speedMeasurement.BaseUrl,
-			"modelName": speedMeasurement.ModelName,
-			"maxTokens": speedMeasurement.MaxTokens,
-		})
-
-		// Run benchmark with context for cancellation support
-		result, err := speedMeasurement.Run(ctx, nil)
-		if err != nil {
-			AppLogger.ErrorWithFields("Benchmark failed for concurrency", map[string]interface{}{
-				"concurrency": concurrency,
-				"error": err,
-			})
-			return nil, fmt.Errorf("concurrency %d: %v", concurrency, err)
-		}
-		
-		AppLogger.DebugWithFields("Benchmark completed for concurrency", map[string]interface{}{
-			"concurrency": concurrency,
-			"generationSpeed": result.GenerationSpeed,
-			"promptThroughput": result.PromptThroughput,
-		})
-		
-		// Add result for this concurrency level
-		results = append(results, ConcurrencyResult{
-			Concurrency:          concurrency,
-			GenerationThroughput: sanitizeFloat(result.GenerationSpeed),
-			PromptThroughput:     sanitizeFloat(result.PromptThroughput),
-			MinTTFT:              sanitizeFloat(result.MinTtft),
-			MaxTTFT:              sanitizeFloat(result.MaxTtft),
-		})
+	if req.CooldownSeconds < 0 || req.CooldownSeconds > 600 {
+		return fmt.Errorf("cooldownSeconds must be between 0 and 600, got %g", req.CooldownSeconds)
 	}
-	
-	// Return complete benchmark result
-	return &BenchmarkResult{
-		Model:     model.Name,
-		Results:   results,
-		Timestamp: time.Now(),
-	}, nil
+	return nil
 }
 
 // sanitizeFloat ensures float values are JSON-serializable (no Inf or NaN)
@@ -606,72 +463,48 @@ func sanitizeFloat(value float64) float64 {
 	return value
 }
 
-// getAPIKeyForModel retrieves the API key for a given model using hybrid approach
+// getAPIKeyForModel retrieves the API key for a given model using hybrid approach. The
+// model's SecretID names its credential; without one, the candidates are the configured
+// model of the same name, the service of a "serviceId|modelName" ID and the generic
+// API_KEY. A key is only returned for the base URL its credential belongs to.
 func getAPIKeyForModel(model Model) string {
-	// If model has API key, use it
-	if model.APIKey != "" {
-		return model.APIKey
-	}
-	
-	// STEP 1: Handle simple local model names (e.g., "gpt-4", "Qwen/Qwen3-Coder-30B")
-	if model.Name != "" && !strings.Contains(model.Name, "|") {
-		// Check MODEL1_NAME and MODEL2_NAME to determine which API key to use
-		if model1Name := os.Getenv("MODEL1_NAME"); model1Name != "" && model1Name == model.Name {
-			if key := os.Getenv("MODEL1_API_KEY"); key != "" {
-				return key
+	secretIDs := []string{model.SecretID}
+	if model.SecretID == "" {
+		secretIDs = nil
+		// Simple local model names (e.g., "gpt-4", "Qwen/Qwen3-Coder-30B") configured as MODEL1_NAME or MODEL2_NAME
+		if model.Name != "" && !strings.Contains(model.Name, "|") {
+			if model1Name := os.Getenv("MODEL1_NAME"); model1Name != "" && model1Name == model.Name {
+				secretIDs = append(secretIDs, "model1")
 			}
-		}
-		if model2Name := os.Getenv("MODEL2_NAME"); model2Name != "" && model2Name == model.Name {
-			if key := os.Getenv("MODEL2_API_KEY"); key != "" {
-				return key
+			if model2Name := os.Getenv("MODEL2_NAME"); model2Name != "" && model2Name == model.Name {
+				secretIDs = append(secretIDs, "model2")
 			}
 		}
+		// Cloud Foundry model IDs (e.g., "serviceId|modelName")
+		if serviceID, _, found := strings.Cut(model.ID, "|"); found {
+			secretIDs = append(secretIDs, serviceID)
+		}
+		secretIDs = append(secretIDs, "generic")
 	}
-	
-	// STEP 2: Handle complex Cloud Foundry model IDs (e.g., "serviceId|modelName")
-	if model.ID != "" && strings.Contains(model.ID, "|") {
-		parts := strings.SplitN(model.ID, "|", 2)
-		if len(parts) == 2 {
-			serviceID := parts[0]
-			modelName := parts[1]
-			
-			AppLogger.DebugWithFields("Resolving API key for Cloud Foundry model", map[string]interface{}{
-			"serviceId": serviceID,
-			"modelName": modelName,
-		})
-			
-			// Try to get API key from VCAP_SERVICES
-			if IsVCAPServicesAvailable() {
-				if apiKey, err := GetAPIKeyForService(serviceID); err == nil && apiKey != "" {
-					AppLogger.InfoWithFields("Found API key from VCAP_SERVICES for service", map[string]interface{}{
-						"serviceId": serviceID,
-					})
-					return apiKey
-				}
-				AppLogger.WarnWithFields("No API key found in VCAP_SERVICES for service", map[string]interface{}{
-					"serviceId": serviceID,
-				})
-			}
-			
-			// Try environment variables as fallback
-			if apiKey, err := GetAPIKeyForEnvironmentModel(serviceID); err == nil && apiKey != "" {
-				AppLogger.InfoWithFields("Found API key from environment for service", map[string]interface{}{
-					"serviceId": serviceID,
-				})
-				return apiKey
-			}
-			AppLogger.WarnWithFields("No API key found in environment for service", map[string]interface{}{
-				"serviceId": serviceID,
+
+	for _, secretID := range secretIDs {
+		apiKey, err := credentialForEndpoint(secretID, model.BaseURL)
+		if err == nil {
+			return apiKey
+		}
+		if model.SecretID != "" || errors.Is(err, ErrForeignEndpoint) {
+			AppLogger.WarnWithFields("Failed to resolve the credential of a model", map[string]interface{}{
+				"model":    model.Name,
+				"secretId": secretID,
+				"error":    err,
 			})
 		}
 	}
-	
-	// STEP 3: Final fallback to generic API_KEY
-	fallbackKey := os.Getenv("API_KEY")
-	if fallbackKey != "" {
-		AppLogger.Info("Using fallback API_KEY")
-	}
-	return fallbackKey
+	AppLogger.DebugWithFields("No API key found for model", map[string]interface{}{
+		"model":   model.Name,
+		"baseUrl": model.BaseURL,
+	})
+	return ""
 }
 
 // convertEnhancedToLegacyModel converts an EnhancedModel to a legacy Model for backward compatibility
@@ -681,7 +514,6 @@ func convertEnhancedToLegacyModel(enhanced EnhancedModel) Model {
 		Name:     enhanced.Name,
 		Provider: enhanced.Provider,
 		BaseURL:  enhanced.BaseURL,
-		// APIKey is intentionally not included for security
 	}
 }
 
@@ -726,7 +558,39 @@ func compareResults(result1, result2 *BenchmarkResult) *Comparison {
 		differences["timeToFirstToken"] = ((avgTTFT1 - avgTTFT2) / avgTTFT2) * 100
 	}
 	
-	// Determine winner (based on average generation throughput)
+	costWinner := compareCostEfficiency(result1.Cost, result2.Cost, differences)
+	
+	// With repeated runs, let significance tests on the per-run samples of each level decide;
+	// otherwise fall back to the 5% threshold on average generation throughput
+	if levels := compareRepeatedLevels(result1, result2); len(levels) > 0 {
+		wins1, wins2 := 0, 0
+		for _, level := range levels {
+			if !level.Significant {
+				continue
+			}
+			if level.Difference > 0 {
+				wins1++
+			} else {
+				wins2++
+			}
+		}
+		winner := "tie"
+		if wins1 > wins2 {
+			winner = "model1"
+		} else if wins2 > wins1 {
+			winner = "model2"
+		}
+		significant := wins1+wins2 > 0
+		return &Comparison{
+			Winner:      winner,
+			Differences: differences,
+			Method:      "welch-t-test",
+			Significant: &significant,
+			Levels:      levels,
+			CostEfficiencyWinner: costWinner,
+		}
+	}
+	
 	winner := "tie"
 	if avgGen1 > avgGen2*1.05 { // 5% threshold
 		winner = "model1"
@@ -737,7 +601,72 @@ func compareResults(result1, result2 *BenchmarkResult) *Comparison {
 	return &Comparison{
 		Winner:      winner,
 		Differences: differences,
+		Method:      "threshold",
+		CostEfficiencyWinner: costWinner,
+	}
+}
+
+// compareCostEfficiency adds the cost differences of two priced models and returns the
+// model producing more output tokens per unit of cost (5% rule), or "" when either model
+// has no price
+func compareCostEfficiency(cost1, cost2 *CostEfficiency, differences map[string]float64) string {
+	if cost1 == nil || cost2 == nil {
+		return ""
+	}
+	if cost2.TokensPerDollar > 0 {
+		differences["tokensPerDollar"] = ((cost1.TokensPerDollar - cost2.TokensPerDollar) / cost2.TokensPerDollar) * 100
+	}
+	if cost2.PerThousandOutputTokens > 0 {
+		differences["costPerThousandOutputTokens"] = ((cost1.PerThousandOutputTokens - cost2.PerThousandOutputTokens) / cost2.PerThousandOutputTokens) * 100
+	}
+	// A free model wins unless both are free
+	switch {
+	case cost1.Total == 0 && cost2.Total == 0:
+		return "tie"
+	case cost1.Total == 0:
+		return "model1"
+	case cost2.Total == 0:
+		return "model2"
+	case cost1.TokensPerDollar > cost2.TokensPerDollar*1.05:
+		return "model1"
+	case cost2.TokensPerDollar > cost1.TokensPerDollar*1.05:
+		return "model2"
+	}
+	return "tie"
+}
+
+// compareRepeatedLevels runs a Welch t-test on the per-run generation throughputs of every
+// concurrency level that both models repeated. The p-values are Holm-adjusted over the
+// tested levels, so the chance of any false difference stays at 5%.
+func compareRepeatedLevels(result1, result2 *BenchmarkResult) []LevelComparison {
+	repeated2 := make(map[int]ConcurrencyResult)
+	for _, r := range result2.Results {
+		if len(r.GenerationThroughputSamples) >= 2 {
+			repeated2[r.Concurrency] = r
+		}
+	}
+
+	var levels []LevelComparison
+	var pValues []float64
+	for _, r1 := range result1.Results {
+		r2, ok := repeated2[r1.Concurrency]
+		if !ok || len(r1.GenerationThroughputSamples) < 2 {
+			continue
+		}
+		level := LevelComparison{Concurrency: r1.Concurrency}
+		if r2.GenerationThroughput > 0 {
+			level.Difference = ((r1.GenerationThroughput - r2.GenerationThroughput) / r2.GenerationThroughput) * 100
+		}
+		_, p := utils.WelchTest(r1.GenerationThroughputSamples, r2.GenerationThroughputSamples)
+		levels = append(levels, level)
+		pValues = append(pValues, p)
+	}
+
+	for i, p := range utils.HolmAdjust(pValues) {
+		levels[i].PValue = p
+		levels[i].Significant = p < 0.05
 	}
+	return levels
 }
 
 // ExportJSONHandler exports results as JSON file
@@ -797,18 +726,22 @@ func generateCSV(results ComparisonResponse) string {
 	var csv strings.Builder
 	
 	// CSV Header
-	csv.WriteString("Model,Concurrency,Generation Throughput (tokens/s),Prompt Throughput (tokens/s),Min TTFT (s),Max TTFT (s),Timestamp\n")
+	csv.WriteString("Model,Concurrency,Generation Throughput (tokens/s),Prompt Throughput (tokens/s),Min TTFT (s),Max TTFT (s),P95 TTFT (s),Requests/s,Per-User Mean (tokens/s),Per-User P50 (tokens/s),Per-User P90 (tokens/s),Per-User P99 (tokens/s),Goodput (%),Goodput (requests/s),Error Rate (%),Runs,Generation Median (tokens/s),Generation StdDev (tokens/s),Generation CV,Connect (ms),TLS (ms),Headers (ms),First Chunk (ms),Reused Connections,Prompt Tokens,Completion Tokens,Duration (s),Limiter Wait Mean (s),Limiter Wait Max (s),Cost,Cost per 1k Output Tokens,Tokens per Dollar,Timestamp\n")
 	
 	// Model 1 data
 	if results.Model1 != nil {
 		for _, result := range results.Model1.Results {
-			csv.WriteString(fmt.Sprintf("%s,%d,%.2f,%.2f,%.2f,%.2f,%s\n",
+			csv.WriteString(fmt.Sprintf("%s,%d,%.2f,%.2f,%.2f,%.2f,%s,%s,%s,%s,%s\n",
 				escapeCsvField(results.Model1.Model),
 				result.Concurrency,
 				result.GenerationThroughput,
 				result.PromptThroughput,
 				result.MinTTFT,
 				result.MaxTTFT,
+				csvRequestMetricFields(result),
+				csvRunStatsFields(result),
+				csvTTFTBreakdownFields(result),
+				csvCostFields(result),
 				results.Model1.Timestamp.Format(time.RFC3339),
 			))
 		}
@@ -817,22 +750,46 @@ func generateCSV(results ComparisonResponse) string {
 	// Model 2 data
 	if results.Model2 != nil {
 		for _, result := range results.Model2.Results {
-			csv.WriteString(fmt.Sprintf("%s,%d,%.2f,%.2f,%.2f,%.2f,%s\n",
+			csv.WriteString(fmt.Sprintf("%s,%d,%.2f,%.2f,%.2f,%.2f,%s,%s,%s,%s,%s\n",
 				escapeCsvField(results.Model2.Model),
 				result.Concurrency,
 				result.GenerationThroughput,
 				result.PromptThroughput,
 				result.MinTTFT,
 				result.MaxTTFT,
+				csvRequestMetricFields(result),
+				csvRunStatsFields(result),
+				csvTTFTBreakdownFields(result),
+				csvCostFields(result),
 				results.Model2.Timestamp.Format(time.RFC3339),
 			))
 		}
 	}
 	
+	// Add the cost of priced models
+	var priced []*BenchmarkResult
+	for _, result := range []*BenchmarkResult{results.Model1, results.Model2} {
+		if result != nil && result.Cost != nil {
+			priced = append(priced, result)
+		}
+	}
+	if len(priced) > 0 {
+		csv.WriteString("\nCost\nModel,Total Cost,Cost per 1k Output Tokens,Tokens per Dollar\n")
+		for _, result := range priced {
+			csv.WriteString(fmt.Sprintf("%s,%.6f,%.6f,%.0f\n", escapeCsvField(result.Model), result.Cost.Total, result.Cost.PerThousandOutputTokens, result.Cost.TokensPerDollar))
+		}
+	}
+	
 	// Add comparison section if available
 	if results.Comparison != nil {
 		csv.WriteString("\nComparison\n")
 		csv.WriteString(fmt.Sprintf("Winner,%s\n", results.Comparison.Winner))
+		if results.Comparison.CostEfficiencyWinner != "" {
+			csv.WriteString(fmt.Sprintf("Cost Efficiency Winner,%s\n", results.Comparison.CostEfficiencyWinner))
+		}
+		if results.Comparison.Significant != nil {
+			csv.WriteString(fmt.Sprintf("Significant (%s),%t\n", results.Comparison.Method, *results.Comparison.Significant))
+		}
 		csv.WriteString("\nMetric,Difference (%%)\n")
 		for metric, diff := range results.Comparison.Differences {
 			csv.WriteString(fmt.Sprintf("%s,%.2f\n", metric, diff))
@@ -843,6 +800,49 @@ func generateCSV(results ComparisonResponse) string {
 }
 
 
+// csvCostFields formats the token, rate limiter and cost columns of a CSV row; the cost
+// columns stay empty for models without a price
+func csvCostFields(result ConcurrencyResult) string {
+	tokens := fmt.Sprintf("%d,%d,%.2f,%.2f,%.2f", result.PromptTokens, result.CompletionTokens, result.DurationSeconds, result.LimiterWaitMean, result.LimiterWaitMax)
+	if result.Cost == nil {
+		return tokens + ",,,"
+	}
+	return fmt.Sprintf("%s,%.6f,%.6f,%.0f", tokens, result.Cost.Total, result.Cost.PerThousandOutputTokens, result.Cost.TokensPerDollar)
+}
+
+// csvRequestMetricFields formats the per-request, goodput and error columns of a CSV row
+func csvRequestMetricFields(result ConcurrencyResult) string {
+	return fmt.Sprintf("%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f,%.2f",
+		result.P95TTFT,
+		result.RequestsPerSecond,
+		result.PerUserThroughput,
+		result.PerUserThroughputP50,
+		result.PerUserThroughputP90,
+		result.PerUserThroughputP99,
+		result.GoodputRatio*100,
+		result.GoodputRate,
+		result.ErrorRate*100,
+	)
+}
+
+// csvRunStatsFields formats the repeated-run columns of a CSV row; they stay empty for single runs
+func csvRunStatsFields(result ConcurrencyResult) string {
+	stats := result.GenerationThroughputStats
+	if stats == nil {
+		return "1,,,"
+	}
+	return fmt.Sprintf("%d,%.2f,%.2f,%.4f", result.Runs, stats.Median, stats.StdDev, stats.CV)
+}
+
+// csvTTFTBreakdownFields formats the TTFT breakdown columns of a CSV row
+func csvTTFTBreakdownFields(result ConcurrencyResult) string {
+	breakdown := result.TTFTBreakdown
+	if breakdown == nil {
+		return ",,,,"
+	}
+	return fmt.Sprintf("%.2f,%.2f,%.2f,%.2f,%d", breakdown.ConnectMs, breakdown.TLSMs, breakdown.HeadersMs, breakdown.FirstChunkMs, breakdown.ReusedConnections)
+}
+
 // escapeCsvField escapes CSV field if it contains special characters
 func escapeCsvField(field string) string {
 	if strings.ContainsAny(field, ",\"\n") {
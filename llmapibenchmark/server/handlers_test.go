@@ -0,0 +1,47 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestCompareResultsTestsEveryRepeatedLevel(t *testing.T) {
+	level := func(concurrency int, samples ...float64) ConcurrencyResult {
+		var sum float64
+		for _, sample := range samples {
+			sum += sample
+		}
+		return ConcurrencyResult{Concurrency: concurrency, GenerationThroughput: sum / float64(len(samples)), GenerationThroughputSamples: samples}
+	}
+	// Model 1 is clearly faster at concurrency 1 and indistinguishable at 8, where both are
+	// far faster. Pooling the levels would hide neither.
+	result1 := &BenchmarkResult{Results: []ConcurrencyResult{level(1, 100, 101, 99, 100), level(8, 700, 760, 640, 720)}}
+	result2 := &BenchmarkResult{Results: []ConcurrencyResult{level(1, 80, 81, 79, 80), level(8, 710, 650, 750, 690)}}
+
+	comparison := compareResults(result1, result2)
+	if comparison.Method != "welch-t-test" || len(comparison.Levels) != 2 {
+		t.Fatalf("expected a Welch t-test per level, got %+v", comparison)
+	}
+	if first := comparison.Levels[0]; first.Concurrency != 1 || !first.Significant || first.Difference <= 0 {
+		t.Errorf("expected model1 to be significantly faster at concurrency 1, got %+v", first)
+	}
+	if second := comparison.Levels[1]; second.Significant || second.PValue < comparison.Levels[0].PValue {
+		t.Errorf("expected no significant difference at concurrency 8, got %+v", second)
+	}
+	if comparison.Winner != "model1" || comparison.Significant == nil || !*comparison.Significant {
+		t.Errorf("expected model1 to win, got %s", comparison.Winner)
+	}
+
+	// Levels measured by only one model are not tested
+	result2.Results = result2.Results[1:]
+	if comparison := compareResults(result1, result2); len(comparison.Levels) != 1 || comparison.Winner != "tie" {
+		t.Errorf("expected only concurrency 8 to be tested, got %+v", comparison.Levels)
+	}
+}
+
+func TestValidateRepetitions(t *testing.T) {
+	for repetitions, valid := range map[int]bool{-1: false, 0: true, 1: true, 20: true, 21: false} {
+		if err := validateRepetitions(&BenchmarkRequest{Repetitions: repetitions}); (err == nil) != valid {
+			t.Errorf("repetitions %d: unexpected result %v", repetitions, err)
+		}
+	}
+}
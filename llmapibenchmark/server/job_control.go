@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by job control operations
+var (
+	ErrJobNotRunning  = errors.New("job is not running")
+	ErrNoLevelRunning = errors.New("no concurrency level is running")
+	ErrInvalidControl = errors.New("invalid job control request")
+)
+
+// PauseJob holds a running benchmark before its next concurrency level. Levels in
+// progress finish; the job keeps its slot while paused.
+func (jm *SimpleJobManager) PauseJob(jobID string) error {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	job, err := jm.controllableJobLocked(jobID)
+	if err != nil {
+		return err
+	}
+	if job.Paused {
+		return nil
+	}
+	job.Paused = true
+	job.resumeSignal = make(chan struct{})
+	job.Message = "Pausing after the current level..."
+	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Job pause requested")
+	jm.broadcastUpdate(jobID, job)
+	return nil
+}
+
+// UnpauseJob lets a paused benchmark continue with its next level
+func (jm *SimpleJobManager) UnpauseJob(jobID string) error {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	job, err := jm.controllableJobLocked(jobID)
+	if err != nil {
+		return err
+	}
+	if !job.Paused {
+		return nil
+	}
+	job.Paused = false
+	close(job.resumeSignal)
+	job.resumeSignal = nil
+	job.Message = "Resuming..."
+	job.events.append(JobEventJobResumed, JobControlEvent{Message: job.Message})
+	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Job resumed")
+	jm.broadcastUpdate(jobID, job)
+	return nil
+}
+
+// SkipLevel stops the running concurrency level of a benchmark without keeping its
+// result; the job continues with its next level
+func (jm *SimpleJobManager) SkipLevel(jobID string) error {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	job, err := jm.controllableJobLocked(jobID)
+	if err != nil {
+		return err
+	}
+	if job.skipLevel == nil {
+		return ErrNoLevelRunning
+	}
+	job.skipLevel()
+	job.Message = "Skipping the current level..."
+	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Skipping the running level")
+	jm.broadcastUpdate(jobID, job)
+	return nil
+}
+
+// SetRemainingLevels replaces the concurrency levels a benchmark has not started yet.
+// The change applies before the next level; levels already run for one model still run
+// for the other.
+func (jm *SimpleJobManager) SetRemainingLevels(jobID string, levels []int) error {
+	for _, concurrency := range levels {
+		if concurrency < 1 {
+			return fmt.Errorf("%w: concurrency levels must be at least 1, got %d", ErrInvalidControl, concurrency)
+		}
+	}
+
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	job, err := jm.controllableJobLocked(jobID)
+	if err != nil {
+		return err
+	}
+	job.remainingLevels = append([]int{}, levels...) // Non-nil, even when empty
+	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Remaining concurrency levels set to %v", levels)
+	return nil
+}
+
+// controllableJobLocked returns a running benchmark job. The caller holds the lock.
+func (jm *SimpleJobManager) controllableJobLocked(jobID string) (*SimpleJob, error) {
+	job, exists := jm.jobs[jobID]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+	if job.Status != "running" || job.cancelRequested {
+		return nil, fmt.Errorf("%w (status: %s)", ErrJobNotRunning, job.Status)
+	}
+	if job.Type != JobTypeBenchmark {
+		return nil, fmt.Errorf("%w: %s jobs cannot be controlled", ErrInvalidControl, job.Type)
+	}
+	return job, nil
+}
+
+// jobControl steers a job's Executor with the job's control requests
+type jobControl struct {
+	jm    *SimpleJobManager
+	jobID string
+}
+
+// executionControl returns the ExecutionControl of a job
+func (jm *SimpleJobManager) executionControl(jobID string) ExecutionControl {
+	return &jobControl{jm: jm, jobID: jobID}
+}
+
+// BeforeGroup waits while the job is paused, then hands over changed levels
+func (control *jobControl) BeforeGroup(ctx context.Context) ([]int, error) {
+	jm := control.jm
+	jm.mutex.Lock()
+	job, exists := jm.jobs[control.jobID]
+	if !exists {
+		jm.mutex.Unlock()
+		return nil, nil
+	}
+	if job.Paused {
+		resumed := job.resumeSignal
+		job.Message = "Paused"
+		job.events.append(JobEventJobPaused, JobControlEvent{Message: job.Message})
+		jm.broadcastUpdate(control.jobID, job)
+		jm.mutex.Unlock()
+
+		AppLogger.InfoWithContext(&LogContext{JobID: control.jobID}, "Job paused before its next level")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-resumed:
+		}
+		jm.mutex.Lock()
+	}
+	remaining := job.remainingLevels
+	job.remainingLevels = nil
+	jm.mutex.Unlock()
+	return remaining, nil
+}
+
+// GroupContext makes the group's levels skippable with SkipLevel
+func (control *jobControl) GroupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	jm := control.jm
+	groupCtx, cancel := context.WithCancel(ctx)
+	jm.mutex.Lock()
+	if job, exists := jm.jobs[control.jobID]; exists {
+		job.skipLevel = cancel
+	}
+	jm.mutex.Unlock()
+	return groupCtx, func() {
+		cancel()
+		jm.mutex.Lock()
+		if job, exists := jm.jobs[control.jobID]; exists {
+			job.skipLevel = nil
+		}
+		jm.mutex.Unlock()
+	}
+}
+
+// recordControlEvent keeps the job's state in line with the Executor's control events
+func (jm *SimpleJobManager) recordControlEvent(event ExecutionEvent) {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	job, exists := jm.jobs[event.JobID]
+	if !exists {
+		return
+	}
+	switch event.Type {
+	case EventPlanChanged:
+		job.Request.ConcurrencyLevels = append([]int{}, event.Levels...)
+		job.Message = fmt.Sprintf("Concurrency levels changed to %v", event.Levels)
+	case EventLevelSkipped:
+		job.SkippedLevels = append(job.SkippedLevels, event.Step.Label())
+		job.Message = fmt.Sprintf("Skipped %s", event.Step.Label())
+	default:
+		return
+	}
+	jm.broadcastUpdate(event.JobID, job)
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPausedJobWaitsBeforeNextLevel(t *testing.T) {
+	endpoint := newFakeEndpoint(t, false)
+	jm := NewSimpleJobManager()
+	request := newExecutorRequest(endpoint.URL, 1, 2)
+	request.Model2 = nil
+	jobID := jm.CreateJob(request)
+	jm.Enqueue(jobID, func() {})
+
+	started := make(chan int, 10)
+	executor := &Executor{Control: jm.executionControl(jobID)}
+	done := make(chan error, 1)
+	go func() {
+		_, err := executor.Execute(jm.jobContext(jobID), NewExecutionPlan(jobID, request), func(event ExecutionEvent) {
+			switch event.Type {
+			case EventLevelStarted:
+				started <- event.Step.Concurrency
+			case EventLevelDone:
+				if event.Completed == 1 {
+					jm.PauseJob(jobID)
+				}
+			}
+		})
+		done <- err
+	}()
+
+	<-started
+	select {
+	case concurrency := <-started:
+		t.Fatalf("expected the job to pause, but concurrency %d started", concurrency)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if state, _ := jm.GetJobState(jobID); state.Status != "running" || state.Message != "Paused" {
+		t.Fatalf("expected a paused running job, got %s: %s", state.Status, state.Message)
+	}
+
+	if err := jm.UnpauseJob(jobID); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected the job to finish after resuming, got %v", err)
+	}
+
+	// Only running benchmarks can be paused
+	jm.CompleteJob(jobID, nil)
+	if err := jm.PauseJob(jobID); !errors.Is(err, ErrJobNotRunning) {
+		t.Errorf("expected ErrJobNotRunning, got %v", err)
+	}
+}
+
+func TestCancellingPausedJobStopsIt(t *testing.T) {
+	jm := NewSimpleJobManager()
+	jobID := jm.CreateJob(BenchmarkRequest{})
+	jm.Enqueue(jobID, func() {})
+	jm.PauseJob(jobID)
+
+	control := jm.executionControl(jobID)
+	result := make(chan error, 1)
+	go func() {
+		_, err := control.BeforeGroup(jm.jobContext(jobID))
+		result <- err
+	}()
+	jm.CancelJob(jobID)
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the control to report the cancellation, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected cancelling to end the pause")
+	}
+}
+
+func TestSkipLevelAndChangeRemainingLevels(t *testing.T) {
+	var block atomic.Bool
+	block.Store(true)
+	endpoint := newFakeEndpoint(t, false)
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if block.Load() {
+			<-r.Context().Done() // Until the level is skipped
+			return
+		}
+		endpoint.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer blocking.Close()
+	allowGenericCredential(t, blocking.URL)
+
+	jm := NewSimpleJobManager()
+	request := newExecutorRequest(blocking.URL, 4, 1, 8)
+	request.Model2 = nil
+	jobID := jm.CreateJob(request)
+	jm.Enqueue(jobID, func() {})
+
+	var changed []int
+	events, _ := jm.GetJobEvents(jobID)
+	live := newLiveEvents(events, 1)
+	executor := &Executor{Control: jm.executionControl(jobID)}
+	result, err := executor.Execute(jm.jobContext(jobID), NewExecutionPlan(jobID, request), jm.executionEmitter(func(event ExecutionEvent) {
+		live.observe(event)
+		switch {
+		case event.Type == EventLevelStarted && event.Step.Concurrency == 4:
+			if err := jm.SetRemainingLevels(jobID, []int{2, 16}); err != nil {
+				t.Error(err)
+			}
+			if err := jm.SkipLevel(jobID); err != nil {
+				t.Error(err)
+			}
+		case event.Type == EventLevelSkipped:
+			block.Store(false)
+		case event.Type == EventPlanChanged:
+			changed = event.Levels
+		}
+	}))
+	if err != nil {
+		t.Fatalf("expected the job to finish, got %v", err)
+	}
+
+	// The skipped level stays in the list so that it is not run again
+	if len(changed) != 3 || changed[0] != 4 || changed[1] != 2 || changed[2] != 16 {
+		t.Errorf("expected levels [4 2 16], got %v", changed)
+	}
+	if len(result.Model1.Results) != 2 || result.Model1.Results[0].Concurrency != 2 || result.Model1.Results[1].Concurrency != 16 || result.TotalLevels() != 2 {
+		t.Errorf("expected results for 2 and 16, got %+v", result.Model1.Results)
+	}
+	if skipped, _ := result.JobResult()["skippedLevels"].([]string); len(skipped) != 1 || skipped[0] != "model1@4" {
+		t.Errorf("expected the skipped level in the job result, got %v", skipped)
+	}
+	logged, _, _, _ := events.since(0)
+	types := make(map[string]bool)
+	for _, event := range logged {
+		types[event.Type] = true
+	}
+	if !types[JobEventLevelSkipped] || !types[JobEventLevelsChanged] {
+		t.Errorf("expected level_skipped and levels_changed events, got %v", types)
+	}
+	job, _ := jm.GetJob(jobID)
+	if len(job.SkippedLevels) != 1 || len(job.Request.ConcurrencyLevels) != 3 {
+		t.Errorf("expected the job to reflect the changes, got %v and %v", job.SkippedLevels, job.Request.ConcurrencyLevels)
+	}
+}
+
+func TestJobControlHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jm := NewSimpleJobManager()
+	handlers := NewSimpleHandlers(jm)
+	router := gin.New()
+	router.POST("/api/jobs/:jobId/pause", handlers.PauseJob)
+	router.POST("/api/jobs/:jobId/continue", handlers.ContinueJob)
+	router.POST("/api/jobs/:jobId/skip", handlers.SkipLevel)
+	router.PUT("/api/jobs/:jobId/levels", handlers.SetRemainingLevels)
+
+	jobID := jm.CreateJob(BenchmarkRequest{ConcurrencyLevels: []int{1}})
+	jm.Enqueue(jobID, func() {})
+	path := "/api/jobs/" + jobID
+
+	response := serveJSON(router, http.MethodPost, path+"/pause", nil)
+	var state struct {
+		Status string
+		Paused bool
+	}
+	json.Unmarshal(response.Body.Bytes(), &state)
+	if response.Code != http.StatusOK || !state.Paused || state.Status != "running" {
+		t.Fatalf("expected a paused running job, got %d: %s", response.Code, response.Body)
+	}
+	if response := serveJSON(router, http.MethodPost, path+"/continue", nil); response.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", response.Code)
+	}
+	if job, _ := jm.GetJobState(jobID); job.Paused {
+		t.Error("expected the job to continue")
+	}
+
+	expected := []struct {
+		method, path string
+		body         interface{}
+		code         int
+	}{
+		{http.MethodPost, path + "/skip", nil, http.StatusConflict}, // No level is running
+		{http.MethodPut, path + "/levels", map[string][]int{"concurrencyLevels": {0}}, http.StatusBadRequest},
+		{http.MethodPut, path + "/levels", map[string][]int{}, http.StatusBadRequest},
+		{http.MethodPut, path + "/levels", map[string][]int{"concurrencyLevels": {2, 4}}, http.StatusOK},
+		{http.MethodPost, "/api/jobs/missing/pause", nil, http.StatusNotFound},
+	}
+	for _, test := range expected {
+		if response := serveJSON(router, test.method, test.path, test.body); response.Code != test.code {
+			t.Errorf("%s %s: expected %d, got %d: %s", test.method, test.path, test.code, response.Code, response.Body)
+		}
+	}
+
+	jm.CompleteJob(jobID, nil)
+	if response := serveJSON(router, http.MethodPost, path+"/pause", nil); response.Code != http.StatusConflict {
+		t.Errorf("expected 409 for an ended job, got %d", response.Code)
+	}
+}
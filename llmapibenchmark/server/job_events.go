@@ -0,0 +1,400 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"llmapibenchmark/internal/utils"
+)
+
+// Job event types sent over SSE. Job snapshots are sent as unnamed "message" events so
+// clients that only listen to onmessage keep working.
+const (
+	JobEventSnapshot         = "job"
+	JobEventLevelStarted     = "level_started"
+	JobEventRequestCompleted = "request_completed" // Throttled, see requestEventInterval
+	JobEventLevelResult      = "level_result"
+	JobEventLiveMetrics      = "live_metrics"
+	JobEventJobCompleted     = "job_completed" // Sent once, whatever the final status
+	JobEventJobPaused        = "job_paused"    // The job waits before its next level
+	JobEventJobResumed       = "job_resumed"
+	JobEventLevelSkipped     = "level_skipped"
+	JobEventLevelsChanged    = "levels_changed" // The remaining concurrency levels were replaced
+)
+
+// maxJobEvents caps the events kept per job for replay; older ones are dropped first
+const maxJobEvents = 5000
+
+// Live event pacing
+const (
+	requestEventInterval = 250 * time.Millisecond
+	liveMetricsInterval  = time.Second
+	liveMetricsWindow    = 10 * time.Second
+)
+
+// JobEvent is one entry of a job's event log. IDs increase by one per job and are used
+// as SSE event IDs, so a reconnecting client can resume with Last-Event-ID.
+type JobEvent struct {
+	ID   uint64          `json:"id"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ToSSE formats the event as an SSE message
+func (event JobEvent) ToSSE() string {
+	if event.Type == JobEventSnapshot {
+		return fmt.Sprintf("id: %d\ndata: %s\n\n", event.ID, event.Data)
+	}
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// LevelEvent is the data of level_started, level_result and level_skipped events
+type LevelEvent struct {
+	ModelIndex      int                `json:"modelIndex"`
+	Model           string             `json:"model"`
+	Concurrency     int                `json:"concurrency"`
+	CompletedLevels int                `json:"completedLevels"`
+	TotalLevels     int                `json:"totalLevels"`
+	Result          *ConcurrencyResult `json:"result,omitempty"` // level_result only
+}
+
+// RequestCompletedEvent is the data of request_completed events. Counts cover every
+// request of the level, including those finished since the previous event.
+type RequestCompletedEvent struct {
+	ModelIndex        int      `json:"modelIndex"`
+	Concurrency       int      `json:"concurrency"`
+	CompletedRequests int      `json:"completedRequests"`
+	TotalRequests     int      `json:"totalRequests"`
+	TtftMs            *float64 `json:"ttftMs"` // Of the latest request
+	DurationMs        float64  `json:"durationMs"`
+	CompletionTokens  int      `json:"completionTokens"`
+	ErrorClass        string   `json:"errorClass,omitempty"`
+}
+
+// LiveMetricsEvent is the data of live_metrics events
+type LiveMetricsEvent struct {
+	TokensPerSecond   float64 `json:"tokensPerSecond"` // Over the last liveMetricsWindow
+	InFlight          int     `json:"inFlight"`
+	CompletedRequests int     `json:"completedRequests"`
+}
+
+// JobControlEvent is the data of job_paused and job_resumed events
+type JobControlEvent struct {
+	Message string `json:"message"`
+}
+
+// LevelsChangedEvent is the data of levels_changed events
+type LevelsChangedEvent struct {
+	ConcurrencyLevels []int `json:"concurrencyLevels"` // Every level of the job, including those already run
+	CompletedLevels   int   `json:"completedLevels"`
+	TotalLevels       int   `json:"totalLevels"`
+}
+
+// JobCompletedEvent is the data of job_completed events
+type JobCompletedEvent struct {
+	Status      string     `json:"status"`
+	Message     string     `json:"message"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// jobEvents is the append-only event log of a job
+type jobEvents struct {
+	mu      sync.Mutex
+	events  []JobEvent
+	lastID  uint64
+	done    bool          // job_completed was appended
+	changed chan struct{} // Closed and replaced whenever an event is appended
+}
+
+func newJobEvents() *jobEvents {
+	return &jobEvents{changed: make(chan struct{})}
+}
+
+// append adds an event with JSON data. Nothing is added after job_completed.
+func (log *jobEvents) append(eventType string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded, err = json.Marshal(sanitizeAnyValue(data))
+		if err != nil {
+			AppLogger.Error("Failed to encode %s event: %v", eventType, err)
+			return
+		}
+	}
+	log.appendRaw(eventType, encoded)
+}
+
+func (log *jobEvents) appendRaw(eventType string, data json.RawMessage) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.done {
+		return
+	}
+	log.lastID++
+	log.events = append(log.events, JobEvent{ID: log.lastID, Type: eventType, Time: time.Now(), Data: data})
+	if len(log.events) > maxJobEvents {
+		log.events = append([]JobEvent(nil), log.events[len(log.events)-maxJobEvents:]...)
+	}
+	log.done = eventType == JobEventJobCompleted
+	close(log.changed)
+	log.changed = make(chan struct{})
+}
+
+// since returns the events after ID after, whether older events were needed but already
+// dropped, whether the log has ended and a channel that is closed on the next append
+func (log *jobEvents) since(after uint64) (events []JobEvent, gap bool, done bool, changed <-chan struct{}) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if after > log.lastID {
+		after = log.lastID // An ID from before a server restart
+	}
+	first := log.lastID - uint64(len(log.events)) + 1
+	start := 0
+	if after >= first {
+		start = int(after - first + 1)
+	} else if len(log.events) > 0 {
+		gap = after+1 < first
+	}
+	return log.events[start:len(log.events):len(log.events)], gap, log.done, log.changed
+}
+
+// recordSnapshot appends the job's current state and, once it has ended, job_completed.
+// The caller holds the job manager lock.
+func (job *SimpleJob) recordSnapshot() {
+	if job.events == nil {
+		return
+	}
+	data, err := job.ToJSON()
+	if err != nil {
+		return
+	}
+	job.events.appendRaw(JobEventSnapshot, data)
+	if isFinalJobStatus(job.Status) {
+		job.events.append(JobEventJobCompleted, JobCompletedEvent{
+			Status:      job.Status,
+			Message:     job.Message,
+			Error:       job.Error,
+			CompletedAt: job.CompletedAt,
+		})
+	}
+}
+
+func isFinalJobStatus(status string) bool {
+	return status == "completed" || status == "partial" || status == "failed" || status == "cancelled"
+}
+
+// GetJobEvents returns the event log of a job
+func (jm *SimpleJobManager) GetJobEvents(jobID string) (*jobEvents, bool) {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	job, exists := jm.jobs[jobID]
+	if !exists || job.events == nil {
+		return nil, false
+	}
+	return job.events, true
+}
+
+// jobSnapshotEvent returns the current state of a job as an event carrying the log's
+// latest ID, for clients that connect without Last-Event-ID
+func (jm *SimpleJobManager) jobSnapshotEvent(jobID string) (JobEvent, bool) {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	job, exists := jm.jobs[jobID]
+	if !exists || job.events == nil {
+		return JobEvent{}, false
+	}
+	data, err := job.ToJSON()
+	if err != nil {
+		return JobEvent{}, false
+	}
+	job.events.mu.Lock()
+	defer job.events.mu.Unlock()
+	return JobEvent{ID: job.events.lastID, Type: JobEventSnapshot, Time: time.Now(), Data: data}, true
+}
+
+// liveEvents turns Executor events into the fine-grained events of a job's log
+type liveEvents struct {
+	log         *jobEvents
+	repetitions int
+	mu          sync.Mutex
+	levels      map[string]*liveLevel // Running levels by step label
+	completed   int                   // Requests finished in the job
+	tokens      []tokenSample         // Completion tokens inside the rolling window
+	lastRequest time.Time
+}
+
+type liveLevel struct {
+	step      BenchmarkStep
+	total     int
+	completed int
+}
+
+type tokenSample struct {
+	at     time.Time
+	tokens int
+}
+
+func newLiveEvents(log *jobEvents, repetitions int) *liveEvents {
+	return &liveEvents{log: log, repetitions: max(repetitions, 1), levels: make(map[string]*liveLevel)}
+}
+
+// observe records an Executor event; it is called by one goroutine at a time
+func (live *liveEvents) observe(event ExecutionEvent) {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	switch event.Type {
+	case EventLevelStarted:
+		step := *event.Step
+		live.levels[step.Label()] = &liveLevel{step: step, total: step.Concurrency * live.repetitions}
+		live.log.append(JobEventLevelStarted, LevelEvent{
+			ModelIndex:      step.ModelIndex,
+			Model:           step.Model.Name,
+			Concurrency:     step.Concurrency,
+			CompletedLevels: event.Completed,
+			TotalLevels:     event.Total,
+		})
+	case EventRequestDone:
+		level := live.levels[event.Step.Label()]
+		if level == nil {
+			return
+		}
+		level.completed++
+		live.completed++
+		live.addTokens(*event.Request, time.Now())
+		now := time.Now()
+		if now.Sub(live.lastRequest) < requestEventInterval && level.completed < level.total {
+			return
+		}
+		live.lastRequest = now
+		live.log.append(JobEventRequestCompleted, RequestCompletedEvent{
+			ModelIndex:        event.Step.ModelIndex,
+			Concurrency:       event.Step.Concurrency,
+			CompletedRequests: level.completed,
+			TotalRequests:     level.total,
+			TtftMs:            event.Request.TtftMs,
+			DurationMs:        event.Request.DurationMs,
+			CompletionTokens:  event.Request.CompletionTokens,
+			ErrorClass:        event.Request.ErrorClass,
+		})
+	case EventLevelDone:
+		delete(live.levels, event.Step.Label())
+		step := *event.Step
+		live.log.append(JobEventLevelResult, LevelEvent{
+			ModelIndex:      step.ModelIndex,
+			Model:           step.Model.Name,
+			Concurrency:     step.Concurrency,
+			CompletedLevels: event.Completed,
+			TotalLevels:     event.Total,
+			Result:          event.Result,
+		})
+	case EventLevelSkipped:
+		delete(live.levels, event.Step.Label())
+		step := *event.Step
+		live.log.append(JobEventLevelSkipped, LevelEvent{
+			ModelIndex:      step.ModelIndex,
+			Model:           step.Model.Name,
+			Concurrency:     step.Concurrency,
+			CompletedLevels: event.Completed,
+			TotalLevels:     event.Total,
+		})
+	case EventPlanChanged:
+		live.log.append(JobEventLevelsChanged, LevelsChangedEvent{
+			ConcurrencyLevels: event.Levels,
+			CompletedLevels:   event.Completed,
+			TotalLevels:       event.Total,
+		})
+	case EventJobDone:
+		live.levels = make(map[string]*liveLevel)
+	}
+}
+
+// addTokens spreads a request's completion tokens over its chunk arrival times. The
+// caller holds the lock.
+func (live *liveEvents) addTokens(record utils.TraceRecord, now time.Time) {
+	if record.CompletionTokens == 0 {
+		return
+	}
+	sentAt := now.Add(-time.Duration(record.DurationMs * float64(time.Millisecond)))
+	if len(record.ChunkOffsetsMs) == 0 {
+		live.tokens = append(live.tokens, tokenSample{at: now, tokens: record.CompletionTokens})
+		return
+	}
+	perChunk := float64(record.CompletionTokens) / float64(len(record.ChunkOffsetsMs))
+	assigned := 0
+	for i, offset := range record.ChunkOffsetsMs {
+		tokens := int(perChunk*float64(i+1)) - assigned
+		assigned += tokens
+		live.tokens = append(live.tokens, tokenSample{at: sentAt.Add(time.Duration(offset * float64(time.Millisecond))), tokens: tokens})
+	}
+}
+
+// metrics returns the rolling metrics at now and forgets samples outside the window
+func (live *liveEvents) metrics(now time.Time) LiveMetricsEvent {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	cutoff := now.Add(-liveMetricsWindow)
+	kept := live.tokens[:0]
+	tokens := 0
+	for _, sample := range live.tokens {
+		if sample.at.After(cutoff) {
+			kept = append(kept, sample)
+			tokens += sample.tokens
+		}
+	}
+	live.tokens = kept
+
+	inFlight := 0
+	for _, level := range live.levels {
+		// Each run of a level sends one request per concurrency slot
+		if level.completed < level.total {
+			inFlight += level.step.Concurrency - level.completed%level.step.Concurrency
+		}
+	}
+	return LiveMetricsEvent{
+		TokensPerSecond:   float64(tokens) / liveMetricsWindow.Seconds(),
+		InFlight:          inFlight,
+		CompletedRequests: live.completed,
+	}
+}
+
+// publishMetrics appends live_metrics every liveMetricsInterval until stop is closed
+func (live *liveEvents) publishMetrics(stop <-chan struct{}) {
+	ticker := time.NewTicker(liveMetricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			live.log.append(JobEventLiveMetrics, live.metrics(now))
+		}
+	}
+}
+
+// parseLastEventID reads a Last-Event-ID value; invalid values replay nothing
+func parseLastEventID(value string) (uint64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	return id, err == nil
+}
+
+// startLiveEvents records the fine-grained events of a running benchmark job. stop ends
+// the live_metrics ticker.
+func (jm *SimpleJobManager) startLiveEvents(jobID string, repetitions int) (observe func(ExecutionEvent), stop func()) {
+	log, exists := jm.GetJobEvents(jobID)
+	if !exists {
+		return func(ExecutionEvent) {}, func() {}
+	}
+	live := newLiveEvents(log, repetitions)
+	done := make(chan struct{})
+	go live.publishMetrics(done)
+	return live.observe, func() { close(done) }
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestJobEventsReplayAndGaps(t *testing.T) {
+	log := newJobEvents()
+	for i := 0; i < maxJobEvents+10; i++ {
+		log.append(JobEventLiveMetrics, LiveMetricsEvent{CompletedRequests: i})
+	}
+
+	events, gap, done, _ := log.since(maxJobEvents + 5)
+	if gap || done || len(events) != 5 || events[0].ID != maxJobEvents+6 {
+		t.Fatalf("expected the last 5 events, got %d from %d (gap=%v)", len(events), events[0].ID, gap)
+	}
+	if _, gap, _, _ := log.since(3); !gap {
+		t.Error("expected a gap for dropped events")
+	}
+	if events, gap, _, _ := log.since(1 << 40); gap || len(events) != 0 {
+		t.Errorf("expected nothing for an ID from the future, got %d events (gap=%v)", len(events), gap)
+	}
+
+	log.append(JobEventJobCompleted, JobCompletedEvent{Status: "completed"})
+	log.append(JobEventLiveMetrics, LiveMetricsEvent{})
+	events, _, done, _ = log.since(maxJobEvents + 10)
+	if !done || len(events) != 1 || events[0].Type != JobEventJobCompleted {
+		t.Errorf("expected job_completed to end the log, got %+v (done=%v)", events, done)
+	}
+	if sse := events[0].ToSSE(); !strings.HasPrefix(sse, "id: 5011\nevent: job_completed\ndata: {") {
+		t.Errorf("unexpected SSE message %q", sse)
+	}
+}
+
+func TestLiveEventsFromExecutor(t *testing.T) {
+	endpoint := newFakeEndpoint(t, false)
+	request := newExecutorRequest(endpoint.URL, 4)
+	request.Model2 = nil
+	log := newJobEvents()
+	live := newLiveEvents(log, 1)
+	if _, err := (&Executor{}).Execute(context.Background(), NewExecutionPlan("job", request), live.observe); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _, _, _ := log.since(0)
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	// The last request of a level is always reported, however fast it came
+	if len(types) < 3 || types[0] != JobEventLevelStarted || types[len(types)-2] != JobEventRequestCompleted || types[len(types)-1] != JobEventLevelResult {
+		t.Fatalf("unexpected events %v", types)
+	}
+	var completed RequestCompletedEvent
+	json.Unmarshal(events[len(events)-2].Data, &completed)
+	if completed.CompletedRequests != 4 || completed.TotalRequests != 4 {
+		t.Errorf("expected 4 of 4 requests, got %+v", completed)
+	}
+	var level LevelEvent
+	json.Unmarshal(events[len(events)-1].Data, &level)
+	if level.Result == nil || level.Result.Concurrency != 4 || level.CompletedLevels != 1 {
+		t.Errorf("expected the level result, got %+v", level)
+	}
+
+	metrics := live.metrics(time.Now())
+	if metrics.InFlight != 0 || metrics.CompletedRequests != 4 || metrics.TokensPerSecond <= 0 {
+		t.Errorf("unexpected live metrics %+v", metrics)
+	}
+}
+
+func TestLiveEventsArriveWhileLevelRuns(t *testing.T) {
+	// The first request answers at once, the second stalls until released
+	release := make(chan struct{})
+	var requests atomic.Int32
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			<-release
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"token \"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":3}}\n\ndata: [DONE]\n\n")
+	}))
+	t.Cleanup(endpoint.Close)
+	t.Setenv("API_KEY", "test")
+	allowGenericCredential(t, endpoint.URL)
+
+	request := newExecutorRequest(endpoint.URL, 2)
+	request.Model2 = nil
+	log := newJobEvents()
+	live := newLiveEvents(log, 1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := (&Executor{}).Execute(context.Background(), NewExecutionPlan("job", request), live.observe)
+		done <- err
+	}()
+	defer func() {
+		close(release)
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, _, _, _ := log.since(0)
+		var types []string
+		for _, event := range events {
+			types = append(types, event.Type)
+		}
+		if slices.Contains(types, JobEventRequestCompleted) {
+			if slices.Contains(types, JobEventLevelResult) {
+				t.Fatalf("expected request_completed before level_result, got %v", types)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected request_completed while the level runs, got %v", types)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	metrics := live.metrics(time.Now())
+	if metrics.InFlight != 1 || metrics.CompletedRequests != 1 || metrics.TokensPerSecond <= 0 {
+		t.Errorf("expected one request in flight and tokens flowing, got %+v", metrics)
+	}
+}
+
+func TestStreamJobProgressReplaysFromLastEventID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jobManager := NewSimpleJobManager()
+	jobID := jobManager.CreateJob(BenchmarkRequest{})
+	jobManager.UpdateJobProgress(jobID, 10, "first")
+	jobManager.UpdateJobProgress(jobID, 50, "second")
+	jobManager.CompleteJob(jobID, map[string]interface{}{"ok": true})
+
+	router := gin.New()
+	router.GET("/api/jobs/:jobId/stream", NewSSEHandler(jobManager).StreamJobProgress)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/api/jobs/"+jobID+"/stream", nil)
+	request.Header.Set("Last-Event-ID", "1")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	// Events 2 and 3 are job snapshots, 4 ends the job
+	var ids, types []string
+	scanner := bufio.NewScanner(response.Body)
+	eventType := "message"
+	for (len(types) == 0 || types[len(types)-1] != JobEventJobCompleted) && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case line == "":
+			types = append(types, eventType)
+			eventType = "message"
+		}
+	}
+	if strings.Join(ids, ",") != "2,3,4" || strings.Join(types, ",") != "message,message,job_completed" {
+		t.Errorf("expected events 2-4, got ids %v types %v", ids, types)
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Errors returned when resuming or rerunning a job
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobNotResumable  = errors.New("job cannot be resumed")
+	ErrInvalidOverrides = errors.New("invalid overrides")
+)
+
+// ResumeJob creates a benchmark job that continues a partial, failed or cancelled job
+// from its first missing level with the same configuration. It returns the new job's ID
+// and the function that runs it; the caller enqueues it.
+func (jm *SimpleJobManager) ResumeJob(jobID string) (string, func(), error) {
+	jm.mutex.RLock()
+	source, exists := jm.jobs[jobID]
+	if !exists {
+		jm.mutex.RUnlock()
+		return "", nil, ErrJobNotFound
+	}
+	if source.Type != JobTypeBenchmark || (source.Status != "partial" && source.Status != "failed" && source.Status != "cancelled") {
+		status, jobType := source.Status, source.Type
+		jm.mutex.RUnlock()
+		return "", nil, fmt.Errorf("%w: %s job is %s", ErrJobNotResumable, jobType, status)
+	}
+	request := source.Request
+	levels := append([]LevelResult(nil), source.levels...)
+	if source.scheduleSeed != 0 {
+		request.ScheduleSeed = source.scheduleSeed // Keeps a random schedule in the same order
+	}
+	jm.mutex.RUnlock()
+
+	newID := jm.addJob(&SimpleJob{
+		Type:        JobTypeBenchmark,
+		Message:     fmt.Sprintf("Resuming benchmark with %d measured levels...", len(levels)),
+		Request:     request,
+		ResumedFrom: jobID,
+		levels:      levels,
+	})
+	AppLogger.InfoWithContext(&LogContext{JobID: newID}, "Resuming job %s from %d measured levels", jobID, len(levels))
+	return newID, func() { jm.RunBenchmark(newID, request) }, nil
+}
+
+// RerunJob creates a new job with the configuration of jobID. overrides is an optional
+// JSON merge patch (RFC 7386) applied to the cloned request before validation.
+func (jm *SimpleJobManager) RerunJob(jobID string, overrides []byte) (string, func(), error) {
+	jm.mutex.RLock()
+	source, exists := jm.jobs[jobID]
+	if !exists {
+		jm.mutex.RUnlock()
+		return "", nil, ErrJobNotFound
+	}
+	var original interface{} = source.Request
+	if source.Search != nil {
+		original = *source.Search
+	}
+	jm.mutex.RUnlock()
+
+	patched, err := applyMergePatch(original, overrides)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidOverrides, err)
+	}
+
+	var newID string
+	var run func()
+	if source.Type == JobTypeSearch {
+		var request SearchRequest
+		if err := json.Unmarshal(patched, &request); err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidOverrides, err)
+		}
+		if err := validateSearchRequest(&request); err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidOverrides, err)
+		}
+		newID = jm.CreateSearchJob(request)
+		run = func() { jm.RunSearch(newID, request) }
+	} else {
+		var request BenchmarkRequest
+		if err := json.Unmarshal(patched, &request); err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidOverrides, err)
+		}
+		if err := validateAsyncBenchmarkRequest(&request); err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidOverrides, err)
+		}
+		newID = jm.CreateJob(request)
+		run = func() { jm.RunBenchmark(newID, request) }
+	}
+
+	jm.mutex.Lock()
+	jm.jobs[newID].RerunOf = jobID
+	jm.mutex.Unlock()
+	AppLogger.InfoWithContext(&LogContext{JobID: newID}, "Rerunning job %s", jobID)
+	return newID, run, nil
+}
+
+// finishedLevels returns the levels a resumed job does not run again
+func (jm *SimpleJobManager) finishedLevels(jobID string) []LevelResult {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	if job, exists := jm.jobs[jobID]; exists {
+		return append([]LevelResult(nil), job.levels...)
+	}
+	return nil
+}
+
+// applyMergePatch returns the JSON of original with an RFC 7386 merge patch applied
+func applyMergePatch(original interface{}, patch []byte) ([]byte, error) {
+	data, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	if len(patch) == 0 || string(patch) == "null" {
+		return data, nil
+	}
+	var target, changes interface{}
+	if err := json.Unmarshal(data, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	if _, ok := changes.(map[string]interface{}); !ok {
+		return nil, errors.New("overrides must be a JSON object")
+	}
+	return json.Marshal(mergePatch(target, changes))
+}
+
+// mergePatch merges patch into target: objects merge recursively, null removes a key
+// and any other value replaces the target
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	merged, ok := target.(map[string]interface{})
+	if !ok {
+		merged = make(map[string]interface{})
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = mergePatch(merged[key], value)
+	}
+	return merged
+}
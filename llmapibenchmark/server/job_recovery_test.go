@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExecutorKeepsAndResumesFinishedLevels(t *testing.T) {
+	endpoint := newFakeEndpoint(t, false)
+	failing := newFakeEndpoint(t, true)
+	request := newExecutorRequest(endpoint.URL, 1, 2)
+	request.Model2.BaseURL = failing.URL
+
+	// Model 1 runs first and is kept when Model 2 fails
+	result, err := (&Executor{}).Execute(context.Background(), NewExecutionPlan("job", request), func(ExecutionEvent) {})
+	if err == nil || result == nil || !result.Partial || len(result.Finished) != 2 {
+		t.Fatalf("expected a partial result with model 1's levels, got %v and %+v", err, result)
+	}
+	if partial, _ := result.JobResult()["partial"].(bool); !partial || len(result.Model1.Results) != 2 || len(result.Model2.Results) != 0 {
+		t.Errorf("unexpected partial job result %+v", result.JobResult())
+	}
+
+	// Resuming runs only the missing levels and reports every level
+	request.Model2.BaseURL = endpoint.URL
+	plan := NewExecutionPlan("job", request)
+	plan.Finished = result.Finished
+	var started []int
+	resumed, err := (&Executor{}).Execute(context.Background(), plan, func(event ExecutionEvent) {
+		if event.Type == EventLevelStarted {
+			started = append(started, event.Step.ModelIndex)
+		}
+	})
+	if err != nil || resumed.Partial {
+		t.Fatalf("expected the resumed plan to finish, got %v", err)
+	}
+	if len(started) != 2 || started[0] != 2 || started[1] != 2 {
+		t.Errorf("expected only model 2 to run, got %v", started)
+	}
+	if len(resumed.Model1.Results) != 2 || len(resumed.Model2.Results) != 2 || resumed.Comparison == nil {
+		t.Errorf("expected every level in the result, got %+v", resumed)
+	}
+}
+
+func TestCancelledJobEndsPartialWhenRunnerStops(t *testing.T) {
+	jm := NewSimpleJobManager()
+	request := newExecutorRequest("https://genai.example.com", 1, 2)
+	request.Model2.BaseURL = "https://other.example.com"
+	jobID := jm.CreateJob(request)
+	jm.Enqueue(jobID, func() {})
+
+	if !jm.CancelJob(jobID) {
+		t.Fatal("expected a running job to be cancellable")
+	}
+	if state, _ := jm.GetJobState(jobID); state.Status != "running" || state.Message != "Cancelling..." {
+		t.Fatalf("expected the job to run until its runner stops, got %s: %s", state.Status, state.Message)
+	}
+	if status := jm.GetSystemStatus(); status["activeJobs"] != 1 {
+		t.Errorf("expected the job to keep its slot, got %v", status)
+	}
+
+	plan := NewExecutionPlan(jobID, request)
+	execution := newExecutionResult(plan, []LevelResult{{ModelIndex: 1, LevelIndex: 0, Result: ConcurrencyResult{Concurrency: 1}}}, nil, true)
+	jm.PartialJob(jobID, execution, "context canceled")
+	jm.FailJob(jobID, "Job cancelled by user") // Ignored once the job ended
+	job, _ := jm.GetJob(jobID)
+	if job.Status != "partial" || job.Error != "Job cancelled by user" || job.Message != "Benchmark stopped after 1 of 4 levels" {
+		t.Errorf("unexpected partial job %s: %s (%s)", job.Status, job.Message, job.Error)
+	}
+	if status := jm.GetSystemStatus(); status["activeJobs"] != 0 {
+		t.Errorf("expected the slot to be released, got %v", status)
+	}
+
+	// Without levels, a cancelled run ends as cancelled
+	other := jm.CreateJob(request)
+	jm.Enqueue(other, func() {})
+	jm.CancelJob(other)
+	jm.FailJob(other, "Job cancelled by user")
+	if state, _ := jm.GetJobState(other); state.Status != "cancelled" {
+		t.Errorf("expected a cancelled job, got %s", state.Status)
+	}
+}
+
+func TestResumeAndRerunHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jm := NewSimpleJobManager()
+	handlers := NewSimpleHandlers(jm)
+	router := gin.New()
+	router.POST("/api/jobs/:jobId/resume", handlers.ResumeJob)
+	router.POST("/api/jobs/:jobId/rerun", handlers.RerunJob)
+
+	t.Setenv("API_KEY", "test")
+	t.Setenv("BASE_URL", "https://genai.example.com")
+	request := newExecutorRequest("https://genai.example.com", 1, 2)
+	request.Schedule = ScheduleRandom
+	jobID := jm.CreateJob(request)
+	jm.Enqueue(jobID, func() {})
+	plan := NewExecutionPlan(jobID, request)
+	finished := []LevelResult{{ModelIndex: 2, LevelIndex: 1, Result: ConcurrencyResult{Concurrency: 2}}}
+	jm.PartialJob(jobID, newExecutionResult(plan, finished, nil, true), "Benchmark failed for overloaded")
+
+	response := serveJSON(router, http.MethodPost, "/api/jobs/"+jobID+"/resume", nil)
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", response.Code, response.Body)
+	}
+	var started struct{ JobID string }
+	json.Unmarshal(response.Body.Bytes(), &started)
+	resumed, _ := jm.GetJob(started.JobID)
+	if resumed.ResumedFrom != jobID || resumed.Request.ScheduleSeed != plan.Schedule.Seed || len(jm.finishedLevels(started.JobID)) != 1 {
+		t.Errorf("expected the resumed job to keep the levels and seed, got %+v", resumed)
+	}
+
+	// Only jobs that stopped early can be resumed
+	jm.CompleteJob(started.JobID, nil)
+	if response := serveJSON(router, http.MethodPost, "/api/jobs/"+started.JobID+"/resume", nil); response.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a completed job, got %d", response.Code)
+	}
+	if response := serveJSON(router, http.MethodPost, "/api/jobs/missing/rerun", nil); response.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", response.Code)
+	}
+
+	// A rerun clones the request with the overrides merged in
+	response = serveJSON(router, http.MethodPost, "/api/jobs/"+jobID+"/rerun", map[string]interface{}{
+		"concurrencyLevels": []int{4},
+		"model2":            nil,
+		"model1":            map[string]interface{}{"name": "model-c"},
+	})
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", response.Code, response.Body)
+	}
+	json.Unmarshal(response.Body.Bytes(), &started)
+	rerun, _ := jm.GetJob(started.JobID)
+	if rerun.RerunOf != jobID || rerun.Request.Model2 != nil || rerun.Request.Model1.Name != "model-c" || rerun.Request.Model1.SecretID != "generic" || len(rerun.Request.ConcurrencyLevels) != 1 {
+		t.Errorf("unexpected rerun request %+v", rerun.Request)
+	}
+	if response := serveJSON(router, http.MethodPost, "/api/jobs/"+jobID+"/rerun", map[string]interface{}{"concurrencyLevels": []int{}}); response.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid overrides, got %d", response.Code)
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"llmapibenchmark/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxJobSamples caps the request traces kept in memory per job; later requests are
+// counted but not stored
+const maxJobSamples = 50000
+
+// jobSamples collects the per-request traces of a job so they can be downloaded as JSONL
+type jobSamples struct {
+	mu      sync.Mutex
+	records []utils.TraceRecord
+	dropped int
+	done    bool
+	changed chan struct{} // Closed and replaced whenever records are added or the job ends
+}
+
+func newJobSamples() *jobSamples {
+	return &jobSamples{changed: make(chan struct{})}
+}
+
+// WriteTrace stores one record, implementing utils.TraceSink
+func (samples *jobSamples) WriteTrace(record utils.TraceRecord) error {
+	samples.mu.Lock()
+	defer samples.mu.Unlock()
+	if samples.done {
+		return nil
+	}
+	if len(samples.records) >= maxJobSamples {
+		if samples.dropped == 0 {
+			AppLogger.WarnWithContext(&LogContext{JobID: record.JobID}, "Request trace limit of %d reached, further samples are not kept", maxJobSamples)
+		}
+		samples.dropped++
+		return nil
+	}
+	samples.records = append(samples.records, record)
+	samples.notify()
+	return nil
+}
+
+// finish marks the job as ended, so followers stop waiting for records
+func (samples *jobSamples) finish() {
+	samples.mu.Lock()
+	defer samples.mu.Unlock()
+	if !samples.done {
+		samples.done = true
+		samples.notify()
+	}
+}
+
+// notify wakes up followers; the caller holds the lock
+func (samples *jobSamples) notify() {
+	close(samples.changed)
+	samples.changed = make(chan struct{})
+}
+
+// since returns the records from index from on, whether the job has ended and a channel
+// that is closed on the next change
+func (samples *jobSamples) since(from int) ([]utils.TraceRecord, bool, <-chan struct{}) {
+	samples.mu.Lock()
+	defer samples.mu.Unlock()
+	if from > len(samples.records) {
+		from = len(samples.records)
+	}
+	return samples.records[from:len(samples.records):len(samples.records)], samples.done, samples.changed
+}
+
+// GetJobSamples returns the request traces of a job
+func (jm *SimpleJobManager) GetJobSamples(jobID string) (*jobSamples, bool) {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	job, exists := jm.jobs[jobID]
+	if !exists || job.samples == nil {
+		return nil, false
+	}
+	return job.samples, true
+}
+
+// traceSink returns the sink collecting a job's request traces and usage, or nil for
+// unknown jobs
+func (jm *SimpleJobManager) traceSink(jobID string) utils.TraceSink {
+	if samples, ok := jm.GetJobSamples(jobID); ok {
+		return &usageSink{jm: jm, jobID: jobID, next: samples}
+	}
+	return nil
+}
+
+// GetJobSamples streams the per-request traces of a job as JSON lines. With follow=true
+// the response stays open and new records are written as they arrive until the job ends.
+func (h *SimpleHandlers) GetJobSamples(c *gin.Context) {
+	jobID := c.Param("jobId")
+	samples, exists := h.jobManager.GetJobSamples(jobID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	follow := c.Query("follow") == "true"
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	written := 0
+	for {
+		records, done, changed := samples.since(written)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Failed to stream request samples: %v", err)
+				return
+			}
+		}
+		written += len(records)
+		c.Writer.Flush()
+
+		if !follow || done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
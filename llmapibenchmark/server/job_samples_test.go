@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"llmapibenchmark/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetJobSamples_FollowsUntilJobEnds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jobManager := NewSimpleJobManager()
+	jobID := jobManager.CreateJob(BenchmarkRequest{})
+	sink := jobManager.traceSink(jobID)
+	sink.WriteTrace(utils.TraceRecord{JobID: jobID, RequestIndex: 0})
+
+	router := gin.New()
+	router.GET("/api/jobs/:jobId/samples", NewSimpleHandlers(jobManager).GetJobSamples)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/api/jobs/" + jobID + "/samples?follow=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("expected application/x-ndjson, got %q", contentType)
+	}
+
+	// Records written while following arrive, and the stream ends with the job
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		sink.WriteTrace(utils.TraceRecord{JobID: jobID, RequestIndex: 1})
+		jobManager.CompleteJob(jobID, nil)
+	}()
+
+	var indexes []int
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		var record utils.TraceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		indexes = append(indexes, record.RequestIndex)
+	}
+	if len(indexes) != 2 || indexes[0] != 0 || indexes[1] != 1 {
+		t.Errorf("expected records 0 and 1, got %v", indexes)
+	}
+
+	// Nothing is kept once the job has ended
+	sink.WriteTrace(utils.TraceRecord{JobID: jobID, RequestIndex: 2})
+	samples, _ := jobManager.GetJobSamples(jobID)
+	if records, done, _ := samples.since(0); len(records) != 2 || !done {
+		t.Errorf("expected 2 records of an ended job, got %d (done=%v)", len(records), done)
+	}
+}
+
+func TestGetJobSamples_UnknownJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/jobs/:jobId/samples", NewSimpleHandlers(NewSimpleJobManager()).GetJobSamples)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/jobs/missing/samples", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", recorder.Code)
+	}
+}
@@ -47,7 +47,8 @@ type LogContext struct {
 	Operation string
 }
 
-// Logger provides structured logging with proper output streams
+// Logger provides structured logging with proper output streams. Anything that looks
+// like a bearer token or API key is masked before it is written.
 type Logger struct {
 	debug *log.Logger
 	info  *log.Logger
@@ -67,7 +68,7 @@ type JSONLogEntry struct {
 }
 
 // Global logger instance
-var AppLogger *Logger
+var AppLogger = NewLogger()
 
 // NewLogger creates a new structured logger
 func NewLogger() *Logger {
@@ -75,10 +76,10 @@ func NewLogger() *Logger {
 	isCF := os.Getenv("VCAP_APPLICATION") != ""
 	
 	// Normal logs (INFO, DEBUG, WARN) → stdout (white/green in CF)
-	stdout := os.Stdout
+	stdout := redactingWriter{out: os.Stdout}
 	
 	// Error logs (ERROR, FATAL) → stderr (red in CF)
-	stderr := os.Stderr
+	stderr := redactingWriter{out: os.Stderr}
 	
 	return &Logger{
 		debug: log.New(stdout, "[DEBUG] ", log.LstdFlags|log.Lshortfile),
@@ -239,7 +240,7 @@ func (l *Logger) logJSON(level LogLevel, format string, ctx *LogContext, fields
 		output = os.Stdout
 	}
 	
-	encoder := json.NewEncoder(output)
+	encoder := json.NewEncoder(redactingWriter{out: output})
 	encoder.SetEscapeHTML(false)
 	encoder.Encode(entry)
 }
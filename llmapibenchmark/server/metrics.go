@@ -0,0 +1,68 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"llmapibenchmark/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job statuses reported by llmbench_jobs, also when no job has them
+var jobMetricStatuses = []string{"queued", "running", "completed", "partial", "failed", "cancelled"}
+
+var (
+	httpRequestDuration = metrics.NewHistogramVec(metrics.Default, "llmbench_http_request_duration_seconds",
+		"Latency of the server's HTTP handlers by route template.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}, "method", "route", "status")
+	jobsCreated = metrics.NewCounterVec(metrics.Default, "llmbench_jobs_created_total",
+		"Benchmark and search jobs created.", "type")
+)
+
+// The job gauges are computed from the singleton job manager on every scrape
+func init() {
+	metrics.NewGaugeFunc(metrics.Default, "llmbench_jobs",
+		"Jobs known to the server by status.", "status", func() map[string]float64 {
+			return GetJobManager().jobCountsByStatus()
+		})
+	metrics.NewGaugeFunc(metrics.Default, "llmbench_jobs_active",
+		"Jobs currently running.", "", func() map[string]float64 {
+			return map[string]float64{"": float64(GetJobManager().GetActiveJobCount())}
+		})
+}
+
+// jobCountsByStatus counts the registered jobs per status
+func (jm *SimpleJobManager) jobCountsByStatus() map[string]float64 {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+
+	counts := make(map[string]float64, len(jobMetricStatuses))
+	for _, status := range jobMetricStatuses {
+		counts[status] = 0
+	}
+	for _, job := range jm.jobs {
+		counts[job.Status]++
+	}
+	return counts
+}
+
+// MetricsMiddleware records the latency of every request by route template, so path
+// parameters such as job IDs do not create new series
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDuration.With(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(startTime).Seconds())
+	}
+}
+
+// MetricsHandler serves all metrics in the Prometheus text format
+func MetricsHandler(c *gin.Context) {
+	metrics.Default.Handler().ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsHandler_ReportsJobsAndRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jobManager := GetJobManager()
+	jobID := jobManager.CreateJob(BenchmarkRequest{})
+	defer jobManager.RemoveJob(jobID)
+	release := make(chan struct{})
+	defer close(release)
+	if err := jobManager.Enqueue(jobID, func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+	queuedID := jobManager.CreateJob(BenchmarkRequest{}) // Created but not yet enqueued
+	defer jobManager.RemoveJob(queuedID)
+
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+	router.GET("/api/jobs/:jobId", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.GET("/metrics", MetricsHandler)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/jobs/123", nil))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("expected the text format, got %q", recorder.Header().Get("Content-Type"))
+	}
+	body := recorder.Body.String()
+	for _, line := range []string{
+		`llmbench_jobs{status="running"} 1`,
+		`llmbench_jobs{status="queued"} 1`,
+		`llmbench_jobs_active 1`,
+		`llmbench_http_request_duration_seconds_count{method="GET",route="/api/jobs/:jobId",status="404"} `,
+		`# TYPE llmbench_ttft_seconds histogram`,
+		`# TYPE llmbench_request_errors_total counter`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected %q in metrics output:\n%s", line, body)
+		}
+	}
+}
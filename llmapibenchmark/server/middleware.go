@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"runtime/debug"
 	"strings"
@@ -143,7 +144,7 @@ func LoggingMiddleware() gin.HandlerFunc {
 		)
 
 		if query != "" {
-			logMsg += fmt.Sprintf(" | Query: %s", query)
+			logMsg += fmt.Sprintf(" | Query: %s", redactQuery(query))
 		}
 
 		// Add error message if present
@@ -151,10 +152,20 @@ func LoggingMiddleware() gin.HandlerFunc {
 			logMsg += fmt.Sprintf(" | Errors: %s", c.Errors.String())
 		}
 
-		AppLogger.Info(logMsg)
+		AppLogger.Info("%s", logMsg)
 	}
 }
 
+// redactQuery hides access tokens passed as query parameters, see RequireRole
+func redactQuery(query string) string {
+	values, _ := url.ParseQuery(query)
+	if !values.Has("access_token") {
+		return query
+	}
+	values.Set("access_token", "REDACTED")
+	return values.Encode()
+}
+
 // ErrorHandlingMiddleware handles errors and formats them as JSON
 func ErrorHandlingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
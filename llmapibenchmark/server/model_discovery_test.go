@@ -336,9 +336,6 @@ func TestConvertEnhancedToLegacyModel(t *testing.T) {
 	if legacy.BaseURL != enhanced.BaseURL {
 		t.Errorf("Expected BaseURL %s, got %s", enhanced.BaseURL, legacy.BaseURL)
 	}
-	if legacy.APIKey != "" {
-		t.Error("Expected APIKey to be empty for security")
-	}
 }
 
 func TestEnhancedModelsResponse_JSON(t *testing.T) {
@@ -0,0 +1,440 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification events
+const (
+	JobEventCompleted = "job.completed"
+	JobEventFailed    = "job.failed"
+	JobEventCancelled = "job.cancelled"
+	JobEventPartial   = "job.partial" // Failed or cancelled, with some levels measured
+)
+
+// Headers sent with every notification. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with JOB_WEBHOOK_SECRET, prefixed with "sha256=".
+const (
+	webhookEventHeader     = "X-Llmbench-Event"
+	webhookDeliveryHeader  = "X-Llmbench-Delivery"
+	webhookTimestampHeader = "X-Llmbench-Timestamp"
+	webhookSignatureHeader = "X-Llmbench-Signature"
+)
+
+// JobNotification is the JSON body posted when a job ends
+type JobNotification struct {
+	Event           string                `json:"event"`
+	JobID           string                `json:"jobId"`
+	Type            string                `json:"type"`
+	Status          string                `json:"status"`
+	Message         string                `json:"message"`
+	Error           string                `json:"error,omitempty"`
+	CreatedAt       time.Time             `json:"createdAt"`
+	CompletedAt     time.Time             `json:"completedAt"`
+	DurationSeconds float64               `json:"durationSeconds"`
+	Models          []NotificationSummary `json:"models"`
+	ResultsURL      string                `json:"resultsUrl"`
+}
+
+// NotificationSummary highlights the results of one model
+type NotificationSummary struct {
+	Model                     string  `json:"model"`
+	Levels                    int     `json:"levels"` // Measured concurrency levels, 0 unless completed
+	PeakGenerationThroughput  float64 `json:"peakGenerationThroughput,omitempty"`
+	PeakConcurrency           int     `json:"peakConcurrency,omitempty"` // Level of the peak throughput
+	FailedRequests            int     `json:"failedRequests"`
+	MaxSustainableConcurrency *int    `json:"maxSustainableConcurrency,omitempty"` // Search jobs only
+}
+
+// jobWebhookSettings holds the server-wide notification settings:
+//
+//	JOB_WEBHOOK_URL      receives every job notification
+//	JOB_WEBHOOK_FORMAT   "json" (default) or "slack"
+//	JOB_WEBHOOK_SECRET   HMAC-SHA256 key for the signature header, also used for callbackUrl
+//	JOB_WEBHOOK_RETRIES  extra attempts after a failed delivery (default 3)
+//	PUBLIC_URL           external base URL used for resultsUrl, e.g. https://llmbench.example.com
+//
+//	JOB_CALLBACK_ALLOWED_HOSTS  comma-separated host names, IPs or CIDRs that notifications
+//	                            may reach although they are loopback, link-local or private.
+//	                            The host of JOB_WEBHOOK_URL is always allowed.
+type jobWebhookSettings struct {
+	URL          string
+	Format       string
+	Secret       string
+	Retries      int
+	PublicURL    string
+	AllowedHosts []string
+}
+
+func jobWebhookSettingsFromEnv() (jobWebhookSettings, error) {
+	settings := jobWebhookSettings{
+		URL:       os.Getenv("JOB_WEBHOOK_URL"),
+		Format:    os.Getenv("JOB_WEBHOOK_FORMAT"),
+		Secret:    os.Getenv("JOB_WEBHOOK_SECRET"),
+		Retries:   3,
+		PublicURL: strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
+	}
+	for _, host := range strings.Split(os.Getenv("JOB_CALLBACK_ALLOWED_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host == "" {
+			continue
+		}
+		if strings.Contains(host, "/") {
+			if _, _, err := net.ParseCIDR(host); err != nil {
+				return settings, fmt.Errorf("invalid JOB_CALLBACK_ALLOWED_HOSTS entry %q", host)
+			}
+		}
+		settings.AllowedHosts = append(settings.AllowedHosts, host)
+	}
+	if value := os.Getenv("JOB_WEBHOOK_RETRIES"); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return settings, fmt.Errorf("invalid JOB_WEBHOOK_RETRIES %q", value)
+		}
+		settings.Retries = retries
+	}
+	if settings.URL != "" {
+		if err := validateCallbackFormat(settings.URL, settings.Format); err != nil {
+			return settings, fmt.Errorf("invalid JOB_WEBHOOK_URL: %w", err)
+		}
+	}
+	return settings, nil
+}
+
+// allowsHost reports whether notifications may reach host, or one of its addresses when
+// ip is set, although it is internal
+func (settings jobWebhookSettings) allowsHost(host string, ip net.IP) bool {
+	if settings.URL != "" {
+		if parsed, err := url.Parse(settings.URL); err == nil && strings.EqualFold(parsed.Hostname(), host) {
+			return true
+		}
+	}
+	for _, allowed := range settings.AllowedHosts {
+		if _, network, err := net.ParseCIDR(allowed); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+		} else if strings.EqualFold(allowed, host) || (ip != nil && ip.Equal(net.ParseIP(allowed))) {
+			return true
+		}
+	}
+	return false
+}
+
+// jobWebhookConfig returns the notification settings, read once. Invalid settings are
+// logged and disable the server-wide webhook; callbackUrl still works.
+var jobWebhookConfig = sync.OnceValue(func() jobWebhookSettings {
+	settings, err := jobWebhookSettingsFromEnv()
+	if err != nil {
+		AppLogger.Warn("Job webhook disabled: %v", err)
+		settings.URL = ""
+	}
+	return settings
+})
+
+// jobWebhookBackoff is the delay before the first retry, doubled for each further one
+var jobWebhookBackoff = time.Second
+
+// jobWebhookClient connects through dialCallback and without a proxy, so every connection,
+// including those of redirects, goes to an address checked by resolveCallbackHost
+var jobWebhookClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: &http.Transport{DialContext: dialCallback},
+}
+
+// lookupCallbackHost resolves callback hosts; tests replace it
+var lookupCallbackHost = net.DefaultResolver.LookupIPAddr
+
+// validateJobCallback checks the callback URL and format of a request and rejects URLs that
+// reach internal addresses, so callbacks cannot probe the server's network
+func validateJobCallback(callback *JobCallback) error {
+	if err := validateCallbackFormat(callback.CallbackURL, callback.CallbackFormat); err != nil {
+		return err
+	}
+	if callback.CallbackURL == "" {
+		return nil
+	}
+	parsed, _ := url.Parse(callback.CallbackURL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := resolveCallbackHost(ctx, parsed.Hostname()); err != nil {
+		return fmt.Errorf("callbackUrl: %w", err)
+	}
+	return nil
+}
+
+// validateCallbackFormat checks that rawURL, if set, is an absolute http(s) URL and that
+// format is known
+func validateCallbackFormat(rawURL string, format string) error {
+	switch format {
+	case "", "json", "slack":
+	default:
+		return fmt.Errorf("callbackFormat must be \"json\" or \"slack\", got %q", format)
+	}
+	if rawURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("callbackUrl must be an absolute http(s) URL, got %q", rawURL)
+	}
+	return nil
+}
+
+// resolveCallbackHost returns the addresses of a notification host. It fails when any of
+// them is loopback, link-local, private or unspecified, unless JOB_CALLBACK_ALLOWED_HOSTS
+// allows it. A host allowed by name returns no addresses and is resolved as usual.
+func resolveCallbackHost(ctx context.Context, host string) ([]net.IPAddr, error) {
+	settings := jobWebhookConfig()
+	if settings.allowsHost(host, nil) {
+		return nil, nil
+	}
+	addresses := []net.IPAddr{{IP: net.ParseIP(host)}}
+	if addresses[0].IP == nil {
+		resolved, err := lookupCallbackHost(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve %s: %w", host, err)
+		}
+		addresses = resolved
+	}
+	for _, address := range addresses {
+		if isInternalAddress(address.IP) && !settings.allowsHost(host, address.IP) {
+			return nil, fmt.Errorf("%s resolves to the internal address %s, which JOB_CALLBACK_ALLOWED_HOSTS does not allow", host, address.IP)
+		}
+	}
+	return addresses, nil
+}
+
+func isInternalAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// dialCallback connects to the addresses resolveCallbackHost checked rather than resolving
+// the host again, so it cannot pass the check and then resolve to an internal address
+func dialCallback(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := resolveCallbackHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	if len(addresses) == 0 {
+		return dialer.DialContext(ctx, network, address)
+	}
+	for _, resolved := range addresses {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(resolved.IP.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// notifyJob sends the notifications of a job that just ended. It must be called with
+// jm.mutex held, the deliveries run in the background.
+func (jm *SimpleJobManager) notifyJob(job *SimpleJob, event string) {
+	settings := jobWebhookConfig()
+	type target struct{ url, format string }
+	var targets []target
+	if settings.URL != "" {
+		targets = append(targets, target{settings.URL, settings.Format})
+	}
+	if job.Request.CallbackURL != "" {
+		targets = append(targets, target{job.Request.CallbackURL, job.Request.CallbackFormat})
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	notification := newJobNotification(job, event, settings.PublicURL)
+	for _, target := range targets {
+		go deliverJobNotification(target.url, target.format, settings.Secret, settings.Retries, notification)
+	}
+}
+
+// newJobNotification summarizes an ended job
+func newJobNotification(job *SimpleJob, event string, publicURL string) JobNotification {
+	notification := JobNotification{
+		Event:      event,
+		JobID:      job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		Message:    job.Message,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		ResultsURL: publicURL + "/api/jobs/" + job.ID,
+	}
+	if job.CompletedAt != nil {
+		notification.CompletedAt = *job.CompletedAt
+		notification.DurationSeconds = job.CompletedAt.Sub(job.CreatedAt).Seconds()
+	}
+
+	models := []string{job.Request.Model1.Name}
+	if job.Request.Model2 != nil {
+		models = append(models, job.Request.Model2.Name)
+	}
+	summaries := make(map[string]*NotificationSummary, len(models))
+	for _, model := range models {
+		notification.Models = append(notification.Models, NotificationSummary{Model: model})
+		summaries[model] = &notification.Models[len(notification.Models)-1]
+	}
+	if job.Status != "completed" {
+		return notification
+	}
+
+	// Reuse the per-level extraction of the result sinks
+	for _, level := range newSinkResult(job.ID, job.Type, notification.CompletedAt, job.Result).Levels {
+		summary, ok := summaries[level.Model]
+		if !ok {
+			continue
+		}
+		summary.Levels++
+		summary.FailedRequests += int(level.Metrics["failed_requests"])
+		if throughput := level.Metrics["generation_throughput"]; throughput > summary.PeakGenerationThroughput {
+			summary.PeakGenerationThroughput = throughput
+			summary.PeakConcurrency = level.Concurrency
+		}
+	}
+	if resultMap, ok := job.Result.(map[string]interface{}); ok {
+		if search, ok := resultMap["search"].(SearchResult); ok {
+			if summary, ok := summaries[search.Model]; ok {
+				maxSustainable := search.MaxSustainableConcurrency
+				summary.MaxSustainableConcurrency = &maxSustainable
+			}
+		}
+	}
+	return notification
+}
+
+// deliverJobNotification posts a notification, retrying network errors, 429 and 5xx
+func deliverJobNotification(targetURL string, format string, secret string, retries int, notification JobNotification) {
+	logContext := &LogContext{JobID: notification.JobID}
+	var body []byte
+	var err error
+	if format == "slack" {
+		body, err = json.Marshal(map[string]string{"text": slackText(notification)})
+	} else {
+		body, err = json.Marshal(notification)
+	}
+	if err != nil {
+		AppLogger.ErrorWithContext(logContext, "Failed to encode job notification: %v", err)
+		return
+	}
+
+	deliveryID := uuid.New().String()
+	backoff := jobWebhookBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := postJobNotification(targetURL, deliveryID, secret, notification.Event, body)
+		if err == nil {
+			AppLogger.InfoWithContext(logContext, "Delivered %s notification to %s", notification.Event, redactURL(targetURL))
+			return
+		}
+		if !retry || attempt >= retries {
+			AppLogger.ErrorWithContext(logContext, "Failed to deliver %s notification to %s: %v", notification.Event, redactURL(targetURL), err)
+			return
+		}
+		AppLogger.WarnWithContext(logContext, "Notification to %s failed (attempt %d/%d), retrying in %v: %v", redactURL(targetURL), attempt+1, retries+1, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postJobNotification sends one signed delivery and reports whether a failure is worth retrying
+func postJobNotification(targetURL string, deliveryID string, secret string, event string, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobWebhookClient.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhookEventHeader, event)
+	request.Header.Set(webhookDeliveryHeader, deliveryID)
+	request.Header.Set(webhookTimestampHeader, timestamp)
+	if secret != "" {
+		request.Header.Set(webhookSignatureHeader, signWebhook(secret, timestamp, body))
+	}
+
+	response, err := jobWebhookClient.Do(request)
+	if err != nil {
+		return true, err
+	}
+	response.Body.Close()
+	if response.StatusCode/100 == 2 {
+		return false, nil
+	}
+	retry = response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned %s", response.Status)
+}
+
+// signWebhook returns the signature header value for a delivery
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// slackText renders a notification as a Slack incoming-webhook message
+func slackText(notification JobNotification) string {
+	icon := map[string]string{
+		JobEventCompleted: ":white_check_mark:",
+		JobEventFailed:    ":x:",
+		JobEventCancelled: ":no_entry_sign:",
+		JobEventPartial:   ":warning:",
+	}[notification.Event]
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s %s job `%s` %s after %s", icon, notification.Type, notification.JobID, notification.Status,
+		time.Duration(notification.DurationSeconds*float64(time.Second)).Round(time.Second))
+	if notification.Error != "" {
+		fmt.Fprintf(&text, ": %s", notification.Error)
+	}
+	for _, model := range notification.Models {
+		fmt.Fprintf(&text, "\n• *%s*", model.Model)
+		if model.Levels > 0 {
+			fmt.Fprintf(&text, ": peak %.1f tokens/s at concurrency %d over %d levels", model.PeakGenerationThroughput, model.PeakConcurrency, model.Levels)
+		}
+		if model.MaxSustainableConcurrency != nil {
+			fmt.Fprintf(&text, ", max sustainable concurrency %d", *model.MaxSustainableConcurrency)
+		}
+		if model.FailedRequests > 0 {
+			fmt.Fprintf(&text, ", %d failed requests", model.FailedRequests)
+		}
+	}
+	if strings.HasPrefix(notification.ResultsURL, "http") {
+		fmt.Fprintf(&text, "\n<%s|View results>", notification.ResultsURL)
+	} else {
+		fmt.Fprintf(&text, "\nResults: %s", notification.ResultsURL)
+	}
+	return text.String()
+}
+
+// redactURL drops the path and query of a URL for logging, since webhook URLs often embed tokens
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "webhook"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, failures int) (*httptest.Server, chan delivery) {
+	deliveries := make(chan delivery, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		deliveries <- delivery{header: r.Header, body: body}
+	}))
+	t.Cleanup(server.Close)
+	return server, deliveries
+}
+
+func useJobWebhookSettings(t *testing.T, settings jobWebhookSettings) {
+	previousConfig, previousBackoff := jobWebhookConfig, jobWebhookBackoff
+	jobWebhookConfig = func() jobWebhookSettings { return settings }
+	jobWebhookBackoff = time.Millisecond
+	t.Cleanup(func() { jobWebhookConfig, jobWebhookBackoff = previousConfig, previousBackoff })
+}
+
+func receive(t *testing.T, deliveries chan delivery) delivery {
+	select {
+	case received := <-deliveries:
+		return received
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification delivered")
+		return delivery{}
+	}
+}
+
+func TestCompletedJobNotifiesWebhookWithSignature(t *testing.T) {
+	server, deliveries := newWebhookReceiver(t, 2)
+	useJobWebhookSettings(t, jobWebhookSettings{URL: server.URL, Secret: "s3cret", Retries: 2, PublicURL: "https://bench.example.com"})
+
+	jobManager := NewSimpleJobManager()
+	jobID := jobManager.CreateJob(BenchmarkRequest{Model1: Model{Name: "llama"}})
+	jobManager.CompleteJob(jobID, map[string]interface{}{
+		"model1": map[string]interface{}{
+			"model":   "llama",
+			"results": []ConcurrencyResult{{Concurrency: 1, GenerationThroughput: 20}, {Concurrency: 8, GenerationThroughput: 90, FailedRequests: 1}},
+		},
+	})
+
+	received := receive(t, deliveries)
+	timestamp := received.header.Get(webhookTimestampHeader)
+	if signature := received.header.Get(webhookSignatureHeader); signature != signWebhook("s3cret", timestamp, received.body) {
+		t.Errorf("signature %q does not match the body", signature)
+	}
+	if event := received.header.Get(webhookEventHeader); event != JobEventCompleted {
+		t.Errorf("expected %s, got %q", JobEventCompleted, event)
+	}
+
+	var notification JobNotification
+	if err := json.Unmarshal(received.body, &notification); err != nil {
+		t.Fatal(err)
+	}
+	if notification.JobID != jobID || notification.ResultsURL != "https://bench.example.com/api/jobs/"+jobID {
+		t.Errorf("unexpected notification %+v", notification)
+	}
+	if len(notification.Models) != 1 {
+		t.Fatalf("expected one model summary, got %+v", notification.Models)
+	}
+	if summary := notification.Models[0]; summary.Levels != 2 || summary.PeakConcurrency != 8 || summary.PeakGenerationThroughput != 90 || summary.FailedRequests != 1 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestCancelledJobNotifiesCallbackOnce(t *testing.T) {
+	useJobWebhookSettings(t, jobWebhookSettings{AllowedHosts: []string{"127.0.0.1"}})
+	server, deliveries := newWebhookReceiver(t, 0)
+
+	jobManager := NewSimpleJobManager()
+	request := BenchmarkRequest{Model1: Model{Name: "llama"}, JobCallback: JobCallback{CallbackURL: server.URL, CallbackFormat: "slack"}}
+	jobID := jobManager.CreateJob(request)
+	jobManager.SetJobContext(jobID, nil, func() {})
+	jobManager.CancelJob(jobID)
+	jobManager.FailJob(jobID, "context canceled") // The runner reports the cancellation as a failure
+
+	received := receive(t, deliveries)
+	if received.header.Get(webhookSignatureHeader) != "" {
+		t.Error("expected no signature without a secret")
+	}
+	var message map[string]string
+	if err := json.Unmarshal(received.body, &message); err != nil {
+		t.Fatal(err)
+	}
+	if text := message["text"]; !strings.Contains(text, "`"+jobID+"` cancelled") || !strings.Contains(text, "*llama*") {
+		t.Errorf("unexpected Slack text %q", text)
+	}
+	select {
+	case extra := <-deliveries:
+		t.Errorf("expected a single notification, also got %s", extra.body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestValidateJobCallback(t *testing.T) {
+	useJobWebhookSettings(t, jobWebhookSettings{URL: "http://hooks.internal:8080/jobs", AllowedHosts: []string{"metrics.internal", "10.1.0.0/16"}})
+	previousLookup := lookupCallbackHost
+	lookupCallbackHost = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "hooks.slack.com":
+			return []net.IPAddr{{IP: net.ParseIP("52.1.2.3")}}, nil
+		case "rebind.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("52.1.2.3")}, {IP: net.ParseIP("10.0.0.5")}}, nil
+		case "build.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("10.1.2.3")}}, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	t.Cleanup(func() { lookupCallbackHost = previousLookup })
+
+	for _, callback := range []JobCallback{
+		{CallbackURL: "ftp://example.com/hook"},
+		{CallbackURL: "/relative"},
+		{CallbackURL: "https://example.com", CallbackFormat: "teams"},
+		{CallbackURL: "http://127.0.0.1:8080/admin"},
+		{CallbackURL: "http://[::1]/"},
+		{CallbackURL: "http://169.254.169.254/latest/meta-data"},
+		{CallbackURL: "http://192.168.1.10/hook"},
+		{CallbackURL: "https://rebind.example.com/hook"},
+		{CallbackURL: "https://unknown.example.com/hook"},
+	} {
+		if err := validateJobCallback(&callback); err == nil {
+			t.Errorf("expected %+v to be rejected", callback)
+		}
+	}
+	for _, callback := range []JobCallback{
+		{CallbackURL: "https://hooks.slack.com/services/x", CallbackFormat: "slack"},
+		{CallbackURL: "http://hooks.internal:8080/other"}, // Host of JOB_WEBHOOK_URL
+		{CallbackURL: "http://metrics.internal/hook"},     // Allowed by name
+		{CallbackURL: "https://build.example.com/notify"}, // Resolves into an allowed network
+		{CallbackURL: "http://10.1.200.1/notify"},
+	} {
+		if err := validateJobCallback(&callback); err != nil {
+			t.Errorf("unexpected error for %s: %v", callback.CallbackURL, err)
+		}
+	}
+
+	if _, err := jobWebhookClient.Get("http://127.0.0.1:1/"); err == nil || !strings.Contains(err.Error(), "internal address") {
+		t.Errorf("expected deliveries to internal addresses to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// ModelPrice is the price of a model: per token for hosted APIs, per instance-hour for
+// self-hosted models, or both
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"inputPerMillion,omitempty" yaml:"inputPerMillion"`   // Per 1M prompt tokens
+	OutputPerMillion float64 `json:"outputPerMillion,omitempty" yaml:"outputPerMillion"` // Per 1M completion tokens
+	InstanceHour     float64 `json:"instanceHour,omitempty" yaml:"instanceHour"`         // Per hour of one serving instance
+	Instances        int     `json:"instances,omitempty" yaml:"instances"`               // Serving instances, default 1
+}
+
+// cost returns the price of the given tokens
+func (price ModelPrice) cost(inputTokens int, outputTokens int) float64 {
+	return (float64(inputTokens)*price.InputPerMillion + float64(outputTokens)*price.OutputPerMillion) / 1e6
+}
+
+// levelCost returns the price of the tokens of a measured level plus the instances
+// serving it for its duration
+func (price ModelPrice) levelCost(inputTokens int, outputTokens int, seconds float64) float64 {
+	return price.cost(inputTokens, outputTokens) + price.InstanceHour*float64(max(price.Instances, 1))*seconds/3600
+}
+
+// parseModelPrices reads a catalog of prices by model name. JSON is valid YAML, so both
+// formats are accepted.
+func parseModelPrices(data []byte, prices map[string]ModelPrice) error {
+	var catalog map[string]ModelPrice
+	if err := yaml.Unmarshal(data, &catalog); err != nil {
+		return err
+	}
+	for model, price := range catalog {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 || price.InstanceHour < 0 || price.Instances < 0 {
+			return fmt.Errorf("prices of %s must not be negative", model)
+		}
+		prices[model] = price
+	}
+	return nil
+}
+
+// modelPricesFromEnv reads the pricing catalog, a YAML or JSON object of prices by model
+// name, e.g. {"gpt-oss-120b": {"inputPerMillion": 0.15, "outputPerMillion": 0.6}}:
+//
+//	MODEL_PRICING_FILE  path of a catalog file
+//	MODEL_PRICING       inline catalog, overriding the file per model
+func modelPricesFromEnv() (map[string]ModelPrice, error) {
+	prices := make(map[string]ModelPrice)
+	if path := os.Getenv("MODEL_PRICING_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return map[string]ModelPrice{}, fmt.Errorf("MODEL_PRICING_FILE: %w", err)
+		}
+		if err := parseModelPrices(data, prices); err != nil {
+			return map[string]ModelPrice{}, fmt.Errorf("MODEL_PRICING_FILE %s: %w", path, err)
+		}
+	}
+	if value := os.Getenv("MODEL_PRICING"); value != "" {
+		if err := parseModelPrices([]byte(value), prices); err != nil {
+			return map[string]ModelPrice{}, fmt.Errorf("MODEL_PRICING must be an object of prices by model: %w", err)
+		}
+	}
+	return prices, nil
+}
+
+// modelPrices returns the configured prices, read once. Invalid prices are logged and
+// leave every model unpriced.
+var modelPrices = sync.OnceValue(func() map[string]ModelPrice {
+	prices, err := modelPricesFromEnv()
+	if err != nil {
+		AppLogger.Warn("Model pricing disabled: %v", err)
+	} else if len(prices) > 0 {
+		AppLogger.Info("Loaded prices of %d models", len(prices))
+	}
+	return prices
+})
+
+// priceOf returns the price of a model by name
+func priceOf(model string) (ModelPrice, bool) {
+	price, ok := modelPrices()[model]
+	return price, ok
+}
+
+// CostEfficiency relates the cost of measured levels to the output tokens they produced
+type CostEfficiency struct {
+	Total                   float64 `json:"total"`
+	PerThousandOutputTokens float64 `json:"perThousandOutputTokens"` // 0 without output tokens
+	TokensPerDollar         float64 `json:"tokensPerDollar"`         // Output tokens per unit of currency, 0 when free
+}
+
+// newCostEfficiency returns the efficiency of a cost, or nil for unpriced models
+func newCostEfficiency(cost float64, outputTokens int, priced bool) *CostEfficiency {
+	if !priced {
+		return nil
+	}
+	efficiency := &CostEfficiency{Total: cost}
+	if outputTokens > 0 {
+		efficiency.PerThousandOutputTokens = cost / float64(outputTokens) * 1000
+	}
+	if cost > 0 {
+		efficiency.TokensPerDollar = float64(outputTokens) / cost
+	}
+	return efficiency
+}
+
+// priceLevel sets the cost of a measured level of a model, if the model has a price
+func (result *ConcurrencyResult) priceLevel(model string) {
+	price, ok := priceOf(model)
+	cost := price.levelCost(result.PromptTokens, result.CompletionTokens, result.DurationSeconds)
+	result.Cost = newCostEfficiency(cost, result.CompletionTokens, ok)
+}
+
+// totalCost sums the cost of priced levels, or returns nil when none has a price
+func totalCost(results []ConcurrencyResult) *CostEfficiency {
+	var cost float64
+	outputTokens, priced := 0, false
+	for _, result := range results {
+		if result.Cost != nil {
+			cost += result.Cost.Total
+			outputTokens += result.CompletionTokens
+			priced = true
+		}
+	}
+	return newCostEfficiency(cost, outputTokens, priced)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"llmapibenchmark/internal/utils"
+)
+
+func TestModelPricingCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yml")
+	os.WriteFile(path, []byte(`
+gpt-oss-120b:
+  inputPerMillion: 0.15
+  outputPerMillion: 0.6
+llama-self-hosted:
+  instanceHour: 4
+  instances: 2
+`), 0o600)
+	t.Setenv("MODEL_PRICING_FILE", path)
+	t.Setenv("MODEL_PRICING", `{"gpt-oss-120b": {"inputPerMillion": 0.1, "outputPerMillion": 0.4}}`)
+
+	prices, err := modelPricesFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices["gpt-oss-120b"].OutputPerMillion != 0.4 || prices["llama-self-hosted"].Instances != 2 {
+		t.Errorf("expected the inline prices to override the file, got %+v", prices)
+	}
+
+	t.Setenv("MODEL_PRICING", `{"gpt-oss-120b": {"outputPerMillion": -1}}`)
+	if _, err := modelPricesFromEnv(); err == nil {
+		t.Error("expected negative prices to be rejected")
+	}
+}
+
+func TestLevelCosts(t *testing.T) {
+	usePrices(t, map[string]ModelPrice{
+		"hosted":      {InputPerMillion: 1, OutputPerMillion: 2},
+		"self-hosted": {InstanceHour: 3.6, Instances: 2},
+	})
+	measurement := utils.SpeedResult{Concurrency: 4, PromptTokens: 1000, CompletionTokens: 4000, DurationSeconds: 10}
+
+	hosted := newConcurrencyResult("hosted", measurement)
+	if hosted.Cost == nil || math.Abs(hosted.Cost.Total-0.009) > 1e-12 || math.Abs(hosted.Cost.PerThousandOutputTokens-0.00225) > 1e-12 {
+		t.Fatalf("unexpected token cost %+v", hosted.Cost)
+	}
+	selfHosted := newConcurrencyResult("self-hosted", measurement)
+	if selfHosted.Cost == nil || math.Abs(selfHosted.Cost.Total-0.02) > 1e-12 || math.Abs(selfHosted.Cost.TokensPerDollar-200000) > 1e-6 {
+		t.Fatalf("expected 2 instances for 10 seconds at 3.6 per hour, got %+v", selfHosted.Cost)
+	}
+	if unpriced := newConcurrencyResult("unpriced", measurement); unpriced.Cost != nil {
+		t.Errorf("expected no cost for unpriced models, got %+v", unpriced.Cost)
+	}
+
+	result1 := &BenchmarkResult{Model: "hosted", Results: []ConcurrencyResult{hosted, hosted}}
+	result1.Cost = totalCost(result1.Results)
+	result2 := &BenchmarkResult{Model: "self-hosted", Results: []ConcurrencyResult{selfHosted}}
+	result2.Cost = totalCost(result2.Results)
+	if math.Abs(result1.Cost.Total-0.018) > 1e-12 || math.Abs(result1.Cost.PerThousandOutputTokens-0.00225) > 1e-12 {
+		t.Errorf("unexpected run cost %+v", result1.Cost)
+	}
+
+	comparison := compareResults(result1, result2)
+	if comparison.CostEfficiencyWinner != "model1" || comparison.Differences["tokensPerDollar"] <= 0 {
+		t.Errorf("expected the hosted model to be more cost-efficient, got %+v", comparison)
+	}
+	result2.Cost = nil
+	if comparison := compareResults(result1, result2); comparison.CostEfficiencyWinner != "" {
+		t.Errorf("expected no cost winner with an unpriced model, got %q", comparison.CostEfficiencyWinner)
+	}
+
+	csv := generateCSV(ComparisonResponse{Model1: result1, Model2: result2})
+	if !strings.Contains(csv, ",Cost,Cost per 1k Output Tokens,Tokens per Dollar,") || !strings.Contains(csv, "1000,4000,10.00,0.00,0.00,0.009000,0.002250,444444,") {
+		t.Errorf("expected cost columns in the CSV, got:\n%s", csv)
+	}
+	if !strings.Contains(csv, "\nCost\n") || !strings.Contains(csv, "hosted,0.018000,") {
+		t.Errorf("expected the run cost in the CSV, got:\n%s", csv)
+	}
+}
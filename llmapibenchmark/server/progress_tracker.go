@@ -6,7 +6,7 @@ import (
 	"time"
 )
 
-// ProgressTracker manages progress tracking for benchmark jobs
+// ProgressTracker broadcasts the progress of a benchmark job to WebSocket clients
 type ProgressTracker struct {
 	JobID              string
 	StartTime          time.Time
@@ -66,7 +66,11 @@ func (pt *ProgressTracker) SetStatus(status string, message string) {
 func (pt *ProgressTracker) GetProgress() ProgressUpdate {
 	pt.mutex.RLock()
 	defer pt.mutex.RUnlock()
+	return pt.progressLocked()
+}
 
+// progressLocked computes the progress; the caller holds the mutex
+func (pt *ProgressTracker) progressLocked() ProgressUpdate {
 	elapsed := time.Since(pt.StartTime).Seconds()
 	progress := float64(pt.CurrentStep) / float64(pt.TotalSteps) * 100
 
@@ -92,7 +96,7 @@ func (pt *ProgressTracker) GetProgress() ProgressUpdate {
 
 // broadcastProgress sends a progress update to all connected clients
 func (pt *ProgressTracker) broadcastProgress(currentStep string) {
-	progress := pt.GetProgress()
+	progress := pt.progressLocked()
 	progress.CurrentStep = currentStep
 
 	message := NewProgressMessage(pt.JobID, progress)
@@ -194,3 +198,48 @@ func (pt *ProgressTracker) Cancel(reason string) {
 		pt.Hub.BroadcastMessage(data)
 	}
 }
+
+// Observe applies an Executor event to the tracker
+func (pt *ProgressTracker) Observe(event ExecutionEvent) {
+	switch event.Type {
+	case EventLevelStarted:
+		pt.UpdateProgress(event.Completed, event.Step.Model.Name, event.Step.Concurrency,
+			fmt.Sprintf("Testing %s with concurrency %d", event.Step.Model.Name, event.Step.Concurrency))
+	case EventLevelDone:
+		pt.UpdateProgress(event.Completed, event.Step.Model.Name, event.Step.Concurrency,
+			fmt.Sprintf("Completed benchmark with concurrency %d", event.Step.Concurrency))
+	case EventJobDone:
+		switch {
+		case event.Cancelled():
+			pt.Cancel("User requested cancellation")
+		case event.Err != nil:
+			pt.Fail("Benchmark execution failed", event.Err.Error())
+		default:
+			pt.Complete(event.Output.Response())
+		}
+	}
+}
+
+// NewHubObserver returns an Executor observer that keeps a ProgressTracker per job and
+// broadcasts through hub. Register it with SimpleJobManager.ObserveExecutions.
+func NewHubObserver(hub *Hub) func(ExecutionEvent) {
+	var mutex sync.Mutex
+	trackers := make(map[string]*ProgressTracker)
+	return func(event ExecutionEvent) {
+		mutex.Lock()
+		tracker, exists := trackers[event.JobID]
+		if !exists {
+			tracker = NewProgressTracker(event.JobID, event.Total, hub)
+			trackers[event.JobID] = tracker
+		}
+		if event.Type == EventJobDone {
+			delete(trackers, event.JobID)
+		}
+		mutex.Unlock()
+
+		if !exists {
+			tracker.SetStatus("running", "Benchmark started")
+		}
+		tracker.Observe(event)
+	}
+}
@@ -0,0 +1,423 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"llmapibenchmark/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Usage counts the requests, tokens and cost of benchmark requests
+type Usage struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost,omitempty"` // Of the models with a price, see MODEL_PRICING
+}
+
+// Tokens returns the input and output tokens
+func (usage Usage) Tokens() int {
+	return usage.InputTokens + usage.OutputTokens
+}
+
+func (usage Usage) plus(other Usage) Usage {
+	return Usage{
+		Requests:     usage.Requests + other.Requests,
+		InputTokens:  usage.InputTokens + other.InputTokens,
+		OutputTokens: usage.OutputTokens + other.OutputTokens,
+		Cost:         usage.Cost + other.Cost,
+	}
+}
+
+// minus returns the usage left after other, never below zero
+func (usage Usage) minus(other Usage) Usage {
+	return Usage{
+		Requests:     max(usage.Requests-other.Requests, 0),
+		InputTokens:  max(usage.InputTokens-other.InputTokens, 0),
+		OutputTokens: max(usage.OutputTokens-other.OutputTokens, 0),
+		Cost:         max(usage.Cost-other.Cost, 0),
+	}
+}
+
+// JobUsage is the usage of a job in total and by model name
+type JobUsage struct {
+	Usage
+	Models map[string]Usage `json:"models,omitempty"`
+}
+
+// add counts usage of a model. The map is replaced rather than changed, so that jobs
+// being encoded outside the lock never see it change.
+func (usage *JobUsage) add(model string, delta Usage) {
+	usage.Usage = usage.Usage.plus(delta)
+	models := make(map[string]Usage, len(usage.Models)+1)
+	for name, modelUsage := range usage.Models {
+		models[name] = modelUsage
+	}
+	models[model] = models[model].plus(delta)
+	usage.Models = models
+}
+
+// estimatePromptTokens approximates the tokens of a prompt at 4 characters per token
+func estimatePromptTokens(prompt string) int {
+	return max((len(prompt)+3)/4, 1)
+}
+
+// estimateUsage returns the usage of requests to a model, with every request producing
+// maxTokens tokens
+func estimateUsage(model string, requests int, prompt string, maxTokens int) Usage {
+	usage := Usage{
+		Requests:     requests,
+		InputTokens:  requests * estimatePromptTokens(prompt),
+		OutputTokens: requests * maxTokens,
+	}
+	if price, ok := priceOf(model); ok {
+		usage.Cost = price.cost(usage.InputTokens, usage.OutputTokens)
+	}
+	return usage
+}
+
+// EstimateBenchmark returns the pre-flight estimate of a benchmark: every level of every
+// model sends concurrency requests per repetition
+func EstimateBenchmark(request BenchmarkRequest) JobUsage {
+	requests := 0
+	for _, concurrency := range request.ConcurrencyLevels {
+		requests += concurrency * max(request.Repetitions, 1)
+	}
+	models := []Model{request.Model1}
+	if request.Model2 != nil {
+		models = append(models, *request.Model2)
+	}
+	var estimate JobUsage
+	for _, model := range models {
+		estimate.add(model.Name, estimateUsage(model.Name, requests, request.Prompt, request.MaxTokens))
+	}
+	return estimate
+}
+
+// EstimateSearch returns an upper bound for a saturation search: the ramp reaches the
+// maximum concurrency and every bisection step passes
+func EstimateSearch(request SearchRequest) JobUsage {
+	start, limit := max(request.StartConcurrency, 1), request.MaxConcurrency
+	if limit <= 0 {
+		limit = maxSearchConcurrency
+	}
+	requests, lastPass, firstFail := 0, 0, 0
+	for concurrency := min(start, limit); ; concurrency = min(concurrency*2, limit) {
+		requests += concurrency
+		lastPass, firstFail = firstFail, concurrency
+		if concurrency == limit {
+			break
+		}
+	}
+	for firstFail-lastPass > 1 {
+		lastPass = (lastPass + firstFail) / 2
+		requests += lastPass
+	}
+
+	var estimate JobUsage
+	estimate.add(request.Model.Name, estimateUsage(request.Model.Name, requests, request.Prompt, request.MaxTokens))
+	return estimate
+}
+
+// estimateJob returns the pre-flight estimate of a created job. A resumed job does not
+// run the levels it kept again.
+func estimateJob(job *SimpleJob) JobUsage {
+	if job.Search != nil {
+		return EstimateSearch(*job.Search)
+	}
+	request := job.Request
+	if len(job.levels) == 0 {
+		return EstimateBenchmark(request)
+	}
+	measured := make(map[[2]int]bool, len(job.levels))
+	for _, level := range job.levels {
+		measured[[2]int{level.ModelIndex, level.LevelIndex}] = true
+	}
+	models := []Model{request.Model1}
+	if request.Model2 != nil {
+		models = append(models, *request.Model2)
+	}
+	var estimate JobUsage
+	for modelIndex, model := range models {
+		requests := 0
+		for levelIndex, concurrency := range request.ConcurrencyLevels {
+			if !measured[[2]int{modelIndex, levelIndex}] {
+				requests += concurrency * max(request.Repetitions, 1)
+			}
+		}
+		estimate.add(model.Name, estimateUsage(model.Name, requests, request.Prompt, request.MaxTokens))
+	}
+	return estimate
+}
+
+// QuotaLimits bounds usage. Zero disables a limit.
+type QuotaLimits struct {
+	MaxRequests int     `json:"maxRequests,omitempty"`
+	MaxTokens   int     `json:"maxTokens,omitempty"` // Input and output tokens
+	MaxCost     float64 `json:"maxCost,omitempty"`
+}
+
+// quotaSettings are the usage limits of jobs
+//
+//	QUOTA_JOB_MAX_REQUESTS, _TOKENS, _COST           estimate of a single job
+//	QUOTA_USER_DAILY_MAX_REQUESTS, _TOKENS, _COST    per job owner and UTC day
+//	QUOTA_MODEL_DAILY_LIMITS                         JSON object of QuotaLimits by model name, per UTC day
+type quotaSettings struct {
+	PerJob      QuotaLimits            `json:"perJob"`
+	PerUserDay  QuotaLimits            `json:"perUserPerDay"`
+	PerModelDay map[string]QuotaLimits `json:"perModelPerDay,omitempty"`
+}
+
+// quotaLimitsFromEnv reads the limits with the given prefix
+func quotaLimitsFromEnv(prefix string) (QuotaLimits, error) {
+	var limits QuotaLimits
+	for name, limit := range map[string]*int{prefix + "REQUESTS": &limits.MaxRequests, prefix + "TOKENS": &limits.MaxTokens} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return limits, fmt.Errorf("%s must be a non-negative integer, got %q", name, value)
+			}
+			*limit = parsed
+		}
+	}
+	if value := os.Getenv(prefix + "COST"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			return limits, fmt.Errorf("%sCOST must be a non-negative number, got %q", prefix, value)
+		}
+		limits.MaxCost = parsed
+	}
+	return limits, nil
+}
+
+// quotaSettingsFromEnv reads the QUOTA_* variables
+func quotaSettingsFromEnv() (quotaSettings, error) {
+	var settings quotaSettings
+	var err error
+	if settings.PerJob, err = quotaLimitsFromEnv("QUOTA_JOB_MAX_"); err != nil {
+		return quotaSettings{}, err
+	}
+	if settings.PerUserDay, err = quotaLimitsFromEnv("QUOTA_USER_DAILY_MAX_"); err != nil {
+		return quotaSettings{}, err
+	}
+	if value := os.Getenv("QUOTA_MODEL_DAILY_LIMITS"); value != "" {
+		if err := json.Unmarshal([]byte(value), &settings.PerModelDay); err != nil {
+			return quotaSettings{}, fmt.Errorf("QUOTA_MODEL_DAILY_LIMITS must be a JSON object of limits by model: %w", err)
+		}
+	}
+	return settings, nil
+}
+
+// quotaConfig returns the quota settings of new job managers, read once. Invalid settings
+// are logged and replaced by a per-job limit that rejects every job.
+var quotaConfig = sync.OnceValue(func() quotaSettings {
+	settings, err := quotaSettingsFromEnv()
+	if err != nil {
+		AppLogger.Error("Invalid quota settings, rejecting all jobs: %v", err)
+		return quotaSettings{PerJob: QuotaLimits{MaxRequests: -1}}
+	}
+	return settings
+})
+
+// ErrQuotaExceeded is matched by every QuotaError
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota scopes
+const (
+	QuotaScopeJob   = "job"
+	QuotaScopeUser  = "user"
+	QuotaScopeModel = "model"
+)
+
+// QuotaError reports the limit a job would exceed
+type QuotaError struct {
+	Scope    string  `json:"scope"`             // "job", "user" or "model"
+	Subject  string  `json:"subject,omitempty"` // User or model of daily limits
+	Resource string  `json:"resource"`          // "requests", "tokens" or "cost"
+	Limit    float64 `json:"limit"`
+	Used     float64 `json:"used"`     // Today, including the estimates of queued and running jobs
+	Estimate float64 `json:"estimate"` // Of the rejected job
+}
+
+func (e *QuotaError) Error() string {
+	value := func(amount float64) string {
+		if e.Resource == "cost" {
+			return strconv.FormatFloat(amount, 'f', 2, 64)
+		}
+		return strconv.FormatFloat(amount, 'f', 0, 64)
+	}
+	if e.Scope == QuotaScopeJob {
+		return fmt.Sprintf("quota exceeded: the job needs an estimated %s %s, over the per-job limit of %s",
+			value(e.Estimate), e.Resource, value(e.Limit))
+	}
+	return fmt.Sprintf("quota exceeded: %s %s has used %s of %s %s today and the job needs an estimated %s",
+		e.Scope, e.Subject, value(e.Used), value(e.Limit), e.Resource, value(e.Estimate))
+}
+
+// Is makes errors.Is(err, ErrQuotaExceeded) match
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// checkLimits returns the first limit that used plus estimate exceeds
+func checkLimits(limits QuotaLimits, used Usage, estimate Usage, scope string, subject string) error {
+	checks := []struct {
+		resource        string
+		limit, used, up float64
+	}{
+		{"requests", float64(limits.MaxRequests), float64(used.Requests), float64(estimate.Requests)},
+		{"tokens", float64(limits.MaxTokens), float64(used.Tokens()), float64(estimate.Tokens())},
+		{"cost", limits.MaxCost, used.Cost, estimate.Cost},
+	}
+	for _, check := range checks {
+		if check.limit != 0 && check.used+check.up > check.limit {
+			return &QuotaError{Scope: scope, Subject: subject, Resource: check.resource, Limit: max(check.limit, 0), Used: check.used, Estimate: check.up}
+		}
+	}
+	return nil
+}
+
+// quotaLedger is the usage of the current UTC day by job owner and by model
+type quotaLedger struct {
+	day    string
+	users  map[string]Usage
+	models map[string]Usage
+}
+
+// today resets the ledger when the day changed
+func (ledger *quotaLedger) today() *quotaLedger {
+	day := time.Now().UTC().Format(time.DateOnly)
+	if ledger.day != day {
+		*ledger = quotaLedger{day: day, users: make(map[string]Usage), models: make(map[string]Usage)}
+	}
+	return ledger
+}
+
+// checkQuotaLocked checks a job's estimate against the limits. Daily usage counts what
+// was spent today plus the rest of the estimates of other queued and running jobs. The
+// caller holds the lock.
+func (jm *SimpleJobManager) checkQuotaLocked(owner string, estimate JobUsage, except *SimpleJob) error {
+	settings := jm.quotas
+	if err := checkLimits(settings.PerJob, Usage{}, estimate.Usage, QuotaScopeJob, ""); err != nil {
+		return err
+	}
+
+	ledger := jm.spending.today()
+	userUsed := ledger.users[owner]
+	modelsUsed := make(map[string]Usage)
+	for model := range estimate.Models {
+		modelsUsed[model] = ledger.models[model]
+	}
+	for _, job := range jm.jobs {
+		if job == except || job.Estimate == nil || (job.Status != "queued" && job.Status != "running") {
+			continue
+		}
+		if job.Owner == owner {
+			userUsed = userUsed.plus(job.Estimate.Usage.minus(job.Usage.Usage))
+		}
+		for model, used := range modelsUsed {
+			modelsUsed[model] = used.plus(job.Estimate.Models[model].minus(job.Usage.Models[model]))
+		}
+	}
+
+	if err := checkLimits(settings.PerUserDay, userUsed, estimate.Usage, QuotaScopeUser, owner); err != nil {
+		return err
+	}
+	for model, used := range modelsUsed {
+		if err := checkLimits(settings.PerModelDay[model], used, estimate.Models[model], QuotaScopeModel, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckQuota checks whether owner may start a job with the given estimate now
+func (jm *SimpleJobManager) CheckQuota(owner string, estimate JobUsage) error {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+	return jm.checkQuotaLocked(owner, estimate, nil)
+}
+
+// recordUsage counts a finished request towards its job and the daily ledger
+func (jm *SimpleJobManager) recordUsage(jobID string, record utils.TraceRecord) {
+	delta := Usage{Requests: 1, InputTokens: record.PromptTokens, OutputTokens: record.CompletionTokens}
+	if price, ok := priceOf(record.Model); ok {
+		delta.Cost = price.cost(record.PromptTokens, record.CompletionTokens)
+	}
+
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+	job, exists := jm.jobs[jobID]
+	if !exists {
+		return
+	}
+	job.Usage.add(record.Model, delta)
+	ledger := jm.spending.today()
+	ledger.users[job.Owner] = ledger.users[job.Owner].plus(delta)
+	ledger.models[record.Model] = ledger.models[record.Model].plus(delta)
+}
+
+// withUsage adds a job's usage to a map result
+func withUsage(result interface{}, usage JobUsage) interface{} {
+	if values, ok := result.(map[string]interface{}); ok {
+		values["usage"] = usage
+	}
+	return result
+}
+
+// usageSink counts the usage of a job's requests before passing their traces on
+type usageSink struct {
+	jm    *SimpleJobManager
+	jobID string
+	next  utils.TraceSink
+}
+
+// WriteTrace implements utils.TraceSink
+func (sink *usageSink) WriteTrace(record utils.TraceRecord) error {
+	sink.jm.recordUsage(sink.jobID, record)
+	return sink.next.WriteTrace(record)
+}
+
+// spendingSnapshotLocked returns today's usage and the limits for /api/status. The caller
+// holds the lock.
+func (jm *SimpleJobManager) spendingSnapshotLocked() map[string]interface{} {
+	day := time.Now().UTC().Format(time.DateOnly)
+	users, models := make(map[string]Usage), make(map[string]Usage)
+	if jm.spending.day == day {
+		for user, usage := range jm.spending.users {
+			users[user] = usage
+		}
+		for model, usage := range jm.spending.models {
+			models[model] = usage
+		}
+	}
+	return map[string]interface{}{
+		"day":    day,
+		"users":  users,
+		"models": models,
+		"limits": jm.quotas,
+	}
+}
+
+// quotaErrorStatus returns the HTTP status of a quota error, or 0 for other errors. Jobs
+// over a per-job limit are bad requests; daily limits answer 429 until the next UTC day.
+func quotaErrorStatus(c *gin.Context, err error) int {
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) {
+		return 0
+	}
+	if quotaErr.Scope == QuotaScopeJob {
+		return http.StatusBadRequest
+	}
+	tomorrow := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	c.Header("Retry-After", strconv.Itoa(int(time.Until(tomorrow).Seconds())+1))
+	return http.StatusTooManyRequests
+}
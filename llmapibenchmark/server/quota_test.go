@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+
+	"llmapibenchmark/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usePrices replaces the model prices for a test
+func usePrices(t *testing.T, prices map[string]ModelPrice) {
+	previous := modelPrices
+	modelPrices = func() map[string]ModelPrice { return prices }
+	t.Cleanup(func() { modelPrices = previous })
+}
+
+func TestUsageEstimates(t *testing.T) {
+	usePrices(t, map[string]ModelPrice{"model-a": {InputPerMillion: 1, OutputPerMillion: 2}})
+
+	request := newExecutorRequest("https://genai.example.com", 1, 2)
+	request.Repetitions = 2
+	estimate := EstimateBenchmark(request)
+	modelA := estimate.Models["model-a"]
+	if modelA.Requests != 6 || modelA.InputTokens != 6 || modelA.OutputTokens != 48 {
+		t.Errorf("expected 6 requests of 1+8 tokens to model-a, got %+v", modelA)
+	}
+	if math.Abs(modelA.Cost-(6*1+48*2)/1e6) > 1e-12 || estimate.Models["model-b"].Cost != 0 {
+		t.Errorf("expected only model-a to have a cost, got %+v", estimate.Models)
+	}
+	if estimate.Requests != 12 || estimate.Tokens() != 108 {
+		t.Errorf("unexpected total %+v", estimate.Usage)
+	}
+
+	// Ramp 1..64, 100, then bisect 82, 91, 95, 97, 98, 99
+	search := EstimateSearch(SearchRequest{Model: Model{Name: "model-a"}, Prompt: "hi", MaxTokens: 8, MaxConcurrency: 100})
+	if search.Requests != 127+100+562 {
+		t.Errorf("expected the worst case of 789 requests, got %d", search.Requests)
+	}
+
+	// A resumed job only runs its missing levels
+	job := &SimpleJob{Request: request, levels: []LevelResult{{ModelIndex: 0, LevelIndex: 1}}}
+	if resumed := estimateJob(job); resumed.Models["model-a"].Requests != 2 || resumed.Models["model-b"].Requests != 6 {
+		t.Errorf("expected the measured level to be left out, got %+v", resumed.Models)
+	}
+}
+
+func TestQuotaLimits(t *testing.T) {
+	jm := NewSimpleJobManager()
+	jm.quotas = quotaSettings{
+		PerJob:      QuotaLimits{MaxRequests: 10},
+		PerUserDay:  QuotaLimits{MaxTokens: 100},
+		PerModelDay: map[string]QuotaLimits{"model-b": {MaxRequests: 5}},
+	}
+	start := func(owner string, levels ...int) (string, error) {
+		jobID := jm.CreateJob(newExecutorRequest("https://genai.example.com", levels...))
+		jm.setJobOwner(jobID, owner)
+		return jobID, jm.Enqueue(jobID, func() {})
+	}
+
+	jobID, err := start("alice", 1, 2) // 6 requests, 54 tokens
+	if err != nil {
+		t.Fatal(err)
+	}
+	var quotaErr *QuotaError
+	if _, err := start("alice", 5, 5); !errors.As(err, &quotaErr) || quotaErr.Scope != QuotaScopeJob || !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected the per-job limit to reject 20 requests, got %v", err)
+	}
+	// The estimate of the running job counts towards the day
+	if _, err := start("alice", 1, 2); !errors.As(err, &quotaErr) || quotaErr.Scope != QuotaScopeUser || quotaErr.Used != 54 {
+		t.Errorf("expected alice's daily token limit to be exceeded, got %v", err)
+	}
+	if _, err := start("bob", 1, 2); !errors.As(err, &quotaErr) || quotaErr.Scope != QuotaScopeModel || quotaErr.Subject != "model-b" {
+		t.Errorf("expected the daily limit of model-b to be exceeded, got %v", err)
+	}
+	if len(jm.ListJobs()) != 1 {
+		t.Errorf("expected rejected jobs to be dropped, got %d jobs", len(jm.ListJobs()))
+	}
+
+	// Usage is counted from the request traces
+	sink := jm.traceSink(jobID)
+	sink.WriteTrace(utils.TraceRecord{JobID: jobID, Model: "model-a", PromptTokens: 3, CompletionTokens: 8})
+	sink.WriteTrace(utils.TraceRecord{JobID: jobID, Model: "model-b", PromptTokens: 3, CompletionTokens: 5})
+	job, _ := jm.GetJob(jobID)
+	if job.Usage.Requests != 2 || job.Usage.Tokens() != 19 || job.Usage.Models["model-b"].OutputTokens != 5 {
+		t.Errorf("unexpected job usage %+v", job.Usage)
+	}
+	spending := jm.GetSystemStatus()["spending"].(map[string]interface{})
+	if users := spending["users"].(map[string]Usage); users["alice"].Requests != 2 {
+		t.Errorf("expected alice's spending in the status, got %+v", users)
+	}
+}
+
+func TestQuotaResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	endpoint := newFakeEndpoint(t, false)
+	jm := NewSimpleJobManager()
+	jm.quotas = quotaSettings{PerJob: QuotaLimits{MaxRequests: 10}, PerUserDay: QuotaLimits{MaxRequests: 12}}
+	handlers := NewSimpleHandlers(jm)
+	router := gin.New()
+	router.POST("/api/benchmark/async", handlers.StartBenchmark)
+	router.POST("/api/benchmark/estimate", handlers.EstimateBenchmark)
+
+	response := serveAs(router, http.MethodPost, "/api/benchmark/async", "", newExecutorRequest(endpoint.URL, 5, 5))
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("expected a job over the per-job limit to be a bad request, got %d: %s", response.Code, response.Body)
+	}
+	if response := serveAs(router, http.MethodPost, "/api/benchmark/async", "", newExecutorRequest(endpoint.URL, 1, 2)); response.Code != http.StatusAccepted {
+		t.Fatalf("expected the first job to start, got %d: %s", response.Code, response.Body)
+	}
+	response = serveAs(router, http.MethodPost, "/api/benchmark/async", "", newExecutorRequest(endpoint.URL, 2, 2))
+	if response.Code != http.StatusTooManyRequests || response.Header().Get("Retry-After") == "" {
+		t.Errorf("expected the daily limit to answer 429 with Retry-After, got %d: %s", response.Code, response.Body)
+	}
+
+	response = serveAs(router, http.MethodPost, "/api/benchmark/estimate", "", newExecutorRequest(endpoint.URL, 2, 2))
+	var estimate struct {
+		Estimate JobUsage
+		Allowed  bool
+		Quota    QuotaError
+	}
+	json.Unmarshal(response.Body.Bytes(), &estimate)
+	if response.Code != http.StatusOK || estimate.Allowed || estimate.Estimate.Requests != 8 || estimate.Quota.Resource != "requests" {
+		t.Errorf("expected an estimate over the daily limit, got %d: %s", response.Code, response.Body)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"llmapibenchmark/internal/utils"
+)
+
+// rateLimitSettings are the client-side limits of the model endpoints
+type rateLimitSettings struct {
+	Default   utils.RateLimit            `json:"default"`             // Of every endpoint without an own limit
+	Endpoints map[string]utils.RateLimit `json:"endpoints,omitempty"` // By service ID or base URL
+}
+
+// rateLimitSettingsFromEnv reads the client-side rate limits:
+//
+//	RATE_LIMIT_REQUESTS_PER_MINUTE  requests per minute to each endpoint
+//	RATE_LIMIT_TOKENS_PER_MINUTE    prompt and completion tokens per minute to each endpoint
+//	RATE_LIMITS                     JSON object of {"requestsPerMinute", "tokensPerMinute"} by
+//	                                service ID or base URL, overriding the defaults
+func rateLimitSettingsFromEnv() (rateLimitSettings, error) {
+	var settings rateLimitSettings
+	for name, limit := range map[string]*float64{
+		"RATE_LIMIT_REQUESTS_PER_MINUTE": &settings.Default.RequestsPerMinute,
+		"RATE_LIMIT_TOKENS_PER_MINUTE":   &settings.Default.TokensPerMinute,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed < 0 {
+				return rateLimitSettings{}, fmt.Errorf("%s must be a non-negative number, got %q", name, value)
+			}
+			*limit = parsed
+		}
+	}
+	if value := os.Getenv("RATE_LIMITS"); value != "" {
+		var endpoints map[string]utils.RateLimit
+		if err := json.Unmarshal([]byte(value), &endpoints); err != nil {
+			return rateLimitSettings{}, fmt.Errorf("RATE_LIMITS must be a JSON object of limits by service ID or base URL: %w", err)
+		}
+		settings.Endpoints = make(map[string]utils.RateLimit, len(endpoints))
+		for endpoint, limit := range endpoints {
+			if limit.RequestsPerMinute < 0 || limit.TokensPerMinute < 0 {
+				return rateLimitSettings{}, fmt.Errorf("RATE_LIMITS: limits of %s must not be negative", endpoint)
+			}
+			if strings.Contains(endpoint, "://") {
+				endpoint = backendKey(endpoint)
+			}
+			settings.Endpoints[endpoint] = limit
+		}
+	}
+	return settings, nil
+}
+
+// rateLimitConfig returns the rate limit settings, read once. Invalid settings are logged
+// and leave the endpoints unlimited.
+var rateLimitConfig = sync.OnceValue(func() rateLimitSettings {
+	settings, err := rateLimitSettingsFromEnv()
+	if err != nil {
+		AppLogger.Warn("Rate limits disabled: %v", err)
+		return rateLimitSettings{}
+	}
+	return settings
+})
+
+// rateLimiterForModel returns the limiter shared by every job sending to the model's
+// endpoint, or nil when it is not limited. A limit for the model's service ID takes
+// precedence over one for its base URL.
+func rateLimiterForModel(model Model) *utils.RateLimiter {
+	settings := rateLimitConfig()
+	if serviceID, _, ok := strings.Cut(model.ID, "|"); ok {
+		if limit, exists := settings.Endpoints[serviceID]; exists {
+			return utils.SharedRateLimiter("service:"+serviceID, limit)
+		}
+	}
+	endpoint := backendKey(model.BaseURL)
+	if limit, exists := settings.Endpoints[endpoint]; exists {
+		return utils.SharedRateLimiter(endpoint, limit)
+	}
+	return utils.SharedRateLimiter(endpoint, settings.Default)
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestRateLimitSettings(t *testing.T) {
+	t.Setenv("RATE_LIMIT_REQUESTS_PER_MINUTE", "120")
+	t.Setenv("RATE_LIMITS", `{"openai": {"tokensPerMinute": 90000}, "https://API.example.com/v1": {"requestsPerMinute": 30}}`)
+
+	settings, err := rateLimitSettingsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Default.RequestsPerMinute != 120 || settings.Default.TokensPerMinute != 0 {
+		t.Errorf("unexpected default limit %+v", settings.Default)
+	}
+	if settings.Endpoints["openai"].TokensPerMinute != 90000 || settings.Endpoints["api.example.com/v1"].RequestsPerMinute != 30 {
+		t.Errorf("expected limits by service ID and normalized base URL, got %+v", settings.Endpoints)
+	}
+
+	t.Setenv("RATE_LIMITS", `{"openai": {"requestsPerMinute": -1}}`)
+	if _, err := rateLimitSettingsFromEnv(); err == nil {
+		t.Error("expected negative limits to be rejected")
+	}
+	t.Setenv("RATE_LIMIT_TOKENS_PER_MINUTE", "many")
+	if _, err := rateLimitSettingsFromEnv(); err == nil {
+		t.Error("expected an invalid default to be rejected")
+	}
+}
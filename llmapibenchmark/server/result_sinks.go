@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"llmapibenchmark/internal/sinks"
+)
+
+// resultSinkConfigFromEnv reads the sinks completed jobs are pushed to:
+//
+//	RESULT_SINK_INFLUX_URL       InfluxDB write URL, e.g. http://influx:8086/api/v2/write?org=o&bucket=b
+//	RESULT_SINK_INFLUX_TOKEN     InfluxDB API token
+//	RESULT_SINK_PUSHGATEWAY_URL  Prometheus Pushgateway base URL
+//	RESULT_SINK_WEBHOOK_URL      receives every result as a JSON POST
+//	RESULT_SINK_DIR              directory for timestamped JSON result files
+//	RESULT_SINK_RETRIES          extra attempts per sink after a failure (default 3)
+func resultSinkConfigFromEnv() (sinks.Config, error) {
+	config := sinks.Config{
+		InfluxURL:      os.Getenv("RESULT_SINK_INFLUX_URL"),
+		InfluxToken:    os.Getenv("RESULT_SINK_INFLUX_TOKEN"),
+		PushgatewayURL: os.Getenv("RESULT_SINK_PUSHGATEWAY_URL"),
+		WebhookURL:     os.Getenv("RESULT_SINK_WEBHOOK_URL"),
+		Directory:      os.Getenv("RESULT_SINK_DIR"),
+		Retries:        3,
+	}
+	if value := os.Getenv("RESULT_SINK_RETRIES"); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid RESULT_SINK_RETRIES: %w", err)
+		}
+		config.Retries = retries
+	}
+	return config, nil
+}
+
+// resultSinkDispatcher returns the sinks configured in the environment, or nil when
+// there are none. Invalid settings are logged once and disable the sinks.
+var resultSinkDispatcher = sync.OnceValue(func() *sinks.Dispatcher {
+	config, err := resultSinkConfigFromEnv()
+	if err == nil {
+		var dispatcher *sinks.Dispatcher
+		if dispatcher, err = sinks.New(config); err == nil {
+			if dispatcher != nil {
+				AppLogger.Info("Publishing completed results to %d result sinks", len(dispatcher.Sinks))
+			}
+			return dispatcher
+		}
+	}
+	AppLogger.Warn("Result sinks disabled: %v", err)
+	return nil
+})
+
+// resultSinkTimeout bounds publishing one result, retries included
+const resultSinkTimeout = 5 * time.Minute
+
+// publishJobResult pushes a completed job's result to the configured sinks
+func publishJobResult(jobID string, jobType string, completedAt time.Time, result interface{}) {
+	dispatcher := resultSinkDispatcher()
+	if dispatcher == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resultSinkTimeout)
+	defer cancel()
+	if err := dispatcher.Publish(ctx, newSinkResult(jobID, jobType, completedAt, result)); err != nil {
+		AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Failed to publish result: %v", err)
+	}
+}
+
+// newSinkResult extracts the measured levels from a benchmark or search job result
+func newSinkResult(jobID string, jobType string, completedAt time.Time, result interface{}) sinks.Result {
+	sinkResult := sinks.Result{
+		ID:        jobID,
+		Type:      jobType,
+		Source:    "server",
+		Timestamp: completedAt,
+		Payload:   result,
+	}
+	resultMap, ok := result.(map[string]interface{})
+	if !ok {
+		return sinkResult
+	}
+	for _, key := range []string{"model1", "model2"} {
+		modelResult, ok := resultMap[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		model, _ := modelResult["model"].(string)
+		levels, _ := modelResult["results"].([]ConcurrencyResult)
+		for _, level := range levels {
+			sinkResult.Levels = append(sinkResult.Levels, sinks.Level{Model: model, Concurrency: level.Concurrency, Metrics: level.sinkMetrics()})
+		}
+	}
+	if search, ok := resultMap["search"].(SearchResult); ok {
+		for _, step := range search.Steps {
+			sinkResult.Levels = append(sinkResult.Levels, sinks.Level{Model: search.Model, Concurrency: step.Concurrency, Metrics: step.Result.sinkMetrics()})
+		}
+	}
+	return sinkResult
+}
+
+// sinkMetrics returns the level's metrics under the names shared by all sinks. Cost
+// metrics are added for models with a price.
+func (result ConcurrencyResult) sinkMetrics() map[string]float64 {
+	metrics := map[string]float64{
+		"generation_throughput": result.GenerationThroughput,
+		"prompt_throughput":     result.PromptThroughput,
+		"min_ttft":              result.MinTTFT,
+		"max_ttft":              result.MaxTTFT,
+		"p95_ttft":              result.P95TTFT,
+		"per_user_throughput":   result.PerUserThroughput,
+		"requests_per_second":   result.RequestsPerSecond,
+		"goodput_ratio":         result.GoodputRatio,
+		"requests":              float64(result.Requests),
+		"failed_requests":       float64(result.FailedRequests),
+		"error_rate":            result.ErrorRate,
+		"prompt_tokens":         float64(result.PromptTokens),
+		"completion_tokens":     float64(result.CompletionTokens),
+	}
+	if result.Cost != nil {
+		metrics["cost"] = result.Cost.Total
+		metrics["cost_per_1k_output_tokens"] = result.Cost.PerThousandOutputTokens
+		metrics["tokens_per_dollar"] = result.Cost.TokensPerDollar
+	}
+	return metrics
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSinkResultExtractsLevels(t *testing.T) {
+	completedAt := time.Now()
+	benchmark := map[string]interface{}{
+		"model1": map[string]interface{}{
+			"model":   "llama",
+			"results": []ConcurrencyResult{{Concurrency: 1, GenerationThroughput: 10}, {Concurrency: 4, GenerationThroughput: 35, FailedRequests: 2}},
+		},
+		"model2": map[string]interface{}{
+			"model":   "mistral",
+			"results": []ConcurrencyResult{{Concurrency: 1, GenerationThroughput: 12}},
+		},
+	}
+	result := newSinkResult("job-1", "benchmark", completedAt, benchmark)
+	if result.ID != "job-1" || result.Source != "server" || !result.Timestamp.Equal(completedAt) || len(result.Levels) != 3 {
+		t.Fatalf("unexpected sink result %+v", result)
+	}
+	if level := result.Levels[1]; level.Model != "llama" || level.Concurrency != 4 || level.Metrics["generation_throughput"] != 35 || level.Metrics["failed_requests"] != 2 {
+		t.Errorf("unexpected level %+v", level)
+	}
+	if result.Levels[2].Model != "mistral" {
+		t.Errorf("expected the second model last, got %+v", result.Levels[2])
+	}
+
+	search := map[string]interface{}{"search": SearchResult{
+		Model: "llama",
+		Steps: []SearchStep{{Concurrency: 8, Result: ConcurrencyResult{Concurrency: 8, P95TTFT: 1.5}}},
+	}}
+	result = newSinkResult("job-2", "search", completedAt, search)
+	if len(result.Levels) != 1 || result.Levels[0].Concurrency != 8 || result.Levels[0].Metrics["p95_ttft"] != 1.5 {
+		t.Errorf("unexpected search levels %+v", result.Levels)
+	}
+}
@@ -14,40 +14,61 @@ func SetupRoutes(router *gin.Engine) {
 	jobManager := GetJobManager()
 	sseHandler := NewSSEHandler(jobManager)
 	simpleHandlers := NewSimpleHandlers(jobManager)
+	authConfig() // Logs whether authentication is enabled
+
+	// Roles required by the routes, see auth.go
+	viewer := RequireRole(RoleViewer)
+	runner := RequireRole(RoleRunner)
 	
 	// Apply global middleware in order
 	router.Use(RecoveryMiddleware())      // Recover from panics
 	router.Use(SecurityHeadersMiddleware()) // Add security headers
 	router.Use(CORSMiddleware())          // Handle CORS
 	router.Use(LoggingMiddleware())       // Log requests
+	router.Use(MetricsMiddleware())       // Record handler latency
 	router.Use(ErrorHandlingMiddleware()) // Handle errors
 
+	// Prometheus metrics for benchmarks and the server itself
+	router.GET("/metrics", viewer, MetricsHandler)
+
+	// WebSocket control channel: job and system status subscriptions, cancel, pause and resume
+	mountWebSocket(router, jobManager, viewer)
+
 	// API routes group
 	api := router.Group("/api")
 	{
 		// Apply request validation middleware to API routes
 		api.Use(RequestValidationMiddleware())
 
-		// Health check endpoint
+		// Health check endpoint, public for platform health checks
 		api.GET("/health", HealthHandler)
 
+		// Caller of the request and their role
+		api.GET("/auth/me", viewer, WhoAmIHandler)
+
 
 		// System status endpoint
-		api.GET("/status", func(c *gin.Context) {
+		api.GET("/status", viewer, func(c *gin.Context) {
 			SystemStatusHandler(c, jobManager)
 		})
 
 		// Model discovery endpoint
-		api.GET("/models", ModelsHandler)
+		api.GET("/models", viewer, ModelsHandler)
 
 		// Benchmark execution endpoints
-		api.POST("/benchmark", BenchmarkHandler)                    // Synchronous (legacy)
-		api.POST("/benchmark/async", simpleHandlers.StartBenchmark) // Asynchronous with SSE
+		api.POST("/benchmark", runner, BenchmarkHandler)                    // Synchronous (legacy)
+		api.POST("/benchmark/async", runner, simpleHandlers.StartBenchmark) // Asynchronous with SSE
+		api.POST("/benchmark/search", runner, simpleHandlers.StartSearch)   // Saturation search with SSE
+		api.POST("/benchmark/estimate", viewer, simpleHandlers.EstimateBenchmark)     // Requests, tokens and cost before starting
+		api.POST("/benchmark/search/estimate", viewer, simpleHandlers.EstimateSearch) // Upper bound of a search
 
 		// Task 15.3: Add specific endpoint for benchmark cancellation
-		api.POST("/benchmark/:jobId/cancel", func(c *gin.Context) {
+		api.POST("/benchmark/:jobId/cancel", runner, func(c *gin.Context) {
 			jobID := c.Param("jobId")
 			jobManager := GetJobManager()
+			if !simpleHandlers.authorizeJob(c, jobID) {
+				return
+			}
 			
 			AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Received cancellation request for job")
 			
@@ -69,23 +90,38 @@ func SetupRoutes(router *gin.Engine) {
 		})
 
 		// Job management endpoints
-		api.GET("/jobs/:jobId", simpleHandlers.GetJobStatus)
-		api.POST("/jobs/:jobId/cancel", simpleHandlers.CancelJob)
-		api.GET("/jobs", simpleHandlers.ListJobs)
+		api.GET("/jobs/:jobId", viewer, simpleHandlers.GetJobStatus)
+		api.POST("/jobs/:jobId/cancel", runner, simpleHandlers.CancelJob)
+		api.POST("/jobs/:jobId/resume", runner, simpleHandlers.ResumeJob)
+		api.POST("/jobs/:jobId/rerun", runner, simpleHandlers.RerunJob)
+		api.POST("/jobs/:jobId/pause", runner, simpleHandlers.PauseJob)
+		api.POST("/jobs/:jobId/continue", runner, simpleHandlers.ContinueJob)
+		api.POST("/jobs/:jobId/skip", runner, simpleHandlers.SkipLevel)
+		api.PUT("/jobs/:jobId/levels", runner, simpleHandlers.SetRemainingLevels)
+		api.GET("/jobs", viewer, simpleHandlers.ListJobs)
+		api.GET("/jobs/:jobId/samples", viewer, simpleHandlers.GetJobSamples)
+
+		// Recurring benchmarks, run by the leader instance
+		scheduleRunner := GetScheduleRunner()
+		api.GET("/schedules", viewer, scheduleRunner.ListSchedules)
+		api.POST("/schedules", runner, scheduleRunner.CreateSchedule)
+		api.GET("/schedules/:scheduleId", viewer, scheduleRunner.GetSchedule)
+		api.PUT("/schedules/:scheduleId", runner, scheduleRunner.UpdateSchedule)
+		api.DELETE("/schedules/:scheduleId", runner, scheduleRunner.DeleteSchedule)
 		
 		// SSE endpoint for real-time progress (outside validation middleware)
 		api.OPTIONS("/jobs/:jobId/stream", func(c *gin.Context) {
 			c.Header("Access-Control-Allow-Origin", "*")
 			c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
-			c.Header("Access-Control-Allow-Headers", "Cache-Control")
+			c.Header("Access-Control-Allow-Headers", "Authorization, Cache-Control, Last-Event-ID")
 			c.Status(200)
 		})
-		api.GET("/jobs/:jobId/stream", sseHandler.StreamJobProgress)
-		api.GET("/system-status/stream", sseHandler.StreamSystemStatus)
+		api.GET("/jobs/:jobId/stream", viewer, sseHandler.StreamJobProgress)
+		api.GET("/system-status/stream", viewer, sseHandler.StreamSystemStatus)
 
 		// Export endpoints
-		api.POST("/export/json", ExportJSONHandler)
-		api.POST("/export/csv", ExportCSVHandler)
+		api.POST("/export/json", viewer, ExportJSONHandler)
+		api.POST("/export/csv", viewer, ExportCSVHandler)
 	}
 
 	// Configure static file serving for Vue.js frontend
@@ -107,8 +143,10 @@ func SetupRoutes(router *gin.Engine) {
 				"status":  "ok",
 				"endpoints": gin.H{
 					"health":    "/api/health",
+					"metrics":   "/metrics",
 					"models":    "/api/models",
 					"benchmark": "/api/benchmark",
+					"schedules": "/api/schedules",
 					"export": gin.H{
 						"json": "/api/export/json",
 						"csv":  "/api/export/csv",
@@ -147,3 +185,14 @@ func SetupRoutes(router *gin.Engine) {
 	})
 }
 
+// mountWebSocket serves the WebSocket control channel on /ws behind handlers. The Executor
+// events of every job are broadcast to its clients as progress messages.
+func mountWebSocket(router gin.IRoutes, jobManager *SimpleJobManager, handlers ...gin.HandlerFunc) *Hub {
+	hub := NewHub()
+	go hub.Run()
+	jobManager.ObserveExecutions(NewHubObserver(hub))
+	router.GET("/ws", append(handlers, func(c *gin.Context) {
+		serveWebSocket(hub, jobManager, c)
+	})...)
+	return hub
+}
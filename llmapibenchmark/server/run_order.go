@@ -0,0 +1,149 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Supported execution schedules for two-model benchmarks
+const (
+	ScheduleSequential  = "sequential"  // A1 A2 A3 ... B1 B2 B3 (historical behaviour)
+	ScheduleInterleaved = "interleaved" // A1 B1 A2 B2 A3 B3
+	ScheduleRandom      = "random"      // every (model, level) pair in shuffled order
+	ScheduleParallel    = "parallel"    // A1+B1 together, then A2+B2, ... (separate backends only)
+)
+
+// BenchmarkStep is a single (model, concurrency level) measurement
+type BenchmarkStep struct {
+	ModelIndex  int   `json:"modelIndex"` // 1 or 2
+	Model       Model `json:"-"`
+	Concurrency int   `json:"concurrency"`
+	LevelIndex  int   `json:"-"` // Position of the level in the request's ConcurrencyLevels
+}
+
+// Label returns a compact identifier for the step, e.g. "model1@8"
+func (s BenchmarkStep) Label() string {
+	return fmt.Sprintf("model%d@%d", s.ModelIndex, s.Concurrency)
+}
+
+// ScheduleMetadata records how a benchmark was ordered so results can be audited
+type ScheduleMetadata struct {
+	Schedule       string     `json:"schedule"`
+	Seed           int64      `json:"seed,omitempty"`
+	ExecutionOrder [][]string `json:"executionOrder"` // Steps in each group ran simultaneously
+}
+
+// normalizeSchedule returns the effective schedule name for a request
+func normalizeSchedule(schedule string) string {
+	if schedule == "" {
+		return ScheduleSequential
+	}
+	return schedule
+}
+
+// validateSchedule checks that the requested schedule is supported
+func validateSchedule(schedule string) error {
+	switch normalizeSchedule(schedule) {
+	case ScheduleSequential, ScheduleInterleaved, ScheduleRandom, ScheduleParallel:
+		return nil
+	default:
+		return fmt.Errorf("schedule must be one of %s, %s, %s or %s, got %q",
+			ScheduleSequential, ScheduleInterleaved, ScheduleRandom, ScheduleParallel, schedule)
+	}
+}
+
+// validateRequestSchedule checks the schedule against the models in a request
+func validateRequestSchedule(req *BenchmarkRequest) error {
+	if err := validateSchedule(req.Schedule); err != nil {
+		return err
+	}
+	if normalizeSchedule(req.Schedule) == ScheduleParallel && req.Model2 != nil && req.Model1.BaseURL == req.Model2.BaseURL {
+		return fmt.Errorf("schedule %q requires models on separate backends (both use %s)", ScheduleParallel, req.Model1.BaseURL)
+	}
+	return nil
+}
+
+// planBenchmarkSteps orders the (model, level) pairs of a request according to its schedule.
+// Each returned group is executed as a unit: groups run one after another, and the steps
+// inside a group run simultaneously. Only the parallel schedule produces groups of two.
+func planBenchmarkSteps(request BenchmarkRequest, seed int64) [][]BenchmarkStep {
+	schedule := normalizeSchedule(request.Schedule)
+
+	models := []Model{request.Model1}
+	if request.Model2 != nil {
+		models = append(models, *request.Model2)
+	}
+
+	stepFor := func(modelIdx, levelIdx int) BenchmarkStep {
+		return BenchmarkStep{
+			ModelIndex:  modelIdx + 1,
+			Model:       models[modelIdx],
+			Concurrency: request.ConcurrencyLevels[levelIdx],
+			LevelIndex:  levelIdx,
+		}
+	}
+
+	var groups [][]BenchmarkStep
+	switch schedule {
+	case ScheduleInterleaved:
+		for levelIdx := range request.ConcurrencyLevels {
+			for modelIdx := range models {
+				groups = append(groups, []BenchmarkStep{stepFor(modelIdx, levelIdx)})
+			}
+		}
+	case ScheduleRandom:
+		for modelIdx := range models {
+			for levelIdx := range request.ConcurrencyLevels {
+				groups = append(groups, []BenchmarkStep{stepFor(modelIdx, levelIdx)})
+			}
+		}
+		rng := rand.New(rand.NewSource(seed))
+		rng.Shuffle(len(groups), func(i, j int) {
+			groups[i], groups[j] = groups[j], groups[i]
+		})
+	case ScheduleParallel:
+		for levelIdx := range request.ConcurrencyLevels {
+			group := make([]BenchmarkStep, 0, len(models))
+			for modelIdx := range models {
+				group = append(group, stepFor(modelIdx, levelIdx))
+			}
+			groups = append(groups, group)
+		}
+	default:
+		for modelIdx := range models {
+			for levelIdx := range request.ConcurrencyLevels {
+				groups = append(groups, []BenchmarkStep{stepFor(modelIdx, levelIdx)})
+			}
+		}
+	}
+
+	return groups
+}
+
+// newScheduleMetadata describes the planned execution order for the job result
+func newScheduleMetadata(request BenchmarkRequest, seed int64, groups [][]BenchmarkStep) ScheduleMetadata {
+	metadata := ScheduleMetadata{
+		Schedule:       normalizeSchedule(request.Schedule),
+		ExecutionOrder: make([][]string, 0, len(groups)),
+	}
+	if metadata.Schedule == ScheduleRandom {
+		metadata.Seed = seed
+	}
+	for _, group := range groups {
+		labels := make([]string, 0, len(group))
+		for _, step := range group {
+			labels = append(labels, step.Label())
+		}
+		metadata.ExecutionOrder = append(metadata.ExecutionOrder, labels)
+	}
+	return metadata
+}
+
+// scheduleSeed returns the seed to use for a randomized schedule
+func scheduleSeed(request BenchmarkRequest) int64 {
+	if request.ScheduleSeed != 0 {
+		return request.ScheduleSeed
+	}
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func scheduleTestRequest(schedule string) BenchmarkRequest {
+	return BenchmarkRequest{
+		Model1:            Model{ID: "a", Name: "model-a", BaseURL: "https://a.example.com/v1"},
+		Model2:            &Model{ID: "b", Name: "model-b", BaseURL: "https://b.example.com/v1"},
+		ConcurrencyLevels: []int{1, 2, 4},
+		MaxTokens:         16,
+		Prompt:            "hello",
+		Schedule:          schedule,
+	}
+}
+
+func TestPlanBenchmarkSteps_Orders(t *testing.T) {
+	testCases := []struct {
+		schedule string
+		expected [][]string
+	}{
+		{"", [][]string{{"model1@1"}, {"model1@2"}, {"model1@4"}, {"model2@1"}, {"model2@2"}, {"model2@4"}}},
+		{ScheduleSequential, [][]string{{"model1@1"}, {"model1@2"}, {"model1@4"}, {"model2@1"}, {"model2@2"}, {"model2@4"}}},
+		{ScheduleInterleaved, [][]string{{"model1@1"}, {"model2@1"}, {"model1@2"}, {"model2@2"}, {"model1@4"}, {"model2@4"}}},
+		{ScheduleParallel, [][]string{{"model1@1", "model2@1"}, {"model1@2", "model2@2"}, {"model1@4", "model2@4"}}},
+	}
+
+	for _, tc := range testCases {
+		request := scheduleTestRequest(tc.schedule)
+		metadata := newScheduleMetadata(request, 1, planBenchmarkSteps(request, 1))
+		if !reflect.DeepEqual(metadata.ExecutionOrder, tc.expected) {
+			t.Errorf("schedule %q: expected order %v, got %v", tc.schedule, tc.expected, metadata.ExecutionOrder)
+		}
+	}
+}
+
+func TestPlanBenchmarkSteps_RandomIsSeededPermutation(t *testing.T) {
+	request := scheduleTestRequest(ScheduleRandom)
+
+	first := newScheduleMetadata(request, 42, planBenchmarkSteps(request, 42))
+	second := newScheduleMetadata(request, 42, planBenchmarkSteps(request, 42))
+	if !reflect.DeepEqual(first.ExecutionOrder, second.ExecutionOrder) {
+		t.Errorf("Expected identical order for identical seed, got %v and %v", first.ExecutionOrder, second.ExecutionOrder)
+	}
+	if first.Seed != 42 {
+		t.Errorf("Expected seed 42 to be recorded, got %d", first.Seed)
+	}
+
+	seen := make(map[string]bool)
+	for _, group := range first.ExecutionOrder {
+		if len(group) != 1 {
+			t.Fatalf("Expected single-step groups for random schedule, got %v", group)
+		}
+		seen[group[0]] = true
+	}
+	if len(seen) != 6 {
+		t.Errorf("Expected all 6 steps exactly once, got %v", first.ExecutionOrder)
+	}
+}
+
+func TestPlanBenchmarkSteps_SingleModel(t *testing.T) {
+	request := scheduleTestRequest(ScheduleParallel)
+	request.Model2 = nil
+
+	groups := planBenchmarkSteps(request, 1)
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d", len(groups))
+	}
+	for i, group := range groups {
+		if len(group) != 1 || group[0].ModelIndex != 1 || group[0].LevelIndex != i {
+			t.Errorf("Unexpected group %d: %+v", i, group)
+		}
+	}
+}
+
+func TestValidateRequestSchedule(t *testing.T) {
+	request := scheduleTestRequest("round-robin")
+	if err := validateRequestSchedule(&request); err == nil {
+		t.Error("Expected error for unknown schedule")
+	}
+
+	request = scheduleTestRequest(ScheduleParallel)
+	if err := validateRequestSchedule(&request); err != nil {
+		t.Errorf("Expected parallel schedule on separate backends to be valid, got: %v", err)
+	}
+
+	request.Model2.BaseURL = request.Model1.BaseURL
+	if err := validateRequestSchedule(&request); err == nil {
+		t.Error("Expected error for parallel schedule on a shared backend")
+	}
+}
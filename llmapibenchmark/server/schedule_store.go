@@ -0,0 +1,302 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrScheduleNotFound is returned by a ScheduleStore for unknown schedule IDs
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ScheduleStore persists recurring benchmark definitions and elects the instance that
+// runs them. Implementations must be safe for concurrent use.
+type ScheduleStore interface {
+	ListSchedules() ([]*BenchmarkSchedule, error)
+	GetSchedule(id string) (*BenchmarkSchedule, error)
+	CreateSchedule(schedule *BenchmarkSchedule) error
+	// UpdateSchedule applies update to the stored schedule atomically and returns the result
+	UpdateSchedule(id string, update func(schedule *BenchmarkSchedule) error) (*BenchmarkSchedule, error)
+	DeleteSchedule(id string) error
+	// AcquireLeadership takes or renews the leader lease for ttl and reports whether
+	// holder is the leader
+	AcquireLeadership(holder string, ttl time.Duration) (bool, error)
+}
+
+// newScheduleStoreFromEnv keeps schedules in memory, or in SCHEDULE_STORE_DIR when set.
+// Instances sharing that directory (e.g. a CF volume service) elect one leader.
+func newScheduleStoreFromEnv() (ScheduleStore, error) {
+	if directory := os.Getenv("SCHEDULE_STORE_DIR"); directory != "" {
+		return NewFileScheduleStore(directory)
+	}
+	return NewMemoryScheduleStore(), nil
+}
+
+// cloneSchedule deep-copies a schedule through JSON, so stored schedules are never shared
+func cloneSchedule(schedule *BenchmarkSchedule) *BenchmarkSchedule {
+	data, _ := json.Marshal(schedule)
+	var clone BenchmarkSchedule
+	json.Unmarshal(data, &clone)
+	return &clone
+}
+
+func sortSchedules(schedules []*BenchmarkSchedule) {
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].CreatedAt.Before(schedules[j].CreatedAt) })
+}
+
+// MemoryScheduleStore keeps schedules for the lifetime of the process. It suits a single
+// instance, which is always the leader.
+type MemoryScheduleStore struct {
+	mutex     sync.Mutex
+	schedules map[string]*BenchmarkSchedule
+}
+
+// NewMemoryScheduleStore creates an empty in-memory store
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{schedules: make(map[string]*BenchmarkSchedule)}
+}
+
+func (store *MemoryScheduleStore) ListSchedules() ([]*BenchmarkSchedule, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	schedules := make([]*BenchmarkSchedule, 0, len(store.schedules))
+	for _, schedule := range store.schedules {
+		schedules = append(schedules, cloneSchedule(schedule))
+	}
+	sortSchedules(schedules)
+	return schedules, nil
+}
+
+func (store *MemoryScheduleStore) GetSchedule(id string) (*BenchmarkSchedule, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	schedule, exists := store.schedules[id]
+	if !exists {
+		return nil, ErrScheduleNotFound
+	}
+	return cloneSchedule(schedule), nil
+}
+
+func (store *MemoryScheduleStore) CreateSchedule(schedule *BenchmarkSchedule) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, exists := store.schedules[schedule.ID]; exists {
+		return fmt.Errorf("schedule %s already exists", schedule.ID)
+	}
+	store.schedules[schedule.ID] = cloneSchedule(schedule)
+	return nil
+}
+
+func (store *MemoryScheduleStore) UpdateSchedule(id string, update func(*BenchmarkSchedule) error) (*BenchmarkSchedule, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.schedules[id]
+	if !exists {
+		return nil, ErrScheduleNotFound
+	}
+	schedule := cloneSchedule(stored)
+	if err := update(schedule); err != nil {
+		return nil, err
+	}
+	store.schedules[id] = cloneSchedule(schedule)
+	return schedule, nil
+}
+
+func (store *MemoryScheduleStore) DeleteSchedule(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, exists := store.schedules[id]; !exists {
+		return ErrScheduleNotFound
+	}
+	delete(store.schedules, id)
+	return nil
+}
+
+func (store *MemoryScheduleStore) AcquireLeadership(string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+// FileScheduleStore keeps one JSON file per schedule in a directory. Writes and the
+// leader lease are serialized with a lock directory, which works on shared volumes.
+type FileScheduleStore struct {
+	directory string
+	mutex     sync.Mutex // Serializes this process; the lock directory serializes instances
+}
+
+// Locks older than this are assumed to belong to a crashed instance
+const staleStoreLockAge = 30 * time.Second
+
+// scheduleLease is the content of the leader lease file
+type scheduleLease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// NewFileScheduleStore creates a store in directory, creating it if needed
+func NewFileScheduleStore(directory string) (*FileScheduleStore, error) {
+	if err := os.MkdirAll(filepath.Join(directory, "schedules"), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create schedule store: %w", err)
+	}
+	return &FileScheduleStore{directory: directory}, nil
+}
+
+func (store *FileScheduleStore) schedulePath(id string) string {
+	return filepath.Join(store.directory, "schedules", id+".json")
+}
+
+// locked runs fn while holding the store lock
+func (store *FileScheduleStore) locked(fn func() error) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	lockPath := filepath.Join(store.directory, ".lock")
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		err := os.Mkdir(lockPath, 0o700)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleStoreLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the schedule store lock")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	defer os.Remove(lockPath)
+	return fn()
+}
+
+func (store *FileScheduleStore) read(path string, value interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// write replaces a file atomically, so readers never see a partial schedule
+func (store *FileScheduleStore) write(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(data); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), path)
+}
+
+func (store *FileScheduleStore) readSchedule(id string) (*BenchmarkSchedule, error) {
+	var schedule BenchmarkSchedule
+	if err := store.read(store.schedulePath(id), &schedule); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (store *FileScheduleStore) ListSchedules() ([]*BenchmarkSchedule, error) {
+	entries, err := os.ReadDir(filepath.Join(store.directory, "schedules"))
+	if err != nil {
+		return nil, err
+	}
+	var schedules []*BenchmarkSchedule
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		schedule, err := store.readSchedule(id)
+		if errors.Is(err, ErrScheduleNotFound) {
+			continue // Deleted meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	sortSchedules(schedules)
+	return schedules, nil
+}
+
+func (store *FileScheduleStore) GetSchedule(id string) (*BenchmarkSchedule, error) {
+	return store.readSchedule(id)
+}
+
+func (store *FileScheduleStore) CreateSchedule(schedule *BenchmarkSchedule) error {
+	return store.locked(func() error {
+		if _, err := os.Stat(store.schedulePath(schedule.ID)); err == nil {
+			return fmt.Errorf("schedule %s already exists", schedule.ID)
+		}
+		return store.write(store.schedulePath(schedule.ID), schedule)
+	})
+}
+
+func (store *FileScheduleStore) UpdateSchedule(id string, update func(*BenchmarkSchedule) error) (*BenchmarkSchedule, error) {
+	var schedule *BenchmarkSchedule
+	err := store.locked(func() error {
+		var err error
+		if schedule, err = store.readSchedule(id); err != nil {
+			return err
+		}
+		if err := update(schedule); err != nil {
+			return err
+		}
+		return store.write(store.schedulePath(id), schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (store *FileScheduleStore) DeleteSchedule(id string) error {
+	return store.locked(func() error {
+		err := os.Remove(store.schedulePath(id))
+		if os.IsNotExist(err) {
+			return ErrScheduleNotFound
+		}
+		return err
+	})
+}
+
+func (store *FileScheduleStore) AcquireLeadership(holder string, ttl time.Duration) (bool, error) {
+	leader := false
+	err := store.locked(func() error {
+		path := filepath.Join(store.directory, "leader.json")
+		var lease scheduleLease
+		if err := store.read(path, &lease); err != nil && !os.IsNotExist(err) {
+			AppLogger.Warn("Replacing unreadable schedule leader lease: %v", err)
+		}
+		now := time.Now()
+		if lease.Holder != holder && lease.Holder != "" && now.Before(lease.ExpiresAt) {
+			return nil // Another instance holds a valid lease
+		}
+		leader = true
+		return store.write(path, scheduleLease{Holder: holder, ExpiresAt: now.Add(ttl)})
+	})
+	return leader && err == nil, err
+}
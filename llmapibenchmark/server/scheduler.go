@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrQueueFull is returned by Enqueue when MAX_QUEUED_JOBS jobs are already waiting
+var ErrQueueFull = errors.New("job queue is full")
+
+// Job priorities accepted in requests; higher priorities start first
+const (
+	minJobPriority = -100
+	maxJobPriority = 100
+)
+
+// schedulerLimits bounds how many jobs run at once. Zero disables a limit.
+//
+//	MAX_CONCURRENT_JOBS   jobs running at the same time (default 2)
+//	MAX_JOBS_PER_BACKEND  jobs running against the same model endpoint (default 1)
+//	MAX_QUEUED_JOBS       jobs waiting to start (default 50)
+type schedulerLimits struct {
+	MaxRunning    int `json:"maxConcurrentJobs"`
+	MaxPerBackend int `json:"maxJobsPerBackend"`
+	MaxQueued     int `json:"maxQueuedJobs"`
+}
+
+// schedulerLimitsFromEnv reads the limits, keeping the default of invalid values
+func schedulerLimitsFromEnv() schedulerLimits {
+	limits := schedulerLimits{MaxRunning: 2, MaxPerBackend: 1, MaxQueued: 50}
+	for name, limit := range map[string]*int{
+		"MAX_CONCURRENT_JOBS":  &limits.MaxRunning,
+		"MAX_JOBS_PER_BACKEND": &limits.MaxPerBackend,
+		"MAX_QUEUED_JOBS":      &limits.MaxQueued,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			AppLogger.Warn("Ignoring invalid %s=%q, using %d", name, value, *limit)
+			continue
+		}
+		*limit = parsed
+	}
+	return limits
+}
+
+// validatePriority checks the priority of a request
+func validatePriority(priority int) error {
+	if priority < minJobPriority || priority > maxJobPriority {
+		return fmt.Errorf("priority must be between %d and %d, got %d", minJobPriority, maxJobPriority, priority)
+	}
+	return nil
+}
+
+// jobBackends returns the model endpoints a request benchmarks, without duplicates
+func jobBackends(request BenchmarkRequest) []string {
+	models := []Model{request.Model1}
+	if request.Model2 != nil {
+		models = append(models, *request.Model2)
+	}
+	var backends []string
+	for _, model := range models {
+		backend := backendKey(model.BaseURL)
+		if backend == "" {
+			continue
+		}
+		duplicate := false
+		for _, existing := range backends {
+			duplicate = duplicate || existing == backend
+		}
+		if !duplicate {
+			backends = append(backends, backend)
+		}
+	}
+	return backends
+}
+
+// backendKey identifies a model endpoint by host and path, so that different models
+// behind the same GenAI service share a limit
+func backendKey(baseURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || parsed.Host == "" {
+		return strings.TrimSuffix(strings.TrimSpace(baseURL), "/")
+	}
+	return strings.ToLower(parsed.Host) + strings.TrimSuffix(parsed.Path, "/")
+}
+
+// Enqueue queues a created job. run is started in its own goroutine once the limits
+// allow it; jobs start by priority, then in submission order.
+func (jm *SimpleJobManager) Enqueue(jobID string, run func()) error {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	job, exists := jm.jobs[jobID]
+	if !exists {
+		return fmt.Errorf("job %s not found", jobID)
+	}
+	if job.Status != "queued" || job.run != nil {
+		return fmt.Errorf("job %s is %s and cannot be queued", jobID, job.Status)
+	}
+	if jm.limits.MaxQueued > 0 && len(jm.queue) >= jm.limits.MaxQueued {
+		job.finishSamples()
+		delete(jm.jobs, jobID)
+		AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Rejected job, %d jobs already queued", len(jm.queue))
+		return ErrQueueFull
+	}
+	if job.Estimate != nil {
+		if err := jm.checkQuotaLocked(job.Owner, *job.Estimate, job); err != nil {
+			job.finishSamples()
+			delete(jm.jobs, jobID)
+			AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Rejected job of %q: %v", job.Owner, err)
+			return err
+		}
+	}
+
+	job.run = run
+	jm.nextQueueSeq++
+	job.queueSeq = jm.nextQueueSeq
+	index := sort.Search(len(jm.queue), func(i int) bool { return queuedBefore(job, jm.queue[i]) })
+	jm.queue = append(jm.queue, nil)
+	copy(jm.queue[index+1:], jm.queue[index:])
+	jm.queue[index] = job
+
+	AppLogger.InfoWithFields("Job queued", map[string]interface{}{
+		"jobId":    jobID,
+		"priority": job.Priority,
+		"position": index + 1,
+	})
+	jm.dispatchLocked()
+	go jm.broadcastSystemStatus()
+	return nil
+}
+
+// queuedBefore orders the queue by priority, then by submission
+func queuedBefore(a *SimpleJob, b *SimpleJob) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.queueSeq < b.queueSeq
+}
+
+// dispatchLocked starts every queued job the limits allow and refreshes the queue
+// positions. A job whose backend is busy does not hold back jobs for other backends.
+// It must be called with jm.mutex held.
+func (jm *SimpleJobManager) dispatchLocked() {
+	waiting := jm.queue[:0]
+	for _, job := range jm.queue {
+		if jm.canStartLocked(job) {
+			jm.startLocked(job)
+		} else {
+			waiting = append(waiting, job)
+		}
+	}
+	for i := len(waiting); i < len(jm.queue); i++ {
+		jm.queue[i] = nil
+	}
+	jm.queue = waiting
+
+	for i, job := range jm.queue {
+		if job.QueuePosition != i+1 {
+			job.QueuePosition = i + 1
+			job.Message = fmt.Sprintf("Queued (position %d)", job.QueuePosition)
+			jm.broadcastUpdate(job.ID, job)
+		}
+	}
+}
+
+func (jm *SimpleJobManager) canStartLocked(job *SimpleJob) bool {
+	if jm.limits.MaxRunning > 0 && jm.activeJobCount >= jm.limits.MaxRunning {
+		return false
+	}
+	if jm.limits.MaxPerBackend > 0 {
+		for _, backend := range job.backends {
+			if jm.runningByBackend[backend] >= jm.limits.MaxPerBackend {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// startLocked moves a queued job to running and takes its slots
+func (jm *SimpleJobManager) startLocked(job *SimpleJob) {
+	now := time.Now()
+	job.Status = "running"
+	job.StartedAt = &now
+	job.QueuePosition = 0
+	job.Message = "Starting..."
+	job.holdsSlot = true
+	job.ctx, job.cancelFunc = context.WithCancel(context.Background()) // Cancellable before run sets anything up
+	jm.activeJobCount++
+	for _, backend := range job.backends {
+		jm.runningByBackend[backend]++
+	}
+	AppLogger.InfoWithFields("Job started", map[string]interface{}{
+		"jobId":      job.ID,
+		"queuedFor":  now.Sub(job.CreatedAt).Round(time.Millisecond).String(),
+		"activeJobs": jm.activeJobCount,
+		"backends":   job.backends,
+	})
+	jm.broadcastUpdate(job.ID, job)
+	go job.run()
+}
+
+// releaseLocked takes an ended job out of the queue or gives back its slots, then starts
+// the jobs that can run now. It is safe to call more than once for the same job.
+func (jm *SimpleJobManager) releaseLocked(job *SimpleJob) {
+	for i, queued := range jm.queue {
+		if queued == job {
+			jm.queue = append(jm.queue[:i], jm.queue[i+1:]...)
+			break
+		}
+	}
+	job.QueuePosition = 0
+	if job.holdsSlot {
+		job.holdsSlot = false
+		if jm.activeJobCount > 0 {
+			jm.activeJobCount--
+		}
+		for _, backend := range job.backends {
+			if jm.runningByBackend[backend]--; jm.runningByBackend[backend] <= 0 {
+				delete(jm.runningByBackend, backend)
+			}
+		}
+	}
+	jm.dispatchLocked()
+}
+
+// jobContext returns the context created when the job left the queue. Jobs run without
+// Enqueue get a new cancellable context.
+func (jm *SimpleJobManager) jobContext(jobID string) context.Context {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	job, exists := jm.jobs[jobID]
+	if !exists {
+		return context.Background()
+	}
+	if job.ctx == nil {
+		job.ctx, job.cancelFunc = context.WithCancel(context.Background())
+	}
+	return job.ctx
+}
+
+// queueSnapshotLocked lists the queued jobs in start order
+func (jm *SimpleJobManager) queueSnapshotLocked() []map[string]interface{} {
+	queue := make([]map[string]interface{}, len(jm.queue))
+	for i, job := range jm.queue {
+		queue[i] = map[string]interface{}{
+			"jobId":    job.ID,
+			"type":     job.Type,
+			"position": i + 1,
+			"priority": job.Priority,
+			"queuedAt": job.CreatedAt,
+		}
+	}
+	return queue
+}
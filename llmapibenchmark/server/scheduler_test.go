@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerLimitsPrioritiesAndQueueCancellation(t *testing.T) {
+	jm := NewSimpleJobManager()
+	jm.limits = schedulerLimits{MaxRunning: 2, MaxPerBackend: 1, MaxQueued: 2}
+	started := make(chan string, 10)
+
+	submit := func(baseURL string, priority int) (string, error) {
+		jobID := jm.CreateJob(BenchmarkRequest{Model1: Model{Name: "m", BaseURL: baseURL}, Priority: priority})
+		return jobID, jm.Enqueue(jobID, func() { started <- jobID })
+	}
+	expectStarted := func(jobID string) {
+		t.Helper()
+		select {
+		case id := <-started:
+			if id != jobID {
+				t.Fatalf("expected %s to start, got %s", jobID, id)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s to start", jobID)
+		}
+	}
+	expectState := func(jobID string, status string, position int) {
+		t.Helper()
+		state, _ := jm.GetJobState(jobID)
+		if state.Status != status || state.QueuePosition != position {
+			t.Errorf("expected %s at position %d, got %s at %d", status, position, state.Status, state.QueuePosition)
+		}
+	}
+
+	first, _ := submit("https://genai.example.com/a/", 0)
+	expectStarted(first)
+	sameBackend, _ := submit("https://GENAI.example.com/a", 0)
+	otherBackend, _ := submit("https://genai.example.com/b", 0)
+	expectStarted(otherBackend) // Not held back by the job waiting for backend a
+	urgent, _ := submit("https://genai.example.com/a", 10)
+
+	expectState(first, "running", 0)
+	expectState(urgent, "queued", 1)
+	expectState(sameBackend, "queued", 2)
+	if _, err := submit("https://genai.example.com/c", 0); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if count := len(jm.ListJobs()); count != 4 {
+		t.Errorf("expected the rejected job to be dropped, got %d jobs", count)
+	}
+
+	if !jm.CancelJob(sameBackend) {
+		t.Fatal("expected a queued job to be cancellable")
+	}
+	expectState(sameBackend, "cancelled", 0)
+
+	jm.CompleteJob(first, nil)
+	expectStarted(urgent)
+	expectState(urgent, "running", 0)
+	if status := jm.GetSystemStatus(); status["activeJobs"] != 2 || status["queuedJobs"] != 0 {
+		t.Errorf("unexpected system status %v", status)
+	}
+	select {
+	case id := <-started:
+		t.Errorf("cancelled or extra job %s started", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
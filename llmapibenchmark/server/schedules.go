@@ -0,0 +1,426 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"llmapibenchmark/internal/cron"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BenchmarkSchedule is a benchmark or search job that runs on a cron schedule
+type BenchmarkSchedule struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Cron      string            `json:"cron"`               // Five fields or a macro such as "@daily"
+	Timezone  string            `json:"timezone,omitempty"` // IANA name, default UTC
+	Enabled   bool              `json:"enabled"`
+	Type      string            `json:"type"` // "benchmark" or "search"
+	Benchmark *BenchmarkRequest `json:"benchmark,omitempty"`
+	Search    *SearchRequest    `json:"search,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+	NextRunAt *time.Time        `json:"nextRunAt,omitempty"` // Unset while disabled
+	LastRun   *ScheduleRun      `json:"lastRun,omitempty"`
+	History   []ScheduleRun     `json:"history"`         // Oldest first, at most maxScheduleHistory runs
+	Owner     string            `json:"owner,omitempty"` // Subject that created the schedule; owns its jobs
+}
+
+// ScheduleRun is one job started by a schedule
+type ScheduleRun struct {
+	JobID       string     `json:"jobId,omitempty"` // Unset when the job could not be queued
+	ScheduledAt time.Time  `json:"scheduledAt"`
+	StartedAt   time.Time  `json:"startedAt"` // When the job was submitted
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	Status      string     `json:"status"` // Job status, or "unknown" once the job is no longer tracked
+	Error       string     `json:"error,omitempty"`
+	Instance    string     `json:"instance"` // App instance that ran the job
+}
+
+// ScheduleRequest is the payload of POST /api/schedules and PUT /api/schedules/:id.
+// Exactly one of benchmark and search is required.
+type ScheduleRequest struct {
+	Name      string            `json:"name" binding:"required"`
+	Cron      string            `json:"cron" binding:"required"`
+	Timezone  string            `json:"timezone,omitempty"`
+	Enabled   *bool             `json:"enabled,omitempty"` // Default true
+	Benchmark *BenchmarkRequest `json:"benchmark,omitempty"`
+	Search    *SearchRequest    `json:"search,omitempty"`
+}
+
+const (
+	maxScheduleHistory   = 50
+	scheduleTickInterval = 15 * time.Second
+	scheduleLeaseTTL     = 3 * scheduleTickInterval // Survives two missed renewals
+)
+
+// ScheduleRunner starts the jobs of due schedules. Every instance serves the schedule API,
+// only the leader starts jobs.
+type ScheduleRunner struct {
+	store      ScheduleStore
+	jobManager *SimpleJobManager
+	instanceID string
+	leader     atomic.Bool
+}
+
+// NewScheduleRunner creates a runner for the schedules in store
+func NewScheduleRunner(store ScheduleStore, jobManager *SimpleJobManager) *ScheduleRunner {
+	instanceID := os.Getenv("CF_INSTANCE_GUID")
+	if instanceID == "" {
+		hostname, _ := os.Hostname()
+		instanceID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return &ScheduleRunner{store: store, jobManager: jobManager, instanceID: instanceID}
+}
+
+// GetScheduleRunner returns the runner for the store configured in the environment.
+// An unusable SCHEDULE_STORE_DIR is logged and falls back to memory.
+var GetScheduleRunner = sync.OnceValue(func() *ScheduleRunner {
+	store, err := newScheduleStoreFromEnv()
+	if err != nil {
+		AppLogger.Error("Keeping schedules in memory: %v", err)
+		store = NewMemoryScheduleStore()
+	}
+	return NewScheduleRunner(store, GetJobManager())
+})
+
+// Start checks the schedules every scheduleTickInterval until stop is called
+func (runner *ScheduleRunner) Start() (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(scheduleTickInterval)
+		defer ticker.Stop()
+		for {
+			runner.tick(time.Now())
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// IsLeader reports whether this instance started jobs at the last check
+func (runner *ScheduleRunner) IsLeader() bool {
+	return runner.leader.Load()
+}
+
+// tick renews the leader lease and, as leader, starts due jobs and records job outcomes
+func (runner *ScheduleRunner) tick(now time.Time) {
+	leader, err := runner.store.AcquireLeadership(runner.instanceID, scheduleLeaseTTL)
+	if err != nil {
+		AppLogger.Warn("Failed to renew the schedule leader lease: %v", err)
+	}
+	if runner.leader.Swap(leader) != leader {
+		AppLogger.Info("Schedule leadership changed, leader=%t (instance %s)", leader, runner.instanceID)
+	}
+	if !leader {
+		return
+	}
+
+	schedules, err := runner.store.ListSchedules()
+	if err != nil {
+		AppLogger.Error("Failed to list schedules: %v", err)
+		return
+	}
+	for _, schedule := range schedules {
+		if !scheduleDue(schedule, now) && !hasPendingRuns(schedule) {
+			continue
+		}
+		_, err := runner.store.UpdateSchedule(schedule.ID, func(schedule *BenchmarkSchedule) error {
+			runner.refreshRuns(schedule)
+			if scheduleDue(schedule, now) {
+				runner.recordRun(schedule, runner.startRun(schedule, *schedule.NextRunAt, now))
+				schedule.NextRunAt = nextScheduleRun(schedule, now)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, ErrScheduleNotFound) {
+			AppLogger.Error("Failed to update schedule %s: %v", schedule.ID, err)
+		}
+	}
+}
+
+func scheduleDue(schedule *BenchmarkSchedule, now time.Time) bool {
+	return schedule.Enabled && schedule.NextRunAt != nil && !schedule.NextRunAt.After(now)
+}
+
+func hasPendingRuns(schedule *BenchmarkSchedule) bool {
+	for _, run := range schedule.History {
+		if run.Status == "queued" || run.Status == "running" {
+			return true
+		}
+	}
+	return false
+}
+
+// startRun submits the schedule's job to the job manager. A run missed while no instance
+// was leader is started once, late.
+func (runner *ScheduleRunner) startRun(schedule *BenchmarkSchedule, scheduledAt time.Time, now time.Time) ScheduleRun {
+	run := ScheduleRun{ScheduledAt: scheduledAt, StartedAt: now, Instance: runner.instanceID}
+	jm := runner.jobManager
+
+	var jobID string
+	var runJob func()
+	if schedule.Type == JobTypeSearch {
+		request := *schedule.Search
+		jobID = jm.CreateSearchJob(request)
+		runJob = func() { jm.RunSearch(jobID, request) }
+	} else {
+		request := *schedule.Benchmark
+		jobID = jm.CreateJob(request)
+		runJob = func() { jm.RunBenchmark(jobID, request) }
+	}
+	jm.setJobSchedule(jobID, schedule.ID)
+	jm.setJobOwner(jobID, schedule.Owner)
+
+	if err := jm.Enqueue(jobID, runJob); err != nil {
+		AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Failed to queue job of schedule %s: %v", schedule.ID, err)
+		run.Status = "failed"
+		run.Error = err.Error()
+		return run
+	}
+	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Started job of schedule %q (%s)", schedule.Name, schedule.ID)
+	run.JobID = jobID
+	run.Status = "queued"
+	return run
+}
+
+func (runner *ScheduleRunner) recordRun(schedule *BenchmarkSchedule, run ScheduleRun) {
+	schedule.History = append(schedule.History, run)
+	if len(schedule.History) > maxScheduleHistory {
+		schedule.History = schedule.History[len(schedule.History)-maxScheduleHistory:]
+	}
+	lastRun := schedule.History[len(schedule.History)-1]
+	schedule.LastRun = &lastRun
+}
+
+// refreshRuns copies the status of unfinished runs from the job manager. Runs whose job
+// is not tracked here, e.g. after a restart or a change of leader, become "unknown".
+func (runner *ScheduleRunner) refreshRuns(schedule *BenchmarkSchedule) {
+	for i := range schedule.History {
+		run := &schedule.History[i]
+		if run.Status != "queued" && run.Status != "running" {
+			continue
+		}
+		status, jobError, completedAt, exists := runner.jobManager.jobOutcome(run.JobID)
+		switch {
+		case exists:
+			run.Status, run.Error, run.CompletedAt = status, jobError, completedAt
+		default:
+			run.Status = "unknown"
+			run.Error = "job is no longer tracked by the server"
+		}
+	}
+	if len(schedule.History) > 0 {
+		lastRun := schedule.History[len(schedule.History)-1]
+		schedule.LastRun = &lastRun
+	}
+}
+
+// nextScheduleRun returns the first run after now, or nil for disabled schedules
+func nextScheduleRun(schedule *BenchmarkSchedule, now time.Time) *time.Time {
+	if !schedule.Enabled {
+		return nil
+	}
+	parsed, err := parseScheduleCron(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		return nil
+	}
+	next := parsed.Next(now)
+	if next.IsZero() {
+		return nil
+	}
+	next = next.UTC()
+	return &next
+}
+
+func parseScheduleCron(expression string, timezone string) (*cron.Schedule, error) {
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", timezone)
+		}
+	}
+	schedule, err := cron.Parse(expression, location)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never runs", expression)
+	}
+	return schedule, nil
+}
+
+// apply validates a schedule request and copies it onto schedule
+func (request *ScheduleRequest) apply(schedule *BenchmarkSchedule, now time.Time) error {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return errors.New("name is required")
+	}
+	if _, err := parseScheduleCron(request.Cron, request.Timezone); err != nil {
+		return err
+	}
+	switch {
+	case (request.Benchmark == nil) == (request.Search == nil):
+		return errors.New("exactly one of benchmark and search is required")
+	case request.Benchmark != nil:
+		if err := validateAsyncBenchmarkRequest(request.Benchmark); err != nil {
+			return fmt.Errorf("benchmark: %w", err)
+		}
+		schedule.Type = JobTypeBenchmark
+	default:
+		if err := validateSearchRequest(request.Search); err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		schedule.Type = JobTypeSearch
+	}
+
+	schedule.Name = request.Name
+	schedule.Cron = request.Cron
+	schedule.Timezone = request.Timezone
+	schedule.Enabled = request.Enabled == nil || *request.Enabled
+	schedule.Benchmark = request.Benchmark
+	schedule.Search = request.Search
+	schedule.UpdatedAt = now
+	schedule.NextRunAt = nextScheduleRun(schedule, now)
+	return nil
+}
+
+// setJobSchedule links a job to the schedule that started it
+func (jm *SimpleJobManager) setJobSchedule(jobID string, scheduleID string) {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+	if job, exists := jm.jobs[jobID]; exists {
+		job.ScheduleID = scheduleID
+	}
+}
+
+// jobOutcome returns the status of a job for schedule histories
+func (jm *SimpleJobManager) jobOutcome(jobID string) (status string, jobError string, completedAt *time.Time, exists bool) {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	job, exists := jm.jobs[jobID]
+	if !exists {
+		return "", "", nil, false
+	}
+	return job.Status, job.Error, job.CompletedAt, true
+}
+
+// respondScheduleError maps store errors to HTTP responses
+func respondScheduleError(c *gin.Context, err error) {
+	if errors.Is(err, ErrScheduleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	AppLogger.Error("Schedule store error: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// ListSchedules returns all schedules with their next run and history
+func (runner *ScheduleRunner) ListSchedules(c *gin.Context) {
+	schedules, err := runner.store.ListSchedules()
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"count":     len(schedules),
+		"leader":    runner.IsLeader(),
+	})
+}
+
+// GetSchedule returns one schedule
+func (runner *ScheduleRunner) GetSchedule(c *gin.Context) {
+	schedule, err := runner.store.GetSchedule(c.Param("scheduleId"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// CreateSchedule stores a new schedule
+func (runner *ScheduleRunner) CreateSchedule(c *gin.Context) {
+	var request ScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	now := time.Now().UTC()
+	schedule := &BenchmarkSchedule{ID: uuid.New().String(), CreatedAt: now, History: []ScheduleRun{}, Owner: principalFrom(c).Subject}
+	if err := request.apply(schedule, now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := runner.store.CreateSchedule(schedule); err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	AppLogger.Info("Created schedule %q (%s) with cron %q", schedule.Name, schedule.ID, schedule.Cron)
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// UpdateSchedule replaces the definition of a schedule and keeps its history
+func (runner *ScheduleRunner) UpdateSchedule(c *gin.Context) {
+	var request ScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	var validationErr error
+	schedule, err := runner.store.UpdateSchedule(c.Param("scheduleId"), func(schedule *BenchmarkSchedule) error {
+		if err := authorizeOwner(principalFrom(c), schedule.Owner); err != nil {
+			return err
+		}
+		validationErr = request.apply(schedule, time.Now().UTC())
+		return validationErr
+	})
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		return
+	}
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteSchedule removes a schedule; jobs it already started keep running
+func (runner *ScheduleRunner) DeleteSchedule(c *gin.Context) {
+	schedule, err := runner.store.GetSchedule(c.Param("scheduleId"))
+	if err == nil {
+		err = authorizeOwner(principalFrom(c), schedule.Owner)
+	}
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	if err := runner.store.DeleteSchedule(c.Param("scheduleId")); err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted", "scheduleId": c.Param("scheduleId")})
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newScheduleRouter(runner *ScheduleRunner) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/schedules", runner.ListSchedules)
+	router.POST("/api/schedules", runner.CreateSchedule)
+	router.GET("/api/schedules/:scheduleId", runner.GetSchedule)
+	router.PUT("/api/schedules/:scheduleId", runner.UpdateSchedule)
+	router.DELETE("/api/schedules/:scheduleId", runner.DeleteSchedule)
+	return router
+}
+
+func serveJSON(router *gin.Engine, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	request := httptest.NewRequest(method, path, bytes.NewReader(data))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestScheduleRunnerStartsDueJobsAndRecordsOutcome(t *testing.T) {
+	t.Setenv("MODEL1_API_KEY", "test-key-value")
+	t.Setenv("MODEL1_BASE_URL", "https://genai.example.com")
+	store, err := NewFileScheduleStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jm := NewSimpleJobManager()
+	jm.limits = schedulerLimits{MaxRunning: 1}
+	blocker := jm.CreateJob(BenchmarkRequest{})
+	release := make(chan struct{})
+	defer close(release)
+	jm.Enqueue(blocker, func() { <-release }) // Keeps the scheduled job queued
+
+	runner := NewScheduleRunner(store, jm)
+	router := newScheduleRouter(runner)
+	withRawKey := map[string]interface{}{
+		"name": "nightly",
+		"cron": "0 2 * * *",
+		"benchmark": map[string]interface{}{
+			"model1":            map[string]interface{}{"name": "llama", "baseUrl": "https://genai.example.com", "apiKey": "sk-raw-key-in-schedule"},
+			"concurrencyLevels": []int{1},
+			"maxTokens":         64,
+			"prompt":            "Hello",
+		},
+	}
+	if response := serveJSON(router, http.MethodPost, "/api/schedules", withRawKey); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "model1.apiKey is not accepted") {
+		t.Fatalf("expected a schedule with an API key to be rejected, got %d: %s", response.Code, response.Body)
+	}
+	if schedules, _ := store.ListSchedules(); len(schedules) != 0 {
+		t.Fatalf("expected no schedule to be stored, got %d", len(schedules))
+	}
+
+	response := serveJSON(router, http.MethodPost, "/api/schedules", ScheduleRequest{
+		Name: "nightly",
+		Cron: "0 2 * * *",
+		Benchmark: &BenchmarkRequest{
+			Model1:            Model{Name: "llama", BaseURL: "https://genai.example.com", SecretID: "model1"},
+			ConcurrencyLevels: []int{1, 2},
+			MaxTokens:         64,
+			Prompt:            "Hello",
+		},
+	})
+	if response.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", response.Code, response.Body)
+	}
+	var schedule BenchmarkSchedule
+	json.Unmarshal(response.Body.Bytes(), &schedule)
+	fetched := serveJSON(router, http.MethodGet, "/api/schedules/"+schedule.ID, nil)
+	if body := fetched.Body.String(); fetched.Code != http.StatusOK || !strings.Contains(body, `"secretId":"model1"`) || strings.Contains(body, "apiKey") || strings.Contains(body, "test-key-value") {
+		t.Errorf("expected the schedule to hold only the credential reference, got %d: %s", fetched.Code, body)
+	}
+	if schedule.NextRunAt == nil || schedule.NextRunAt.Hour() != 2 || schedule.NextRunAt.Minute() != 0 {
+		t.Fatalf("unexpected next run %v", schedule.NextRunAt)
+	}
+
+	due := schedule.NextRunAt.Add(time.Second)
+	runner.tick(due)
+	stored, _ := store.GetSchedule(schedule.ID)
+	if len(stored.History) != 1 || stored.LastRun == nil || stored.LastRun.Status != "queued" {
+		t.Fatalf("expected one queued run, got %+v", stored.History)
+	}
+	if !stored.NextRunAt.After(due) {
+		t.Errorf("expected the next run to move past %v, got %v", due, stored.NextRunAt)
+	}
+	if stored.Benchmark.Model1.SecretID != "model1" {
+		t.Error("expected the stored definition to keep its credential reference")
+	}
+	jobID := stored.LastRun.JobID
+	if job, _ := jm.GetJob(jobID); job.ScheduleID != schedule.ID {
+		t.Errorf("expected the job to reference its schedule, got %q", job.ScheduleID)
+	}
+
+	runner.tick(due) // Not due again
+	jm.CancelJob(jobID)
+	runner.tick(due)
+	stored, _ = store.GetSchedule(schedule.ID)
+	if len(stored.History) != 1 || stored.LastRun.Status != "cancelled" || stored.LastRun.CompletedAt == nil {
+		t.Errorf("expected the cancelled run to be recorded, got %+v", stored.History)
+	}
+
+	response = serveJSON(router, http.MethodPut, "/api/schedules/"+schedule.ID, ScheduleRequest{
+		Name: "disabled", Cron: "@hourly", Enabled: new(bool), Benchmark: stored.Benchmark,
+	})
+	var updated BenchmarkSchedule
+	json.Unmarshal(response.Body.Bytes(), &updated)
+	if response.Code != http.StatusOK || updated.Enabled || updated.NextRunAt != nil || len(updated.History) != 1 {
+		t.Errorf("expected a disabled schedule that keeps its history, got %d: %s", response.Code, response.Body)
+	}
+	if response := serveJSON(router, http.MethodDelete, "/api/schedules/"+schedule.ID, nil); response.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", response.Code)
+	}
+	if response := serveJSON(router, http.MethodGet, "/api/schedules/"+schedule.ID, nil); response.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after deletion, got %d", response.Code)
+	}
+}
+
+func TestCreateScheduleValidation(t *testing.T) {
+	router := newScheduleRouter(NewScheduleRunner(NewMemoryScheduleStore(), NewSimpleJobManager()))
+	benchmark := &BenchmarkRequest{Model1: Model{Name: "llama"}, ConcurrencyLevels: []int{1}, MaxTokens: 64, Prompt: "Hello"}
+	for _, request := range []ScheduleRequest{
+		{Name: "no job", Cron: "@daily"},
+		{Name: "bad cron", Cron: "every night", Benchmark: benchmark},
+		{Name: "never", Cron: "0 0 31 2 *", Benchmark: benchmark},
+		{Name: "bad zone", Cron: "@daily", Timezone: "Mars/Olympus", Benchmark: benchmark},
+	} {
+		if response := serveJSON(router, http.MethodPost, "/api/schedules", request); response.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", request.Name, response.Code)
+		}
+	}
+}
+
+func TestFileScheduleStoreLeaderLease(t *testing.T) {
+	directory := t.TempDir()
+	first, _ := NewFileScheduleStore(directory)
+	second, _ := NewFileScheduleStore(directory)
+
+	if leader, err := first.AcquireLeadership("a", 100*time.Millisecond); !leader || err != nil {
+		t.Fatalf("expected the first instance to lead, got %t, %v", leader, err)
+	}
+	if leader, _ := second.AcquireLeadership("b", 100*time.Millisecond); leader {
+		t.Fatal("expected the lease to exclude the second instance")
+	}
+	if leader, _ := first.AcquireLeadership("a", 100*time.Millisecond); !leader {
+		t.Fatal("expected the leader to renew its lease")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if leader, _ := second.AcquireLeadership("b", 100*time.Millisecond); !leader {
+		t.Fatal("expected the second instance to take over an expired lease")
+	}
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"llmapibenchmark/internal/utils"
+)
+
+// Defaults for saturation search jobs
+const (
+	defaultSearchMaxErrorRate = 0.01
+	maxSearchConcurrency      = 100 // Same ceiling as benchmark concurrency levels
+)
+
+// validateSearchRequest checks a saturation search request and fills in defaults
+func validateSearchRequest(req *SearchRequest) error {
+	if req.Model.ID == "" {
+		return fmt.Errorf("model.id is required")
+	}
+	if req.Model.Name == "" {
+		return fmt.Errorf("model.name is required")
+	}
+	if req.Model.BaseURL == "" {
+		return fmt.Errorf("model.baseUrl is required")
+	}
+	if err := req.Model.validateCredential("model"); err != nil {
+		return err
+	}
+	if req.MaxTokens < 1 || req.MaxTokens > 4096 {
+		return fmt.Errorf("maxTokens must be between 1 and 4096, got %d", req.MaxTokens)
+	}
+	if len(strings.TrimSpace(req.Prompt)) == 0 {
+		return fmt.Errorf("prompt cannot be empty")
+	}
+
+	if req.StartConcurrency == 0 {
+		req.StartConcurrency = 1
+	}
+	if req.MaxConcurrency == 0 {
+		req.MaxConcurrency = maxSearchConcurrency
+	}
+	if req.MaxConcurrency < 1 || req.MaxConcurrency > maxSearchConcurrency {
+		return fmt.Errorf("maxConcurrency must be between 1 and %d, got %d", maxSearchConcurrency, req.MaxConcurrency)
+	}
+	if req.StartConcurrency < 1 || req.StartConcurrency > req.MaxConcurrency {
+		return fmt.Errorf("startConcurrency must be between 1 and maxConcurrency (%d), got %d", req.MaxConcurrency, req.StartConcurrency)
+	}
+
+	if req.SLO.MaxP95TTFTSeconds < 0 {
+		return fmt.Errorf("slo.maxP95TtftSeconds must not be negative, got %g", req.SLO.MaxP95TTFTSeconds)
+	}
+	if req.SLO.MinPerUserTokensPerSecond < 0 {
+		return fmt.Errorf("slo.minPerUserTokensPerSecond must not be negative, got %g", req.SLO.MinPerUserTokensPerSecond)
+	}
+	if req.SLO.MaxErrorRate == nil {
+		rate := defaultSearchMaxErrorRate
+		req.SLO.MaxErrorRate = &rate
+	}
+	if *req.SLO.MaxErrorRate < 0 || *req.SLO.MaxErrorRate > 1 {
+		return fmt.Errorf("slo.maxErrorRate must be between 0 and 1, got %g", *req.SLO.MaxErrorRate)
+	}
+	if err := validateJobCallback(&req.JobCallback); err != nil {
+		return err
+	}
+	if err := validatePriority(req.Priority); err != nil {
+		return err
+	}
+	return req.RequestTimeouts.validate()
+}
+
+// RunSearch executes a saturation search job, reporting each measured level over SSE
+func (jm *SimpleJobManager) RunSearch(jobID string, request SearchRequest) {
+	ctx := jm.jobContext(jobID)
+
+	ctx, jobSpan := startJobSpan(ctx, jobID, JobTypeSearch)
+	defer jm.endJobSpan(jobID, jobSpan)
+	modelContexts, endModelSpans := startModelSpans(ctx, map[int]*Model{1: &request.Model})
+	defer endModelSpans()
+
+	AppLogger.InfoWithFields("Starting saturation search", map[string]interface{}{
+		"jobId":            jobID,
+		"model":            request.Model.Name,
+		"startConcurrency": request.StartConcurrency,
+		"maxConcurrency":   request.MaxConcurrency,
+		"slo":              request.SLO,
+	})
+
+	// Give SSE connection time to establish
+	time.Sleep(2 * time.Second)
+	jm.UpdateJobProgress(jobID, 10, "Initializing saturation search...")
+
+	apiKey := getAPIKeyForModel(request.Model)
+	if apiKey == "" {
+		AppLogger.ErrorWithContext(&LogContext{JobID: jobID, Model: request.Model.Name}, "No API key found for model")
+		jm.FailJob(jobID, fmt.Sprintf("No API key found for model %s", request.Model.Name))
+		return
+	}
+
+	transport, err := transportConfigForModel(request.Model)
+	if err != nil {
+		AppLogger.ErrorWithContext(&LogContext{JobID: jobID, Model: request.Model.Name}, "Invalid transport settings: %v", err)
+		jm.FailJob(jobID, err.Error())
+		return
+	}
+
+	measurement := jm.describeMeasurement(ctx, jobID, request.Model.BaseURL, request.EstimateRTT)
+
+	// Rough upper bound on the number of levels, used only to scale progress
+	expectedSteps := 2*int(math.Ceil(math.Log2(float64(request.MaxConcurrency)/float64(request.StartConcurrency)))) + 1
+	completedSteps := 0
+
+	search := utils.SaturationSearch{
+		SLO: utils.SLO{
+			MaxP95Ttft:      request.SLO.MaxP95TTFTSeconds,
+			MinPerUserSpeed: request.SLO.MinPerUserTokensPerSecond,
+			MaxErrorRate:    *request.SLO.MaxErrorRate,
+		},
+		StartConcurrency: request.StartConcurrency,
+		MaxConcurrency:   request.MaxConcurrency,
+		Measure: func(ctx context.Context, concurrency int) (utils.SpeedResult, error) {
+			progress := 20 + min(completedSteps*70/expectedSteps, 70)
+			jm.UpdateJobProgress(jobID, progress, fmt.Sprintf("Testing concurrency %d...", concurrency))
+
+			setup := utils.SpeedMeasurement{
+				BaseUrl:        request.Model.BaseURL,
+				ApiKey:         apiKey,
+				ModelName:      request.Model.Name,
+				Prompt:         request.Prompt,
+				NumWords:       request.NumWords,
+				MaxTokens:      request.MaxTokens,
+				Concurrency:    concurrency,
+				TolerateErrors: true,
+				Transport:      transport,
+				Timeouts:       request.RequestTimeouts.requestTimeouts(),
+				Trace:          jm.traceSink(jobID),
+				TraceJobID:     jobID,
+				Limiter:        rateLimiterForModel(request.Model),
+			}
+			return setup.Run(ctx, nil)
+		},
+		OnStep: func(step utils.SaturationStep) {
+			completedSteps++
+			AppLogger.InfoWithFields("Saturation search step completed", map[string]interface{}{
+				"jobId":       jobID,
+				"phase":       step.Phase,
+				"concurrency": step.Concurrency,
+				"passed":      step.Passed,
+				"violations":  step.Violations,
+			})
+		},
+	}
+
+	saturation, err := search.Run(modelContexts[1])
+	if err != nil {
+		if ctx.Err() != nil {
+			AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Saturation search cancelled")
+			jm.FailJob(jobID, "Job cancelled by user")
+			return
+		}
+		AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Saturation search failed: %v", err)
+		jm.FailJob(jobID, fmt.Sprintf("Saturation search failed: %v", err))
+		return
+	}
+
+	result := newSearchResult(request.Model.Name, saturation)
+	jm.UpdateJobProgress(jobID, 100, "Saturation search completed")
+	jm.CompleteJob(jobID, map[string]interface{}{
+		"search":   result,
+		"metadata": JobMetadata{Measurement: measurement},
+		"slo":      request.SLO,
+	})
+	AppLogger.InfoWithFields("Saturation search completed", map[string]interface{}{
+		"jobId":                     jobID,
+		"maxSustainableConcurrency": result.MaxSustainableConcurrency,
+		"goodput":                   result.Goodput,
+	})
+}
+
+// newSearchResult converts a saturation search outcome into the API result shape
+func newSearchResult(model string, saturation utils.SaturationResult) SearchResult {
+	result := SearchResult{
+		Model:                     model,
+		MaxSustainableConcurrency: saturation.MaxSustainableConcurrency,
+		Goodput:                   saturation.Goodput,
+		LimitReached:              saturation.LimitReached,
+		Steps:                     make([]SearchStep, 0, len(saturation.Steps)),
+	}
+	if saturation.Best != nil {
+		best := newConcurrencyResult(model, *saturation.Best)
+		result.Best = &best
+	}
+	levels := make([]ConcurrencyResult, 0, len(saturation.Steps))
+	for _, step := range saturation.Steps {
+		concurrencyResult := newConcurrencyResult(model, step.Result)
+		concurrencyResult.Concurrency = step.Concurrency
+		result.Steps = append(result.Steps, SearchStep{
+			Phase:       step.Phase,
+			Concurrency: step.Concurrency,
+			Passed:      step.Passed,
+			Violations:  step.Violations,
+			Result:      concurrencyResult,
+		})
+		levels = append(levels, concurrencyResult)
+	}
+	result.Cost = totalCost(levels)
+	return result
+}
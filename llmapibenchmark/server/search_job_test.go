@@ -0,0 +1,38 @@
+package server
+
+import "testing"
+
+func TestValidateSearchRequest_Defaults(t *testing.T) {
+	request := SearchRequest{
+		Model:     Model{ID: "a", Name: "model-a", BaseURL: "https://a.example.com/v1"},
+		MaxTokens: 64,
+		Prompt:    "hello",
+	}
+	if err := validateSearchRequest(&request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.StartConcurrency != 1 || request.MaxConcurrency != maxSearchConcurrency {
+		t.Errorf("expected concurrency range 1..%d, got %d..%d", maxSearchConcurrency, request.StartConcurrency, request.MaxConcurrency)
+	}
+	if request.SLO.MaxErrorRate == nil || *request.SLO.MaxErrorRate != defaultSearchMaxErrorRate {
+		t.Errorf("expected default max error rate %v, got %v", defaultSearchMaxErrorRate, request.SLO.MaxErrorRate)
+	}
+}
+
+func TestValidateSearchRequest_Rejects(t *testing.T) {
+	rate := 1.5
+	testCases := map[string]SearchRequest{
+		"start above max":  {StartConcurrency: 50, MaxConcurrency: 10},
+		"max above limit":  {MaxConcurrency: 500},
+		"error rate above": {SLO: SearchSLO{MaxErrorRate: &rate}},
+	}
+
+	for name, request := range testCases {
+		request.Model = Model{ID: "a", Name: "model-a", BaseURL: "https://a.example.com/v1"}
+		request.MaxTokens = 64
+		request.Prompt = "hello"
+		if err := validateSearchRequest(&request); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package server
 
 import (
 	"bytes"
-	"io"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -25,72 +25,251 @@ func (h *SimpleHandlers) StartBenchmark(c *gin.Context) {
 	AppLogger.InfoWithFields("StartBenchmark received request", map[string]interface{}{
 		"clientIP": c.ClientIP(),
 	})
-	AppLogger.DebugWithFields("StartBenchmark request headers", map[string]interface{}{
-		"headers": c.Request.Header,
-	})
-	
-	// Log raw request body
-	body, _ := c.GetRawData()
-	AppLogger.DebugWithFields("StartBenchmark raw request body", map[string]interface{}{
-		"body": string(body),
-	})
-	
-	// Reset body for binding
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
-	
 	var request BenchmarkRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		AppLogger.Error("StartBenchmark failed to bind JSON: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
 		return
 	}
-	
-	AppLogger.DebugWithFields("StartBenchmark parsed request", map[string]interface{}{
-		"request": request,
-	})
-	AppLogger.DebugWithFields("StartBenchmark Model1", map[string]interface{}{
-		"model1": request.Model1,
-	})
-	AppLogger.DebugWithFields("StartBenchmark Model2", map[string]interface{}{
-		"model2": request.Model2,
-	})
-	AppLogger.DebugWithFields("StartBenchmark ConcurrencyLevels", map[string]interface{}{
-		"concurrencyLevels": request.ConcurrencyLevels,
+
+	// Validate request
+	if err := validateAsyncBenchmarkRequest(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Create job
+	jobID := h.jobManager.CreateJob(request)
+	h.jobManager.setJobOwner(jobID, principalFrom(c).Subject)
+	AppLogger.InfoWithContext(&LogContext{JobID: jobID, UserID: principalFrom(c).Subject}, "Created job for asynchronous benchmark")
+
+	// Queue the benchmark; it starts as soon as the job limits allow (don't wait for SSE connection)
+	if !h.enqueue(c, jobID, func() { h.jobManager.RunBenchmark(jobID, request) }) {
+		return
+	}
+
+	// Return job ID and SSE endpoint
+	job, _ := h.jobManager.GetJobState(jobID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"jobId": jobID,
+		"message": "Benchmark job started successfully",
+		"status": job.Status,
+		"queuePosition": job.QueuePosition,
+		"sse": gin.H{
+			"url": "/api/jobs/" + jobID + "/stream",
+			"message": "Connect to SSE endpoint for real-time progress updates",
+		},
 	})
-	AppLogger.DebugWithFields("StartBenchmark MaxTokens", map[string]interface{}{
-		"maxTokens": request.MaxTokens,
+}
+
+// validateAsyncBenchmarkRequest checks a benchmark job request and fills in defaults
+func validateAsyncBenchmarkRequest(request *BenchmarkRequest) error {
+	if request.Model1.Name == "" {
+		return errors.New("Model 1 is required")
+	}
+	if len(request.ConcurrencyLevels) == 0 {
+		return errors.New("At least one concurrency level is required")
+	}
+	if err := validateRequestCredentials(request); err != nil {
+		return err
+	}
+	if err := validateRequestSchedule(request); err != nil {
+		return err
+	}
+	if err := validateRepetitions(request); err != nil {
+		return err
+	}
+	if err := validateJobCallback(&request.JobCallback); err != nil {
+		return err
+	}
+	return validatePriority(request.Priority)
+}
+
+// StartSearch starts a saturation search job and returns the job ID
+func (h *SimpleHandlers) StartSearch(c *gin.Context) {
+	var request SearchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		AppLogger.Error("StartSearch failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	if err := validateSearchRequest(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobID := h.jobManager.CreateSearchJob(request)
+	h.jobManager.setJobOwner(jobID, principalFrom(c).Subject)
+	if !h.enqueue(c, jobID, func() { h.jobManager.RunSearch(jobID, request) }) {
+		return
+	}
+	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Queued saturation search job")
+
+	job, _ := h.jobManager.GetJobState(jobID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"jobId": jobID,
+		"message": "Saturation search job started successfully",
+		"status": job.Status,
+		"queuePosition": job.QueuePosition,
+		"sse": gin.H{
+			"url": "/api/jobs/" + jobID + "/stream",
+			"message": "Connect to SSE endpoint for real-time progress updates",
+		},
 	})
-	AppLogger.DebugWithFields("StartBenchmark Prompt", map[string]interface{}{
-		"prompt": request.Prompt,
+}
+
+// EstimateBenchmark returns the pre-flight estimate of a benchmark request and whether
+// the caller's quotas allow it now
+func (h *SimpleHandlers) EstimateBenchmark(c *gin.Context) {
+	var request BenchmarkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if err := validateAsyncBenchmarkRequest(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondEstimate(c, EstimateBenchmark(request))
+}
+
+// EstimateSearch returns the worst-case estimate of a saturation search, see EstimateBenchmark
+func (h *SimpleHandlers) EstimateSearch(c *gin.Context) {
+	var request SearchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if err := validateSearchRequest(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondEstimate(c, EstimateSearch(request))
+}
+
+func (h *SimpleHandlers) respondEstimate(c *gin.Context, estimate JobUsage) {
+	response := gin.H{"estimate": estimate, "allowed": true}
+	if err := h.jobManager.CheckQuota(principalFrom(c).Subject, estimate); err != nil {
+		response["allowed"] = false
+		response["error"] = err.Error()
+		response["quota"] = err
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ResumeJob continues a partial, failed or cancelled benchmark from its first missing level
+func (h *SimpleHandlers) ResumeJob(c *gin.Context) {
+	if !h.authorizeJob(c, c.Param("jobId")) {
+		return
+	}
+	h.startDerivedJob(c, "Resumed job started successfully", func() (string, func(), error) {
+		return h.jobManager.ResumeJob(c.Param("jobId"))
 	})
-	AppLogger.DebugWithFields("StartBenchmark NumWords", map[string]interface{}{
-		"numWords": request.NumWords,
+}
+
+// RerunJob starts a copy of a job. An optional body is a JSON merge patch of the request.
+func (h *SimpleHandlers) RerunJob(c *gin.Context) {
+	if !h.authorizeJob(c, c.Param("jobId")) {
+		return // The clone uses the source job's credentials
+	}
+	overrides, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	h.startDerivedJob(c, "Rerun job started successfully", func() (string, func(), error) {
+		return h.jobManager.RerunJob(c.Param("jobId"), bytes.TrimSpace(overrides))
 	})
+}
 
-	// Validate request
-	if request.Model1.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Model 1 is required"})
+// PauseJob holds a running benchmark before its next concurrency level
+func (h *SimpleHandlers) PauseJob(c *gin.Context) {
+	h.controlJob(c, "Job will pause after the current level", h.jobManager.PauseJob)
+}
+
+// ContinueJob lets a paused benchmark continue
+func (h *SimpleHandlers) ContinueJob(c *gin.Context) {
+	h.controlJob(c, "Job continues", h.jobManager.UnpauseJob)
+}
+
+// SkipLevel stops the running concurrency level of a benchmark
+func (h *SimpleHandlers) SkipLevel(c *gin.Context) {
+	h.controlJob(c, "Skipping the current level", h.jobManager.SkipLevel)
+}
+
+// SetRemainingLevels replaces the concurrency levels a benchmark has not started yet
+func (h *SimpleHandlers) SetRemainingLevels(c *gin.Context) {
+	var request struct {
+		ConcurrencyLevels []int `json:"concurrencyLevels"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
 		return
 	}
+	if request.ConcurrencyLevels == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "concurrencyLevels is required"})
+		return
+	}
+	h.controlJob(c, "Remaining levels change before the next level", func(jobID string) error {
+		return h.jobManager.SetRemainingLevels(jobID, request.ConcurrencyLevels)
+	})
+}
 
-	if len(request.ConcurrencyLevels) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one concurrency level is required"})
+// controlJob applies a control operation to a running job and answers with its state
+func (h *SimpleHandlers) controlJob(c *gin.Context, message string, control func(jobID string) error) {
+	jobID := c.Param("jobId")
+	if !h.authorizeJob(c, jobID) {
+		return
+	}
+	if err := control(jobID); err != nil {
+		AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Job control request failed: %v", err)
+		status := http.StatusConflict
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrInvalidControl):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "jobId": jobID})
 		return
 	}
 
-	// Create job
-	jobID := h.jobManager.CreateJob(request)
-	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Created job for asynchronous benchmark")
+	job, _ := h.jobManager.GetJobState(jobID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"jobId": jobID,
+		"status": job.Status,
+		"paused": job.Paused,
+	})
+}
 
-	// Start the benchmark execution immediately (don't wait for SSE connection)
-	go h.jobManager.RunBenchmark(jobID, request)
-	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Started benchmark execution for job")
+// startDerivedJob creates a job from an existing one and queues it
+func (h *SimpleHandlers) startDerivedJob(c *gin.Context, message string, create func() (string, func(), error)) {
+	jobID, run, err := create()
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "jobId": c.Param("jobId")})
+		return
+	case errors.Is(err, ErrJobNotResumable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "jobId": c.Param("jobId")})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.jobManager.setJobOwner(jobID, principalFrom(c).Subject)
+	if !h.enqueue(c, jobID, run) {
+		return
+	}
 
-	// Return job ID and SSE endpoint
+	job, _ := h.jobManager.GetJobState(jobID)
 	c.JSON(http.StatusAccepted, gin.H{
 		"jobId": jobID,
-		"message": "Benchmark job started successfully",
-		"status": "started",
+		"sourceJobId": c.Param("jobId"),
+		"message": message,
+		"status": job.Status,
+		"queuePosition": job.QueuePosition,
 		"sse": gin.H{
 			"url": "/api/jobs/" + jobID + "/stream",
 			"message": "Connect to SSE endpoint for real-time progress updates",
@@ -98,6 +277,39 @@ func (h *SimpleHandlers) StartBenchmark(c *gin.Context) {
 	})
 }
 
+// authorizeJob answers 403 unless the caller may control the job. Unknown jobs pass, so
+// that the handler reports them.
+func (h *SimpleHandlers) authorizeJob(c *gin.Context, jobID string) bool {
+	principal := principalFrom(c)
+	err := h.jobManager.AuthorizeJob(jobID, principal)
+	if err == nil || errors.Is(err, ErrJobNotFound) {
+		return true
+	}
+	AppLogger.WarnWithContext(&LogContext{JobID: jobID, UserID: principal.Subject}, "Job control denied: %v", err)
+	c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "jobId": jobID})
+	return false
+}
+
+// enqueue schedules a created job and answers 503 when the queue is full
+func (h *SimpleHandlers) enqueue(c *gin.Context, jobID string, run func()) bool {
+	err := h.jobManager.Enqueue(jobID, run)
+	if err == nil {
+		return true
+	}
+	AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Failed to queue job: %v", err)
+	if errors.Is(err, ErrQueueFull) {
+		c.Header("Retry-After", "60")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many queued jobs, try again later"})
+		return false
+	}
+	if status := quotaErrorStatus(c, err); status != 0 {
+		c.JSON(status, gin.H{"error": err.Error(), "quota": err})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	return false
+}
+
 // GetJobStatus returns the current status of a job
 func (h *SimpleHandlers) GetJobStatus(c *gin.Context) {
 	jobID := c.Param("jobId")
@@ -125,14 +337,19 @@ func (h *SimpleHandlers) CancelJob(c *gin.Context) {
 	jobID := c.Param("jobId")
 	
 	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Received cancellation request for job")
+	if !h.authorizeJob(c, jobID) {
+		return
+	}
 	
 	// Use the new CancelJob method that actually cancels the context
 	if h.jobManager.CancelJob(jobID) {
 		AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Successfully cancelled job")
+		// A running job stays "running" until its runner stops
+		job, _ := h.jobManager.GetJobState(jobID)
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Job cancelled successfully",
 			"jobId": jobID,
-			"status": "cancelled",
+			"status": job.Status,
 		})
 	} else {
 		AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Failed to cancel job (not found or not cancellable)")
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/schollz/progressbar/v3"
 	"llmapibenchmark/internal/utils"
 )
 
@@ -23,43 +22,89 @@ var (
 
 // SimpleJob represents a benchmark job with basic status tracking
 type SimpleJob struct {
-	ID          string                 `json:"id"`
-	Status      string                 `json:"status"` // "running", "completed", "failed", "cancelled"
-	Progress    int                    `json:"progress"` // 0-100
-	Message     string                 `json:"message"`
-	Result      interface{}            `json:"result,omitempty"`
-	Error       string                 `json:"error,omitempty"`
-	CreatedAt   time.Time              `json:"createdAt"`
-	CompletedAt *time.Time             `json:"completedAt,omitempty"`
-	Request     BenchmarkRequest       `json:"request"`
+	ID            string           `json:"id"`
+	Status        string           `json:"status"`   // "queued", "running", "completed", "partial", "failed", "cancelled"
+	Progress      int              `json:"progress"` // 0-100
+	Message       string           `json:"message"`
+	Result        interface{}      `json:"result,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	CompletedAt   *time.Time       `json:"completedAt,omitempty"`
+	StartedAt     *time.Time       `json:"startedAt,omitempty"`     // When the job left the queue
+	Priority      int              `json:"priority"`                // Higher priorities start first
+	QueuePosition int              `json:"queuePosition,omitempty"` // 1-based while queued
+	Type          string           `json:"type"`                    // "benchmark" or "search"
+	Request       BenchmarkRequest `json:"request"`
+	Search        *SearchRequest   `json:"search,omitempty"`     // Set for search jobs
+	ScheduleID    string           `json:"scheduleId,omitempty"` // Set for jobs started by a schedule
+	ResumedFrom   string           `json:"resumedFrom,omitempty"` // Job whose finished levels this job keeps
+	Paused        bool             `json:"paused,omitempty"`      // Waits before its next level, see PauseJob
+	SkippedLevels []string         `json:"skippedLevels,omitempty"` // Levels stopped with SkipLevel
+	RerunOf       string           `json:"rerunOf,omitempty"`     // Job this job was cloned from
+	Owner         string           `json:"owner,omitempty"`       // Subject that started the job, see AuthorizeJob
+	Estimate      *JobUsage        `json:"estimate,omitempty"`    // Pre-flight estimate, see quota.go
+	Usage         JobUsage         `json:"usage"`                 // Requests, tokens and cost spent so far
 	// Context and cancellation for proper job cancellation
-	ctx         context.Context        `json:"-"`
-	cancelFunc  context.CancelFunc     `json:"-"`
-}
+	ctx        context.Context    `json:"-"`
+	cancelFunc context.CancelFunc `json:"-"`
+	samples    *jobSamples        `json:"-"` // Per-request traces, see GetJobSamples
+	events     *jobEvents         `json:"-"` // Replayable SSE events, see job_events.go
+	// Cancellation of a running job ends when its runner stops, see CancelJob
+	cancelRequested bool `json:"-"`
+	resumeSignal    chan struct{} `json:"-"` // Closed when a paused job continues
+	// Control requests picked up by the running Executor, see job_control.go
+	skipLevel       context.CancelFunc `json:"-"`
+	remainingLevels []int              `json:"-"`
+	// Measured levels and schedule seed of a partial job, see ResumeJob
+	levels       []LevelResult `json:"-"`
+	scheduleSeed int64         `json:"-"`
+	// Scheduling state, see scheduler.go
+	run       func()   `json:"-"`
+	backends  []string `json:"-"`
+	queueSeq  uint64   `json:"-"`
+	holdsSlot bool     `json:"-"`
+}
+
+// Job types
+const (
+	JobTypeBenchmark = "benchmark"
+	JobTypeSearch    = "search"
+)
 
 // JobState represents the state of a job (for Task 15.2 compliance)
 type JobState struct {
-	ID        string    `json:"id"`
-	Status    string    `json:"status"`
-	Progress  int       `json:"progress"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID            string    `json:"id"`
+	Status        string    `json:"status"`
+	Progress      int       `json:"progress"`
+	Message       string    `json:"message"`
+	CreatedAt     time.Time `json:"createdAt"`
+	QueuePosition int       `json:"queuePosition,omitempty"`
+	Paused        bool      `json:"paused,omitempty"`
 }
 
 // SimpleJobManager manages benchmark jobs with minimal complexity
 type SimpleJobManager struct {
 	jobs                    map[string]*SimpleJob
-	listeners               map[string][]chan *SimpleJob
 	systemStatusListeners   []chan map[string]interface{} // For system status SSE
 	activeJobCount          int // Global counter for active jobs
 	mutex                   sync.RWMutex
+	// Scheduler state, see scheduler.go
+	queue                   []*SimpleJob // Queued jobs in start order
+	nextQueueSeq            uint64
+	runningByBackend        map[string]int
+	limits                  schedulerLimits
+	quotas                  quotaSettings
+	spending                quotaLedger // Today's usage, see quota.go
+	executionObservers      []func(ExecutionEvent) // E.g. the WebSocket hub
 }
 
 // NewSimpleJobManager creates a new simple job manager
 func NewSimpleJobManager() *SimpleJobManager {
 	return &SimpleJobManager{
-		jobs:      make(map[string]*SimpleJob),
-		listeners: make(map[string][]chan *SimpleJob),
+		jobs:             make(map[string]*SimpleJob),
+		runningByBackend: make(map[string]int),
+		limits:           schedulerLimitsFromEnv(),
+		quotas:           quotaConfig(),
 	}
 }
 
@@ -72,31 +117,57 @@ func GetJobManager() *SimpleJobManager {
 	return jobManagerInstance
 }
 
-// CreateJob creates a new job and returns its ID
+// CreateJob creates a new queued job and returns its ID. Enqueue schedules it.
 func (jm *SimpleJobManager) CreateJob(request BenchmarkRequest) string {
+	return jm.addJob(&SimpleJob{
+		Type:    JobTypeBenchmark,
+		Message: "Starting benchmark...",
+		Request: request,
+	})
+}
+
+// CreateSearchJob creates a new queued saturation search job and returns its ID
+func (jm *SimpleJobManager) CreateSearchJob(request SearchRequest) string {
+	return jm.addJob(&SimpleJob{
+		Type:    JobTypeSearch,
+		Message: "Starting saturation search...",
+		Request: BenchmarkRequest{
+			Model1:      request.Model,
+			MaxTokens:   request.MaxTokens,
+			Prompt:      request.Prompt,
+			NumWords:    request.NumWords,
+			Priority:    request.Priority,
+			JobCallback: request.JobCallback,
+		},
+		Search: &request,
+	})
+}
+
+// addJob registers a new queued job and returns its ID
+func (jm *SimpleJobManager) addJob(job *SimpleJob) string {
 	jm.mutex.Lock()
 	defer jm.mutex.Unlock()
 
 	jobID := uuid.New().String()
-	job := &SimpleJob{
-		ID:        jobID,
-		Status:    "running",
-		Progress:  0,
-		Message:   "Starting benchmark...",
-		CreatedAt: time.Now(),
-		Request:   request,
-	}
+	job.ID = jobID
+	job.Status = "queued"
+	job.Progress = 0
+	job.CreatedAt = time.Now()
+	job.Priority = job.Request.Priority
+	job.backends = jobBackends(job.Request)
+	job.samples = newJobSamples()
+	job.events = newJobEvents()
+	estimate := estimateJob(job)
+	job.Estimate = &estimate
 
 	jm.jobs[jobID] = job
-	jm.activeJobCount++
+	jobsCreated.With(job.Type).Inc()
 	AppLogger.InfoWithFields("Job created", map[string]interface{}{
 		"jobId": jobID,
+		"type": job.Type,
 		"activeJobs": jm.activeJobCount,
 	})
 	
-	// Broadcast system status change
-	go jm.broadcastSystemStatus()
-	
 	return jobID
 }
 
@@ -159,14 +230,17 @@ func (jm *SimpleJobManager) CompleteJob(jobID string, result interface{}) {
 		job.Status = "completed"
 		job.Progress = 100
 		job.Message = "Benchmark completed successfully"
+		result = withUsage(result, job.Usage)
 		job.Result = result
+		job.Paused = false
 		now := time.Now()
 		job.CompletedAt = &now
+		job.finishSamples()
+		go publishJobResult(jobID, job.Type, now, result)
+		jm.notifyJob(job, JobEventCompleted)
 		
-		// Decrement active job counter
-		if jm.activeJobCount > 0 {
-			jm.activeJobCount--
-		}
+		// Free the job's slots for queued jobs
+		jm.releaseLocked(job)
 		
 		AppLogger.InfoWithFields("Job completed successfully", map[string]interface{}{
 			"jobId": jobID,
@@ -186,22 +260,32 @@ func (jm *SimpleJobManager) CompleteJob(jobID string, result interface{}) {
 	}
 }
 
-// FailJob marks a job as failed with error message
+// FailJob marks a job as failed with error message. A job whose cancellation was
+// requested ends as cancelled; jobs that already ended are left alone.
 func (jm *SimpleJobManager) FailJob(jobID string, errorMsg string) {
 	jm.mutex.Lock()
 	defer jm.mutex.Unlock()
 
 	if job, exists := jm.jobs[jobID]; exists {
+		if isFinalJobStatus(job.Status) {
+			AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Ignoring failure of ended job (status: %s): %s", job.Status, errorMsg)
+			return
+		}
+		if job.cancelRequested {
+			jm.finishCancelLocked(job)
+			return
+		}
 		job.Status = "failed"
 		job.Message = "Benchmark failed"
 		job.Error = errorMsg
+		job.Paused = false
 		now := time.Now()
 		job.CompletedAt = &now
+		job.finishSamples()
+		jm.notifyJob(job, JobEventFailed)
 		
-		// Decrement active job counter
-		if jm.activeJobCount > 0 {
-			jm.activeJobCount--
-		}
+		// Free the job's slots for queued jobs
+		jm.releaseLocked(job)
 		
 		AppLogger.ErrorWithFields("Job failed", map[string]interface{}{
 			"jobId": jobID,
@@ -221,42 +305,98 @@ func (jm *SimpleJobManager) FailJob(jobID string, errorMsg string) {
 	}
 }
 
-// CancelJob cancels a running job by cancelling its context
+// PartialJob ends a failed or cancelled benchmark that kept some measured levels. The
+// job can be resumed from its first missing level, see ResumeJob.
+func (jm *SimpleJobManager) PartialJob(jobID string, execution *ExecutionResult, reason string) {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+
+	job, exists := jm.jobs[jobID]
+	if !exists {
+		AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Job not found for partial result")
+		return
+	}
+	if isFinalJobStatus(job.Status) {
+		return
+	}
+	if job.cancelRequested {
+		reason = "Job cancelled by user"
+	}
+	job.Status = "partial"
+	job.Message = fmt.Sprintf("Benchmark stopped after %d of %d levels", len(execution.Finished), execution.TotalLevels())
+	job.Error = reason
+	job.Result = withUsage(execution.JobResult(), job.Usage)
+	job.levels = execution.Finished
+	job.scheduleSeed = execution.Schedule.Seed
+	job.Paused = false
+	now := time.Now()
+	job.CompletedAt = &now
+	job.finishSamples()
+	jm.notifyJob(job, JobEventPartial)
+	jm.releaseLocked(job)
+
+	AppLogger.WarnWithFields("Job ended with partial results", map[string]interface{}{
+		"jobId":      jobID,
+		"levels":     len(execution.Finished),
+		"error":      reason,
+		"activeJobs": jm.activeJobCount,
+	})
+	jm.broadcastUpdate(jobID, job)
+	go jm.broadcastSystemStatus()
+}
+
+// CancelJob cancels a queued job at once. A running job has its context cancelled and
+// ends when its runner stops, so its slots stay held until the backend is idle.
 func (jm *SimpleJobManager) CancelJob(jobID string) bool {
 	jm.mutex.Lock()
 	defer jm.mutex.Unlock()
 
-	if job, exists := jm.jobs[jobID]; exists {
-		if job.Status == "running" && job.cancelFunc != nil {
-		// Cancel the context to stop the benchmark execution
-		job.cancelFunc()
-		job.Status = "cancelled"
-		job.Message = "Job cancelled by user"
-		job.Error = "Job cancelled by user"
-		now := time.Now()
-		job.CompletedAt = &now
-		jm.activeJobCount--
-			AppLogger.InfoWithFields("Job cancelled", map[string]interface{}{
-				"jobId": jobID,
-				"activeJobs": jm.activeJobCount,
-			})
-		
-		// Broadcast cancellation update to SSE listeners
-		AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Broadcasting cancellation to SSE listeners")
-		jm.broadcastUpdate(jobID, job)
-			
-			// Broadcast system status change
-			go jm.broadcastSystemStatus()
-			
-			return true
-		} else {
-			AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Job cannot be cancelled (status: %s)", job.Status)
-			return false
-		}
-	} else {
+	job, exists := jm.jobs[jobID]
+	if !exists {
 		AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Job not found for cancellation")
 		return false
 	}
+	switch {
+	case job.Status == "queued":
+		if job.cancelFunc != nil {
+			job.cancelFunc()
+		}
+		jm.finishCancelLocked(job)
+		return true
+	case job.Status == "running" && job.cancelFunc != nil:
+		if !job.cancelRequested {
+			job.cancelRequested = true
+			job.cancelFunc()
+			job.Message = "Cancelling..."
+			AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Job cancellation requested")
+			jm.broadcastUpdate(jobID, job)
+		}
+		return true
+	default:
+		AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Job cannot be cancelled (status: %s)", job.Status)
+		return false
+	}
+}
+
+// finishCancelLocked marks a job as cancelled and frees its slots. The caller holds the lock.
+func (jm *SimpleJobManager) finishCancelLocked(job *SimpleJob) {
+	job.Status = "cancelled"
+	job.Message = "Job cancelled by user"
+	job.Error = "Job cancelled by user"
+	job.Paused = false
+	now := time.Now()
+	job.CompletedAt = &now
+	job.finishSamples()
+	jm.notifyJob(job, JobEventCancelled)
+	jm.releaseLocked(job)
+	AppLogger.InfoWithFields("Job cancelled", map[string]interface{}{
+		"jobId": job.ID,
+		"activeJobs": jm.activeJobCount,
+	})
+
+	// Broadcast cancellation update to SSE listeners
+	jm.broadcastUpdate(job.ID, job)
+	go jm.broadcastSystemStatus()
 }
 
 // AddJob adds a job with context and cancellation function (Task 15.2 compliance)
@@ -286,6 +426,8 @@ func (jm *SimpleJobManager) GetJobState(jobID string) (JobState, bool) {
 			Progress:  job.Progress,
 			Message:   job.Message,
 			CreatedAt: job.CreatedAt,
+			QueuePosition: job.QueuePosition,
+			Paused:        job.Paused,
 		}, true
 	}
 	return JobState{}, false
@@ -302,10 +444,9 @@ func (jm *SimpleJobManager) RemoveJob(jobID string) {
 			job.cancelFunc()
 			AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Job cancelled during removal")
 		}
+		job.finishSamples()
+		jm.releaseLocked(job)
 		delete(jm.jobs, jobID)
-		if jm.activeJobCount > 0 {
-			jm.activeJobCount--
-		}
 		AppLogger.InfoWithFields("Job removed from registry", map[string]interface{}{
 			"jobId": jobID,
 			"activeJobs": jm.activeJobCount,
@@ -351,19 +492,30 @@ func (jm *SimpleJobManager) ListJobs() []*SimpleJob {
 	return jobs
 }
 
-// CleanupOldJobs removes jobs older than 1 hour
+// CleanupOldJobs removes finished jobs older than 1 hour
 func (jm *SimpleJobManager) CleanupOldJobs() {
 	jm.mutex.Lock()
 	defer jm.mutex.Unlock()
 
 	cutoff := time.Now().Add(-1 * time.Hour)
 	for id, job := range jm.jobs {
+		if job.Status == "queued" || job.Status == "running" {
+			continue // Still holds a queue entry or slots
+		}
 		if job.CreatedAt.Before(cutoff) {
+			job.finishSamples()
 			delete(jm.jobs, id)
 		}
 	}
 }
 
+// finishSamples ends the job's trace stream, if it has one
+func (job *SimpleJob) finishSamples() {
+	if job.samples != nil {
+		job.samples.finish()
+	}
+}
+
 // ToJSON converts job to JSON for SSE streaming
 func (job *SimpleJob) ToJSON() ([]byte, error) {
 	// Create a copy of the job to sanitize NaN/Inf values
@@ -394,6 +546,7 @@ func (job *SimpleJob) ToJSON() ([]byte, error) {
 			// Last resort: create a minimal job object without the problematic result
 			minimalJob := SimpleJob{
 				ID:          job.ID,
+				Type:        job.Type,
 				Status:      job.Status,
 				Progress:    job.Progress,
 				Message:     job.Message,
@@ -551,58 +704,16 @@ func (job *SimpleJob) ToSSEMessage() string {
 	return fmt.Sprintf("data: %s\n\n", string(data))
 }
 
-// RegisterSSEListener registers a channel to receive job updates
-func (jm *SimpleJobManager) RegisterSSEListener(jobID string, updateChan chan *SimpleJob) {
-	jm.mutex.Lock()
-	defer jm.mutex.Unlock()
-	
-	if jm.listeners[jobID] == nil {
-		jm.listeners[jobID] = make([]chan *SimpleJob, 0)
-	}
-	jm.listeners[jobID] = append(jm.listeners[jobID], updateChan)
-}
-
-// UnregisterSSEListener removes a channel from job updates
-func (jm *SimpleJobManager) UnregisterSSEListener(jobID string, updateChan chan *SimpleJob) {
-	jm.mutex.Lock()
-	defer jm.mutex.Unlock()
-	
-	if listeners, exists := jm.listeners[jobID]; exists {
-		for i, ch := range listeners {
-			if ch == updateChan {
-				jm.listeners[jobID] = append(listeners[:i], listeners[i+1:]...)
-				close(updateChan)
-				break
-			}
-		}
-		if len(jm.listeners[jobID]) == 0 {
-			delete(jm.listeners, jobID)
-		}
-	}
-}
-
-// broadcastUpdate sends job updates to all registered listeners
+// broadcastUpdate records the job's state in its event log, which SSE clients follow.
+// The caller holds the lock.
 func (jm *SimpleJobManager) broadcastUpdate(jobID string, job *SimpleJob) {
-	if listeners, exists := jm.listeners[jobID]; exists {
-		AppLogger.DebugWithFields("Broadcasting update to SSE listeners", map[string]interface{}{
-			"jobId": jobID,
-			"listeners": len(listeners),
-			"status": job.Status,
-			"progress": job.Progress,
-			"message": job.Message,
-		})
-		for _, ch := range listeners {
-			select {
-			case ch <- job:
-				// Successfully sent update
-			default:
-				// Channel is full, skip this update
-				AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Channel full, skipping update")
-			}
-		}
-	} else {
-		AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "No listeners registered for job")
-	}
+	AppLogger.DebugWithFields("Recording job update", map[string]interface{}{
+		"jobId": jobID,
+		"status": job.Status,
+		"progress": job.Progress,
+		"message": job.Message,
+	})
+	job.recordSnapshot()
 }
 
 // GetActiveJobCount returns the number of currently running jobs
@@ -624,8 +735,13 @@ func (jm *SimpleJobManager) GetSystemStatus() map[string]interface{} {
 	
 	return map[string]interface{}{
 		"activeJobs":    jm.activeJobCount,
+		"queuedJobs":    len(jm.queue),
+		"queue":         jm.queueSnapshotLocked(),
+		"limits":        jm.limits,
 		"isBusy":        jm.activeJobCount > 0,
 		"totalJobs":     len(jm.jobs),
+		"spending":      jm.spendingSnapshotLocked(),
+		"rateLimits":    rateLimitConfig(),
 		"timestamp":     time.Now(),
 	}
 }
@@ -660,13 +776,15 @@ func (jm *SimpleJobManager) UnregisterSystemStatusListener(listener chan map[str
 	}
 }
 
-// RunBenchmark runs the benchmark execution for a job
+// RunBenchmark runs the benchmark execution for a job. It adapts the Executor's events to
+// the job's SSE progress updates.
 func (jm *SimpleJobManager) RunBenchmark(jobID string, request BenchmarkRequest) {
-	// Create a cancellable context for this benchmark job
-	ctx, cancelFunc := context.WithCancel(context.Background())
-	
-	// Set the context in the job for cancellation
-	jm.SetJobContext(jobID, ctx, cancelFunc)
+	// The job's cancellable context, created when it left the queue
+	ctx := jm.jobContext(jobID)
+
+	// Trace the job as job → model → concurrency level → request
+	ctx, jobSpan := startJobSpan(ctx, jobID, JobTypeBenchmark)
+	defer jm.endJobSpan(jobID, jobSpan)
 	
 	AppLogger.InfoWithFields("Starting benchmark", map[string]interface{}{
 		"jobId": jobID,
@@ -683,228 +801,172 @@ func (jm *SimpleJobManager) RunBenchmark(jobID string, request BenchmarkRequest)
 	AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Updating progress: 10%% - Initializing benchmark...")
 	jm.UpdateJobProgress(jobID, 10, "Initializing benchmark...")
 
-	// Test latency first (skip for Cloud Foundry deployments)
-	AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Updating progress: 20%% - Testing latency...")
-	jm.UpdateJobProgress(jobID, 20, "Testing latency...")
-	
-	// Skip latency test for Cloud Foundry deployments as the proxy endpoint may not respond to simple GET requests
-	var latency float64
-	if os.Getenv("VCAP_SERVICES") != "" {
-		AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Skipping latency test for Cloud Foundry deployment")
-		latency = 0.0 // Set to 0 for Cloud Foundry
-	} else {
-		var err error
-		latency, err = utils.MeasureLatency(request.Model1.BaseURL, 5)
-		if err != nil {
-			AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Latency test failed: %v", err)
-			jm.FailJob(jobID, fmt.Sprintf("Latency test failed: %v", err))
-			return
-		}
-		AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Latency test completed: %v", latency)
-	}
+	// Plan the execution order of every (model, concurrency) pair
+	plan := NewExecutionPlan(jobID, request)
+	AppLogger.InfoWithFields("Benchmark schedule planned", map[string]interface{}{
+		"jobId":    jobID,
+		"schedule": plan.Schedule.Schedule,
+		"order":    plan.Schedule.ExecutionOrder,
+	})
 
-	// Prepare results
-	var model1Results []ConcurrencyResult
-	var model2Results []ConcurrencyResult
-	totalSteps := len(request.ConcurrencyLevels)
-	if request.Model2 != nil {
-		totalSteps *= 2
-	}
+	// A resumed job skips the levels measured before
+	plan.Finished = jm.finishedLevels(jobID)
 
-	// Run benchmarks for each concurrency level
-	for i, concurrency := range request.ConcurrencyLevels {
-		// Check for cancellation before each concurrency level
-		select {
-		case <-ctx.Done():
-			AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Job cancelled during Model 1 concurrency %d", concurrency)
-			jm.FailJob(jobID, "Job cancelled by user")
-			return
-		default:
-		}
-		
-		progress := 30 + (i * 60 / totalSteps)
-		AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Updating progress: %d%% - Testing Model 1 concurrency %d...", progress, concurrency)
-		jm.UpdateJobProgress(jobID, progress, fmt.Sprintf("Testing Model 1 concurrency %d...", concurrency))
-
-		// Create progress bar for this concurrency level
-		expectedTokens := concurrency * request.MaxTokens
-		bar := progressbar.NewOptions(expectedTokens,
-			progressbar.OptionSetWriter(os.Stderr), // Use stderr for progress bar output
-			progressbar.OptionSetDescription(fmt.Sprintf("Model1 Concurrency %d", concurrency)),
-			progressbar.OptionSetWidth(40),
-			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
-			progressbar.OptionSetItsString("tokens"),
-			progressbar.OptionSpinnerType(14),
-			progressbar.OptionSetRenderBlankState(true),
-		)
-
-		// Create speed measurement setup
-		// Use API key from environment variables for security
-		apiKey := getAPIKeyForModel(request.Model1)
-		if apiKey == "" {
-			AppLogger.ErrorWithContext(&LogContext{JobID: jobID, Model: request.Model1.Name}, "No API key found for model")
-			jm.FailJob(jobID, fmt.Sprintf("No API key found for model %s", request.Model1.Name))
-			return
-		}
+	// Describe the measurement model, optionally with a network round-trip estimate
+	plan.Measurement = jm.describeMeasurement(ctx, jobID, request.Model1.BaseURL, request.EstimateRTT)
 
-		// Create speed measurement setup
-		setup := utils.SpeedMeasurement{
-			BaseUrl:        request.Model1.BaseURL,
-			ApiKey:         apiKey,
-			ModelName:      request.Model1.Name,
-			Prompt:         request.Prompt,
-			UseRandomInput: false, // We're using custom prompt
-			NumWords:       request.NumWords,
-			MaxTokens:      request.MaxTokens,
-			Latency:        latency,
-			Concurrency:    concurrency,
-		}
-
-		// Run the benchmark
-		AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Running benchmark for concurrency %d...", concurrency)
-		result, err := setup.Run(ctx, bar)
-		if err != nil {
-			AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Benchmark failed for concurrency %d: %v", concurrency, err)
-			jm.FailJob(jobID, fmt.Sprintf("Benchmark failed for concurrency %d: %v", concurrency, err))
-			bar.Close()
-			return
-		}
+	live, stopLive := jm.startLiveEvents(jobID, request.Repetitions)
+	defer stopLive()
+	progress := jm.progressAdapter(jobID)
+	executor := &Executor{Trace: jm.traceSink(jobID), ProgressOutput: os.Stderr, Control: jm.executionControl(jobID)}
+	executor.Execute(ctx, plan, jm.executionEmitter(func(event ExecutionEvent) {
+		live(event)
+		progress(event)
+	}))
+}
 
-		// Store result for Model 1
-		concurrencyResult := ConcurrencyResult{
-			Concurrency:          concurrency,
-			GenerationThroughput: result.GenerationSpeed,
-			PromptThroughput:     result.PromptThroughput,
-			MinTTFT:              result.MinTtft,
-			MaxTTFT:              result.MaxTtft,
-		}
-		model1Results = append(model1Results, concurrencyResult)
-
-			AppLogger.InfoWithFields("Model 1 concurrency completed", map[string]interface{}{
-				"jobId": jobID,
-				"concurrency": concurrency,
-				"generationSpeed": result.GenerationSpeed,
-				"promptThroughput": result.PromptThroughput,
-				"minTtft": result.MinTtft,
-				"maxTtft": result.MaxTtft,
-			})
-	}
+// ObserveExecutions registers an observer for the Executor events of every benchmark job
+func (jm *SimpleJobManager) ObserveExecutions(observer func(ExecutionEvent)) {
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+	jm.executionObservers = append(jm.executionObservers, observer)
+}
 
-	// Handle Model 2 if provided
-	if request.Model2 != nil {
-		for i, concurrency := range request.ConcurrencyLevels {
-			// Check for cancellation before each Model 2 concurrency level
-			select {
-			case <-ctx.Done():
-				AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Job cancelled during Model 2 concurrency %d", concurrency)
+// executionEmitter sends Executor events to adapter, then to the registered observers
+func (jm *SimpleJobManager) executionEmitter(adapter func(ExecutionEvent)) func(ExecutionEvent) {
+	jm.mutex.RLock()
+	observers := append([]func(ExecutionEvent){}, jm.executionObservers...)
+	jm.mutex.RUnlock()
+	return func(event ExecutionEvent) {
+		if event.Type == EventPlanChanged || event.Type == EventLevelSkipped {
+			jm.recordControlEvent(event)
+		}
+		if adapter != nil {
+			adapter(event)
+		}
+		for _, observer := range observers {
+			observer(event)
+		}
+	}
+}
+
+// progressAdapter maps Executor events onto the coarse job progress sent over SSE
+func (jm *SimpleJobManager) progressAdapter(jobID string) func(ExecutionEvent) {
+	var started []BenchmarkStep // Steps of the group that is starting
+	return func(event ExecutionEvent) {
+		if event.Type != EventLevelStarted {
+			started = nil
+		}
+		switch event.Type {
+		case EventLevelStarted:
+			started = append(started, *event.Step)
+			progress := 30 + (event.Completed * 60 / event.Total)
+			message := describeStepGroup(started)
+			AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Updating progress: %d%% - %s", progress, message)
+			jm.UpdateJobProgress(jobID, progress, message)
+		case EventJobDone:
+			switch {
+			case event.Output != nil && event.Err != nil:
+				jm.PartialJob(jobID, event.Output, fmt.Sprintf("Benchmark failed for %v", event.Err))
+			case event.Cancelled():
 				jm.FailJob(jobID, "Job cancelled by user")
-				return
+			case event.Err != nil:
+				jm.FailJob(jobID, fmt.Sprintf("Benchmark failed for %v", event.Err))
 			default:
+				jm.UpdateJobProgress(jobID, 100, "Benchmark completed")
+				jm.CompleteJob(jobID, event.Output.JobResult())
+				AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Benchmark job completed successfully")
 			}
-			
-			progress := 30 + ((len(request.ConcurrencyLevels) + i) * 60 / totalSteps)
-			AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Updating progress: %d%% - Testing Model 2 concurrency %d...", progress, concurrency)
-			jm.UpdateJobProgress(jobID, progress, fmt.Sprintf("Testing Model 2 concurrency %d...", concurrency))
-
-			// Create progress bar for this concurrency level
-			expectedTokens := concurrency * request.MaxTokens
-			bar := progressbar.NewOptions(expectedTokens,
-				progressbar.OptionSetWriter(os.Stderr),
-				progressbar.OptionSetDescription(fmt.Sprintf("Model2 Concurrency %d", concurrency)),
-				progressbar.OptionSetWidth(40),
-				progressbar.OptionShowCount(),
-				progressbar.OptionShowIts(),
-				progressbar.OptionSetItsString("tokens"),
-				progressbar.OptionSpinnerType(14),
-				progressbar.OptionSetRenderBlankState(true),
-			)
-
-			// Get API key for Model 2
-			apiKey := getAPIKeyForModel(*request.Model2)
-			if apiKey == "" {
-				AppLogger.ErrorWithContext(&LogContext{JobID: jobID, Model: request.Model2.Name}, "No API key found for model")
-				jm.FailJob(jobID, fmt.Sprintf("No API key found for model %s", request.Model2.Name))
-				return
-			}
-
-			// Create speed measurement setup for Model 2
-			setup := utils.SpeedMeasurement{
-				BaseUrl:        request.Model2.BaseURL,
-				ApiKey:         apiKey,
-				ModelName:      request.Model2.Name,
-				Prompt:         request.Prompt,
-				UseRandomInput: false, // We're using custom prompt
-				NumWords:       request.NumWords,
-				MaxTokens:      request.MaxTokens,
-				Latency:        latency,
-				Concurrency:    concurrency,
-			}
-
-			// Run speed measurement for Model 2
-			result, err := setup.Run(ctx, bar)
-			if err != nil {
-				AppLogger.ErrorWithContext(&LogContext{JobID: jobID}, "Benchmark failed for Model 2 concurrency %d: %v", concurrency, err)
-				jm.FailJob(jobID, fmt.Sprintf("Benchmark failed for Model 2 concurrency %d: %v", concurrency, err))
-				bar.Close()
-				return
-			}
-
-			// Store result for Model 2
-			concurrencyResult := ConcurrencyResult{
-				Concurrency:          concurrency,
-				GenerationThroughput: result.GenerationSpeed,
-				PromptThroughput:     result.PromptThroughput,
-				MinTTFT:              result.MinTtft,
-				MaxTTFT:              result.MaxTtft,
-			}
-			model2Results = append(model2Results, concurrencyResult)
-
-			AppLogger.InfoWithFields("Model 2 concurrency completed", map[string]interface{}{
-				"jobId": jobID,
-				"concurrency": concurrency,
-				"generationSpeed": result.GenerationSpeed,
-				"promptThroughput": result.PromptThroughput,
-				"minTtft": result.MinTtft,
-				"maxTtft": result.MaxTtft,
-			})
 		}
 	}
+}
 
-	// Complete the job
-	AppLogger.DebugWithContext(&LogContext{JobID: jobID}, "Updating progress: 100%% - Benchmark completed")
-	jm.UpdateJobProgress(jobID, 100, "Benchmark completed")
-	
-	// Create final result with proper structure
-	finalResult := map[string]interface{}{
-		"model1": map[string]interface{}{
-			"model":   request.Model1.Name,
-			"results": model1Results,
-		},
-		"model2": nil,
-		"latency": latency,
-		"summary": map[string]interface{}{
-			"total_concurrency_levels": len(request.ConcurrencyLevels),
-			"total_results": len(model1Results) + len(model2Results),
-		},
+// newConcurrencyResult converts a measurement of a model into the API result shape
+func newConcurrencyResult(model string, result utils.SpeedResult) ConcurrencyResult {
+	concurrencyResult := ConcurrencyResult{
+		Concurrency:                 result.Concurrency,
+		GenerationThroughput:        result.GenerationSpeed,
+		PromptThroughput:            result.PromptThroughput,
+		MinTTFT:                     result.MinTtft,
+		MaxTTFT:                     result.MaxTtft,
+		P95TTFT:                     result.P95Ttft,
+		PerUserThroughput:           result.PerUserSpeed,
+		PerUserThroughputP50:        result.PerUserSpeedP50,
+		PerUserThroughputP90:        result.PerUserSpeedP90,
+		PerUserThroughputP99:        result.PerUserSpeedP99,
+		RequestsPerSecond:           result.RequestsPerSecond,
+		GoodRequests:                result.GoodRequests,
+		GoodputRatio:                result.GoodputRatio,
+		GoodputRate:                 result.GoodputRate,
+		Requests:                    result.Requests,
+		FailedRequests:              result.FailedRequests,
+		ErrorRate:                   result.ErrorRate,
+		PromptTokens:                result.PromptTokens,
+		CompletionTokens:            result.CompletionTokens,
+		DurationSeconds:             result.DurationSeconds,
+		LimitedRequests:             result.LimitedRequests,
+		LimiterWaitMean:             result.LimiterWaitMean,
+		LimiterWaitMax:              result.LimiterWaitMax,
+		ErrorClasses:                result.ErrorClasses,
+		TTFTBreakdown:               newTTFTBreakdown(result.TtftBreakdown),
+		Runs:                        result.Runs,
+		GenerationThroughputStats:   result.GenerationSpeedStats,
+		PromptThroughputStats:       result.PromptThroughputStats,
+		GenerationThroughputSamples: result.GenerationSpeedSamples,
+		OutlierRuns:                 result.OutlierRuns,
+	}
+	concurrencyResult.priceLevel(model)
+	return concurrencyResult
+}
+
+// newTTFTBreakdown converts a measured TTFT breakdown into the API shape
+func newTTFTBreakdown(breakdown *utils.TtftBreakdown) *TTFTBreakdown {
+	if breakdown == nil {
+		return nil
 	}
-	
-	// Add Model 2 results if available
-	if request.Model2 != nil {
-		finalResult["model2"] = map[string]interface{}{
-			"model":   request.Model2.Name,
-			"results": model2Results,
-		}
+	return &TTFTBreakdown{
+		DNSMs:             breakdown.DnsMs,
+		ConnectMs:         breakdown.ConnectMs,
+		TLSMs:             breakdown.TlsMs,
+		HeadersMs:         breakdown.HeadersMs,
+		FirstChunkMs:      breakdown.FirstChunkMs,
+		FirstTokenMs:      breakdown.FirstTokenMs,
+		NewConnections:    breakdown.NewConnections,
+		ReusedConnections: breakdown.ReusedConnections,
 	}
+}
+
+// describeMeasurement returns the measurement model for a job result. When requested it
+// adds a network round-trip estimate; failing to get one only logs a warning.
+func (jm *SimpleJobManager) describeMeasurement(ctx context.Context, jobID string, baseURL string, estimateRTT bool) utils.MeasurementModel {
+	measurement := utils.DescribeMeasurement()
+	if !estimateRTT {
+		return measurement
+	}
+
+	jm.UpdateJobProgress(jobID, 20, "Estimating network round-trip time...")
+	rtt, err := utils.EstimateNetworkRTT(ctx, baseURL, 5)
+	if err != nil {
+		AppLogger.WarnWithContext(&LogContext{JobID: jobID}, "Network RTT estimate failed: %v", err)
+		return measurement
+	}
+	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Network RTT estimate: %.2f ms", rtt)
+	measurement.NetworkRttMs = rtt
+	return measurement
+}
 
-	jm.CompleteJob(jobID, finalResult)
-	AppLogger.InfoWithContext(&LogContext{JobID: jobID}, "Benchmark job completed successfully")
+// describeStepGroup builds the progress message for a group of simultaneous steps
+func describeStepGroup(group []BenchmarkStep) string {
+	if len(group) == 1 {
+		return fmt.Sprintf("Testing Model %d concurrency %d...", group[0].ModelIndex, group[0].Concurrency)
+	}
+	return fmt.Sprintf("Testing Model 1 and Model 2 concurrency %d in parallel...", group[0].Concurrency)
 }
 
 // broadcastSystemStatus sends system status to all listeners
 func (jm *SimpleJobManager) broadcastSystemStatus() {
+	status := jm.GetSystemStatus() // Takes the read lock itself; nesting it deadlocks with a waiting writer
 	jm.mutex.RLock()
-	status := jm.GetSystemStatus()
 	listeners := make([]chan map[string]interface{}, len(jm.systemStatusListeners))
 	copy(listeners, jm.systemStatusListeners)
 	jm.mutex.RUnlock()
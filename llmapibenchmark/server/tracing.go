@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"llmapibenchmark/internal/tracing"
+)
+
+// startJobSpan opens the root span of a job. Concurrency levels and LLM calls made with
+// the returned context become its descendants.
+func startJobSpan(ctx context.Context, jobID string, jobType string) (context.Context, *tracing.Span) {
+	return tracing.Start(ctx, jobType+" job",
+		tracing.String("job.id", jobID),
+		tracing.String("job.type", jobType))
+}
+
+// endJobSpan records the final status of a job on its span and ends it
+func (jm *SimpleJobManager) endJobSpan(jobID string, span *tracing.Span) {
+	if span == nil {
+		return
+	}
+	jm.mutex.RLock()
+	job, exists := jm.jobs[jobID]
+	var status, jobError string
+	if exists {
+		status, jobError = job.Status, job.Error
+	}
+	jm.mutex.RUnlock()
+
+	span.SetAttributes(tracing.String("job.status", status))
+	if jobError != "" {
+		span.RecordError(errors.New(jobError))
+	}
+	span.End()
+}
+
+// startModelSpans opens one span per benchmarked model below the job span. The returned
+// contexts are indexed by BenchmarkStep.ModelIndex; end closes the spans.
+func startModelSpans(ctx context.Context, models map[int]*Model) (contexts map[int]context.Context, end func()) {
+	contexts = make(map[int]context.Context, len(models))
+	spans := make([]*tracing.Span, 0, len(models))
+	for index, model := range models {
+		if model == nil {
+			continue
+		}
+		modelCtx, span := tracing.Start(ctx, "model",
+			tracing.String("gen_ai.request.model", model.Name),
+			tracing.String("llm.model_id", model.ID),
+			tracing.Int("llm.model_index", index))
+		contexts[index] = modelCtx
+		spans = append(spans, span)
+	}
+	return contexts, func() {
+		for _, span := range spans {
+			span.End()
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"llmapibenchmark/internal/api"
+)
+
+// transportConfigFromEnv reads the HTTP transport settings shared by all models:
+//
+//	INSECURE_SKIP_TLS_VERIFY       skip certificate verification ("true"/"false")
+//	HTTP_MAX_IDLE_CONNS_PER_HOST   idle connections kept per host (default 256)
+//	HTTP_VERSION                   "1.1" or "2" to force a protocol
+//	PROXY_URL                      proxy for model calls (default HTTP_PROXY/HTTPS_PROXY)
+//	CA_BUNDLE_FILE                 extra PEM CA bundle
+//	CLIENT_CERT_FILE/CLIENT_KEY_FILE  mTLS client certificate
+//	HTTP_CONNECT_TIMEOUT, HTTP_TLS_HANDSHAKE_TIMEOUT, HTTP_RESPONSE_HEADER_TIMEOUT  durations such as "10s"
+func transportConfigFromEnv() (api.TransportConfig, error) {
+	var config api.TransportConfig
+	if err := applyTransportEnv(&config, ""); err != nil {
+		return config, err
+	}
+	return config, config.Validate()
+}
+
+// transportConfigForModel returns the transport settings for a model. Models configured
+// through MODEL1_* or MODEL2_* can override any shared setting with the same prefix,
+// e.g. MODEL2_CA_BUNDLE_FILE or MODEL1_HTTP_VERSION.
+func transportConfigForModel(model Model) (api.TransportConfig, error) {
+	var config api.TransportConfig
+	if err := applyTransportEnv(&config, ""); err != nil {
+		return config, err
+	}
+	if prefix := modelEnvPrefix(model); prefix != "" {
+		if err := applyTransportEnv(&config, prefix); err != nil {
+			return config, err
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return config, fmt.Errorf("transport settings for model %s: %w", model.Name, err)
+	}
+	return config, nil
+}
+
+// modelEnvPrefix returns "MODEL1_" or "MODEL2_" when the model comes from that
+// environment configuration, and "" otherwise
+func modelEnvPrefix(model Model) string {
+	for _, prefix := range []string{"MODEL1_", "MODEL2_"} {
+		serviceID := strings.ToLower(strings.TrimSuffix(prefix, "_"))
+		if strings.HasPrefix(model.ID, serviceID+"|") {
+			return prefix
+		}
+		if name := os.Getenv(prefix + "NAME"); name != "" && name == model.Name {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// applyTransportEnv overwrites the settings whose prefixed variables are set
+func applyTransportEnv(config *api.TransportConfig, prefix string) error {
+	if value := os.Getenv(prefix + "INSECURE_SKIP_TLS_VERIFY"); value != "" {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %sINSECURE_SKIP_TLS_VERIFY: %w", prefix, err)
+		}
+		config.InsecureSkipTLSVerify = insecure
+	}
+	if value := os.Getenv(prefix + "HTTP_MAX_IDLE_CONNS_PER_HOST"); value != "" {
+		conns, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %sHTTP_MAX_IDLE_CONNS_PER_HOST: %w", prefix, err)
+		}
+		config.MaxIdleConnsPerHost = conns
+	}
+	if value := os.Getenv(prefix + "HTTP_VERSION"); value != "" {
+		config.HTTPVersion = value
+	}
+	if value := os.Getenv(prefix + "PROXY_URL"); value != "" {
+		config.ProxyURL = value
+	}
+	if value := os.Getenv(prefix + "CA_BUNDLE_FILE"); value != "" {
+		config.CAFile = value
+	}
+	if value := os.Getenv(prefix + "CLIENT_CERT_FILE"); value != "" {
+		config.ClientCertFile = value
+	}
+	if value := os.Getenv(prefix + "CLIENT_KEY_FILE"); value != "" {
+		config.ClientKeyFile = value
+	}
+
+	durations := map[string]*time.Duration{
+		"HTTP_CONNECT_TIMEOUT":         &config.DialTimeout,
+		"HTTP_TLS_HANDSHAKE_TIMEOUT":   &config.TLSHandshakeTimeout,
+		"HTTP_RESPONSE_HEADER_TIMEOUT": &config.ResponseHeaderTimeout,
+	}
+	for name, target := range durations {
+		value := os.Getenv(prefix + name)
+		if value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s%s: %w", prefix, name, err)
+		}
+		*target = duration
+	}
+	return nil
+}
+
+// newServiceHTTPClient returns a client for service discovery calls that honours the
+// shared transport settings, falling back to the default client when they are invalid
+func newServiceHTTPClient() *http.Client {
+	config, err := transportConfigFromEnv()
+	if err != nil {
+		AppLogger.Warn("Ignoring invalid HTTP transport settings for service discovery: %v", err)
+		return &http.Client{}
+	}
+	transport, err := api.SharedTransports.Transport(config)
+	if err != nil {
+		AppLogger.Warn("Ignoring invalid HTTP transport settings for service discovery: %v", err)
+		return &http.Client{}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// maxRequestTimeoutSeconds caps every per-request deadline
+const maxRequestTimeoutSeconds = 3600
+
+// validate checks that the deadlines are within range
+func (timeouts RequestTimeouts) validate() error {
+	values := map[string]float64{
+		"connectTimeoutSeconds": timeouts.ConnectTimeoutSeconds,
+		"ttftTimeoutSeconds":    timeouts.TTFTTimeoutSeconds,
+		"idleTimeoutSeconds":    timeouts.IdleTimeoutSeconds,
+		"requestTimeoutSeconds": timeouts.RequestTimeoutSeconds,
+	}
+	for name, value := range values {
+		if value < 0 || value > maxRequestTimeoutSeconds {
+			return fmt.Errorf("%s must be between 0 and %d, got %g", name, maxRequestTimeoutSeconds, value)
+		}
+	}
+	return nil
+}
+
+// requestTimeouts converts the deadlines for a measurement, using the defaults for omitted values
+func (timeouts RequestTimeouts) requestTimeouts() api.RequestTimeouts {
+	result := api.DefaultRequestTimeouts
+	seconds := func(value float64) time.Duration {
+		return time.Duration(value * float64(time.Second))
+	}
+	if timeouts.ConnectTimeoutSeconds > 0 {
+		result.Connect = seconds(timeouts.ConnectTimeoutSeconds)
+	}
+	if timeouts.TTFTTimeoutSeconds > 0 {
+		result.FirstToken = seconds(timeouts.TTFTTimeoutSeconds)
+	}
+	if timeouts.IdleTimeoutSeconds > 0 {
+		result.Idle = seconds(timeouts.IdleTimeoutSeconds)
+	}
+	if timeouts.RequestTimeoutSeconds > 0 {
+		result.Total = seconds(timeouts.RequestTimeoutSeconds)
+	}
+	return result
+}
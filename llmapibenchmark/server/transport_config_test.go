@@ -0,0 +1,59 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"llmapibenchmark/internal/api"
+)
+
+func TestTransportConfigForModel_SharedAndPerModelSettings(t *testing.T) {
+	t.Setenv("INSECURE_SKIP_TLS_VERIFY", "true")
+	t.Setenv("HTTP_MAX_IDLE_CONNS_PER_HOST", "64")
+	t.Setenv("HTTP_RESPONSE_HEADER_TIMEOUT", "30s")
+	t.Setenv("MODEL2_NAME", "llama")
+	t.Setenv("MODEL2_INSECURE_SKIP_TLS_VERIFY", "false")
+	t.Setenv("MODEL2_HTTP_VERSION", "1.1")
+
+	shared, err := transportConfigForModel(Model{ID: "generic|gpt-4", Name: "gpt-4"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := api.TransportConfig{
+		InsecureSkipTLSVerify: true,
+		MaxIdleConnsPerHost:   64,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+	if shared != expected {
+		t.Errorf("Expected %+v, got %+v", expected, shared)
+	}
+
+	// MODEL2_* overrides apply to the model matched by name or by service ID
+	for _, model := range []Model{{Name: "llama"}, {ID: "model2|llama", Name: "model2|llama"}} {
+		config, err := transportConfigForModel(model)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if config.InsecureSkipTLSVerify || config.HTTPVersion != api.HTTPVersion1 || config.MaxIdleConnsPerHost != 64 {
+			t.Errorf("Expected MODEL2 overrides on shared settings for %+v, got %+v", model, config)
+		}
+	}
+}
+
+func TestTransportConfigForModel_InvalidSettings(t *testing.T) {
+	testCases := map[string]string{
+		"INSECURE_SKIP_TLS_VERIFY":     "sometimes",
+		"HTTP_MAX_IDLE_CONNS_PER_HOST": "many",
+		"HTTP_VERSION":                 "3",
+		"HTTP_CONNECT_TIMEOUT":         "10",
+		"CLIENT_CERT_FILE":             "/tmp/cert.pem", // Key missing
+	}
+	for name, value := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := transportConfigForModel(Model{Name: "gpt-4"}); err == nil {
+				t.Errorf("Expected an error for %s=%s", name, value)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package server
 
 import (
 	"time"
+
+	"llmapibenchmark/internal/utils"
 )
 
 // Model represents a configured LLM model
@@ -10,17 +12,45 @@ type Model struct {
 	Name     string `json:"name"`
 	Provider string `json:"provider"`
 	BaseURL  string `json:"baseUrl"`
-	APIKey   string `json:"apiKey,omitempty"` // Omit from JSON for security
+	SecretID string `json:"secretId,omitempty"` // Names the credential, see resolveCredential
+
+	rawAPIKey bool // The request carried an apiKey, which is rejected
 }
 
 // BenchmarkRequest represents the request payload for running benchmarks
 type BenchmarkRequest struct {
-	Model1            Model  `json:"model1" binding:"required"`
-	Model2            *Model `json:"model2"` // Optional - can benchmark single model
-	ConcurrencyLevels []int  `json:"concurrencyLevels" binding:"required,min=1"`
-	MaxTokens         int    `json:"maxTokens" binding:"required,min=1,max=4096"`
-	Prompt            string `json:"prompt" binding:"required,min=1"`
-	NumWords          int    `json:"numWords,omitempty"` // For random prompt generation
+	Model1             Model   `json:"model1" binding:"required"`
+	Model2             *Model  `json:"model2"` // Optional - can benchmark single model
+	ConcurrencyLevels  []int   `json:"concurrencyLevels" binding:"required,min=1"`
+	MaxTokens          int     `json:"maxTokens" binding:"required,min=1,max=4096"`
+	Prompt             string  `json:"prompt" binding:"required,min=1"`
+	NumWords           int     `json:"numWords,omitempty"`           // For random prompt generation
+	Schedule           string  `json:"schedule,omitempty"`           // "sequential" (default), "interleaved", "random" or "parallel"
+	ScheduleSeed       int64   `json:"scheduleSeed,omitempty"`       // Optional seed for the random schedule
+	Repetitions        int     `json:"repetitions,omitempty"`        // Runs per concurrency level (default 1)
+	CooldownSeconds    float64 `json:"cooldownSeconds,omitempty"`    // Pause between repeated runs
+	GoodputTTFTSeconds float64 `json:"goodputTtftSeconds,omitempty"` // Per-request TTFT target for goodput
+	GoodputTPOTSeconds float64 `json:"goodputTpotSeconds,omitempty"` // Per-request time-per-output-token target for goodput
+	EstimateRTT        bool    `json:"estimateRtt,omitempty"`        // Add a network round-trip estimate to the metadata
+	Priority           int     `json:"priority,omitempty"`           // Higher priorities leave the queue first (-100 to 100)
+	RequestTimeouts
+	JobCallback
+}
+
+// JobCallback asks for a signed POST when the job completes, fails or is cancelled,
+// in addition to the server-wide JOB_WEBHOOK_URL.
+type JobCallback struct {
+	CallbackURL    string `json:"callbackUrl,omitempty"`
+	CallbackFormat string `json:"callbackFormat,omitempty"` // "json" (default) or "slack"
+}
+
+// RequestTimeouts are the per-request deadlines of a job in seconds. Omitted values use
+// the defaults; requests that hit a deadline count as failed with a "<phase>_timeout" class.
+type RequestTimeouts struct {
+	ConnectTimeoutSeconds float64 `json:"connectTimeoutSeconds,omitempty"` // Getting a connection (default 30)
+	TTFTTimeoutSeconds    float64 `json:"ttftTimeoutSeconds,omitempty"`    // Sending to first token (default 300)
+	IdleTimeoutSeconds    float64 `json:"idleTimeoutSeconds,omitempty"`    // Gap between stream chunks (default 120)
+	RequestTimeoutSeconds float64 `json:"requestTimeoutSeconds,omitempty"` // Whole request (default none)
 }
 
 // ConcurrencyResult represents the result for a single concurrency level
@@ -30,19 +60,123 @@ type ConcurrencyResult struct {
 	PromptThroughput     float64 `json:"promptThroughput"`
 	MinTTFT              float64 `json:"minTtft"`
 	MaxTTFT              float64 `json:"maxTtft"`
+	P95TTFT              float64 `json:"p95Ttft"`
+	PerUserThroughput    float64 `json:"perUserThroughput"` // Mean decode tokens/s of a single request
+	PerUserThroughputP50 float64 `json:"perUserThroughputP50"`
+	PerUserThroughputP90 float64 `json:"perUserThroughputP90"`
+	PerUserThroughputP99 float64 `json:"perUserThroughputP99"`
+	RequestsPerSecond    float64 `json:"requestsPerSecond"`
+	GoodRequests         int     `json:"goodRequests"` // Requests meeting the goodput targets
+	GoodputRatio         float64 `json:"goodputRatio"` // GoodRequests / Requests
+	GoodputRate          float64 `json:"goodputRate"`  // GoodRequests per second
+	Requests             int     `json:"requests"`
+	FailedRequests       int     `json:"failedRequests"`
+	ErrorRate            float64 `json:"errorRate"`
+	PromptTokens         int     `json:"promptTokens"`              // Of the completed requests
+	CompletionTokens     int     `json:"completionTokens"`          // Of the completed requests
+	DurationSeconds      float64 `json:"durationSeconds"`           // Measurement window, summed over runs
+	LimitedRequests      int     `json:"limitedRequests,omitempty"` // Requests that waited for the rate limiter
+	LimiterWaitMean      float64 `json:"limiterWaitMean,omitempty"` // Seconds before sending, not part of TTFT
+	LimiterWaitMax       float64 `json:"limiterWaitMax,omitempty"`
+
+	Cost          *CostEfficiency `json:"cost,omitempty"`         // Set when the model has a price, see pricing.go
+	ErrorClasses  map[string]int  `json:"errorClasses,omitempty"` // Failed requests by cause, e.g. "ttft_timeout" or "http_429"
+	TTFTBreakdown *TTFTBreakdown  `json:"ttftBreakdown,omitempty"`
+
+	// Populated only when the level was repeated
+	Runs                        int             `json:"runs,omitempty"`
+	GenerationThroughputStats   *utils.RunStats `json:"generationThroughputStats,omitempty"`
+	PromptThroughputStats       *utils.RunStats `json:"promptThroughputStats,omitempty"`
+	GenerationThroughputSamples []float64       `json:"generationThroughputSamples,omitempty"`
+	OutlierRuns                 []int           `json:"outlierRuns,omitempty"` // Indexes into GenerationThroughputSamples
+}
+
+// TTFTBreakdown splits the time to first token into mean per-request phases in milliseconds.
+// DNS, connect and TLS are averaged over new connections and happen before the TTFT clock starts.
+type TTFTBreakdown struct {
+	DNSMs             float64 `json:"dnsMs"`
+	ConnectMs         float64 `json:"connectMs"`
+	TLSMs             float64 `json:"tlsMs"`
+	HeadersMs         float64 `json:"headersMs"`    // Request sent to first response header byte
+	FirstChunkMs      float64 `json:"firstChunkMs"` // Headers to first stream chunk
+	FirstTokenMs      float64 `json:"firstTokenMs"` // First chunk to first non-blank content
+	NewConnections    int     `json:"newConnections"`
+	ReusedConnections int     `json:"reusedConnections"`
+}
+
+// SearchRequest represents the request payload for a saturation search job
+type SearchRequest struct {
+	Model            Model     `json:"model" binding:"required"`
+	MaxTokens        int       `json:"maxTokens" binding:"required,min=1,max=4096"`
+	Prompt           string    `json:"prompt" binding:"required,min=1"`
+	NumWords         int       `json:"numWords,omitempty"`
+	StartConcurrency int       `json:"startConcurrency,omitempty"` // Default 1
+	MaxConcurrency   int       `json:"maxConcurrency,omitempty"`   // Default and upper bound 100
+	SLO              SearchSLO `json:"slo"`
+	EstimateRTT      bool      `json:"estimateRtt,omitempty"`
+	Priority         int       `json:"priority,omitempty"`
+	RequestTimeouts
+	JobCallback
+}
+
+// SearchSLO holds the objectives a concurrency level must meet during a search
+type SearchSLO struct {
+	MaxP95TTFTSeconds         float64  `json:"maxP95TtftSeconds,omitempty"`
+	MinPerUserTokensPerSecond float64  `json:"minPerUserTokensPerSecond,omitempty"`
+	MaxErrorRate              *float64 `json:"maxErrorRate,omitempty"` // Fraction of requests, default 0.01
+}
+
+// SearchStep is one measured concurrency level of a saturation search
+type SearchStep struct {
+	Phase       string            `json:"phase"` // "ramp" or "bisect"
+	Concurrency int               `json:"concurrency"`
+	Passed      bool              `json:"passed"`
+	Violations  []string          `json:"violations,omitempty"`
+	Result      ConcurrencyResult `json:"result"`
+}
+
+// SearchResult represents the outcome of a saturation search job
+type SearchResult struct {
+	Model                     string             `json:"model"`
+	MaxSustainableConcurrency int                `json:"maxSustainableConcurrency"` // 0 when no level met the SLO
+	Goodput                   float64            `json:"goodput"`                   // Generation tokens/s at the max sustainable level
+	LimitReached              bool               `json:"limitReached"`              // Stopped at maxConcurrency without a failing level
+	Best                      *ConcurrencyResult `json:"best,omitempty"`
+	Steps                     []SearchStep       `json:"steps"`
+	Cost                      *CostEfficiency    `json:"cost,omitempty"` // Of all steps, when the model has a price
+}
+
+// JobMetadata records how a job was scheduled and measured so results can be audited
+type JobMetadata struct {
+	*ScheduleMetadata                        // Benchmark jobs only
+	Measurement       utils.MeasurementModel `json:"measurement"`
 }
 
 // BenchmarkResult represents the result of a single model benchmark
 type BenchmarkResult struct {
-	Model                string              `json:"model"`
-	Results              []ConcurrencyResult `json:"results"`
-	Timestamp            time.Time           `json:"timestamp"`
+	Model     string              `json:"model"`
+	Results   []ConcurrencyResult `json:"results"`
+	Timestamp time.Time           `json:"timestamp"`
+	Cost      *CostEfficiency     `json:"cost,omitempty"` // Of all levels, when the model has a price
 }
 
 // Comparison represents the comparison between two models
 type Comparison struct {
 	Winner      string             `json:"winner"` // "model1", "model2", or "tie"
 	Differences map[string]float64 `json:"differences"`
+	Method      string             `json:"method"`                // "threshold" (5% rule) or "welch-t-test" when repeated samples exist
+	Significant *bool              `json:"significant,omitempty"` // Any level differs significantly, set only when Method is "welch-t-test"
+	Levels      []LevelComparison  `json:"levels,omitempty"`      // Per-level tests, set only when Method is "welch-t-test"
+	// Model with more output tokens per unit of cost (5% rule), set when both models have a price
+	CostEfficiencyWinner string `json:"costEfficiencyWinner,omitempty"`
+}
+
+// LevelComparison is the Welch t-test of one concurrency level that both models repeated
+type LevelComparison struct {
+	Concurrency int     `json:"concurrency"`
+	Difference  float64 `json:"difference"` // Generation throughput of model1 over model2 in percent
+	PValue      float64 `json:"pValue"`     // Holm-adjusted over all tested levels
+	Significant bool    `json:"significant"`
 }
 
 // ComparisonResponse represents the full benchmark comparison response
@@ -64,4 +198,3 @@ type ModelsResponse struct {
 	Models []Model `json:"models"`
 	Count  int     `json:"count"`
 }
-
@@ -1,8 +1,13 @@
 package server
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,15 +17,31 @@ import (
 
 // WebSocket upgrader configuration
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow connections from any origin in development
-		// In production, you should validate the origin
-		return true
-	},
+	CheckOrigin:     checkWebSocketOrigin,
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 }
 
+// checkWebSocketOrigin accepts clients without an Origin header, the server's own origin
+// and the origins in WS_ALLOWED_ORIGINS (comma-separated, "*" allows any origin)
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || (allowed != "" && strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin)) {
+			return true
+		}
+	}
+	AppLogger.Warn("Rejected WebSocket connection from origin %s", origin)
+	return false
+}
+
 // Client represents a WebSocket client connection
 type Client struct {
 	ID       string
@@ -28,6 +49,13 @@ type Client struct {
 	Send     chan []byte
 	Hub      *Hub
 	LastPing time.Time
+
+	jobManager    *SimpleJobManager
+	principal     *Principal    // Caller that opened the connection, see RequireRole
+	done          chan struct{} // Closed when the client is gone
+	closeOnce     sync.Once
+	mutex         sync.Mutex
+	subscriptions map[string]context.CancelFunc // By topic key, see subscriptionKey
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them
@@ -52,7 +80,7 @@ type Hub struct {
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan []byte, 256), // Buffered so that bursts of progress are not dropped
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
@@ -70,40 +98,41 @@ func (h *Hub) Run() {
 			h.mutex.Lock()
 			h.clients[client] = true
 			h.mutex.Unlock()
-			log.Printf("WebSocket client connected: %s (total clients: %d)", client.ID, len(h.clients))
+			AppLogger.Info("WebSocket client connected: %s (total clients: %d)", client.ID, h.GetClientCount())
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.Send)
+				client.close()
 			}
 			h.mutex.Unlock()
-			log.Printf("WebSocket client disconnected: %s (total clients: %d)", client.ID, len(h.clients))
+			AppLogger.Info("WebSocket client disconnected: %s (total clients: %d)", client.ID, h.GetClientCount())
 
 		case message := <-h.broadcast:
-			h.mutex.RLock()
+			h.mutex.Lock()
 			for client := range h.clients {
 				select {
 				case client.Send <- message:
 				default:
-					close(client.Send)
+					client.close()
 					delete(h.clients, client)
 				}
 			}
-			h.mutex.RUnlock()
+			h.mutex.Unlock()
 
 		case <-pingTicker.C:
-			h.mutex.RLock()
+			h.mutex.Lock()
 			for client := range h.clients {
 				// Check if client is still alive
 				if time.Since(client.LastPing) > 60*time.Second {
-					log.Printf("WebSocket client %s timed out, removing", client.ID)
+					AppLogger.Warn("WebSocket client %s timed out, removing", client.ID)
+					client.close()
 					client.Conn.Close()
 					delete(h.clients, client)
 				}
 			}
-			h.mutex.RUnlock()
+			h.mutex.Unlock()
 		}
 	}
 }
@@ -113,7 +142,7 @@ func (h *Hub) BroadcastMessage(message []byte) {
 	select {
 	case h.broadcast <- message:
 	default:
-		log.Println("WebSocket broadcast channel full, dropping message")
+		AppLogger.Warn("WebSocket broadcast channel full, dropping message")
 	}
 }
 
@@ -124,9 +153,15 @@ func (h *Hub) GetClientCount() int {
 	return len(h.clients)
 }
 
-// readPump pumps messages from the WebSocket connection to the hub
+// close stops the client's writer; subscriptions end with the reader
+func (c *Client) close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// readPump reads the client's control messages until the connection closes
 func (c *Client) readPump() {
 	defer func() {
+		c.unsubscribeAll()
 		c.Hub.unregister <- c
 		c.Conn.Close()
 	}()
@@ -140,17 +175,220 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, _, err := c.Conn.ReadMessage()
+		_, data, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				AppLogger.Warn("WebSocket error: %v", err)
 			}
 			break
 		}
+		var message ClientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			c.reply(ClientMessage{}, errors.New("invalid message: "+err.Error()))
+			continue
+		}
+		c.reply(message, c.handle(message))
+	}
+}
+
+// handle performs a client action
+func (c *Client) handle(message ClientMessage) error {
+	topic := message.Topic
+	if topic == "" {
+		topic = TopicJob
+	}
+	if topic != TopicJob && topic != TopicSystem {
+		return errors.New("unknown topic " + topic)
+	}
+	if topic == TopicJob && message.JobID == "" {
+		return errors.New("jobId is required")
+	}
+	if message.Action == ActionCancel || message.Action == ActionPause || message.Action == ActionResume {
+		if err := c.jobManager.AuthorizeJob(message.JobID, c.principal); err != nil {
+			return err
+		}
+	}
+
+	switch message.Action {
+	case ActionSubscribe:
+		if topic == TopicSystem {
+			c.subscribe(TopicSystem, c.followSystemStatus)
+			return nil
+		}
+		events, exists := c.jobManager.GetJobEvents(message.JobID)
+		if !exists {
+			return ErrJobNotFound
+		}
+		c.subscribe(TopicJob+":"+message.JobID, func(ctx context.Context) {
+			c.followJob(ctx, message.JobID, events, message.LastEventID)
+		})
+		return nil
+	case ActionUnsubscribe:
+		key := TopicSystem
+		if topic == TopicJob {
+			key = TopicJob + ":" + message.JobID
+		}
+		c.mutex.Lock()
+		if cancel, ok := c.subscriptions[key]; ok {
+			cancel()
+			delete(c.subscriptions, key)
+		}
+		c.mutex.Unlock()
+		return nil
+	case ActionCancel:
+		if !c.jobManager.CancelJob(message.JobID) {
+			return errors.New("job not found or not cancellable")
+		}
+		return nil
+	case ActionPause:
+		return c.jobManager.PauseJob(message.JobID)
+	case ActionResume:
+		return c.jobManager.UnpauseJob(message.JobID)
+	default:
+		return errors.New("unknown action " + message.Action)
+	}
+}
+
+// reply acknowledges a client action or reports why it failed
+func (c *Client) reply(message ClientMessage, err error) {
+	reply := &WebSocketMessage{
+		Type:      MessageTypeAck,
+		JobID:     message.JobID,
+		Timestamp: time.Now(),
+		Action:    message.Action,
+		RequestID: message.RequestID,
+	}
+	if err != nil {
+		reply.Type = MessageTypeError
+		reply.Data = ErrorMessage{JobID: message.JobID, Error: "Invalid request", Message: err.Error()}
+	}
+	c.deliver(context.Background(), reply)
+}
+
+// subscribe starts follow for a topic key, replacing an earlier subscription
+func (c *Client) subscribe(key string, follow func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.mutex.Lock()
+	if previous, ok := c.subscriptions[key]; ok {
+		previous()
+	}
+	c.subscriptions[key] = cancel
+	c.mutex.Unlock()
+	go follow(ctx)
+}
+
+// unsubscribeAll ends every subscription of the client
+func (c *Client) unsubscribeAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, cancel := range c.subscriptions {
+		cancel()
+		delete(c.subscriptions, key)
+	}
+}
+
+// followJob sends a job's events until the job ends, like StreamJobProgress. Without
+// lastEventID it starts with a snapshot of the job.
+func (c *Client) followJob(ctx context.Context, jobID string, events *jobEvents, lastEventID *uint64) {
+	var lastID uint64
+	sendSnapshot := func() bool {
+		snapshot, ok := c.jobManager.jobSnapshotEvent(jobID)
+		if ok {
+			lastID = snapshot.ID
+			return c.deliverEvent(ctx, jobID, snapshot)
+		}
+		return false
+	}
+	if lastEventID != nil {
+		lastID = *lastEventID
+	} else if !sendSnapshot() {
+		return
+	}
+
+	for {
+		batch, gap, done, changed := events.since(lastID)
+		if gap {
+			if !sendSnapshot() {
+				return
+			}
+			continue
+		}
+		for _, event := range batch {
+			if !c.deliverEvent(ctx, jobID, event) {
+				return
+			}
+			lastID = event.ID
+		}
+		if done {
+			return // Nothing is appended after job_completed
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-changed:
+		}
+	}
+}
+
+// followSystemStatus sends system status updates until unsubscribed
+func (c *Client) followSystemStatus(ctx context.Context) {
+	listener := c.jobManager.RegisterSystemStatusListener()
+	defer c.jobManager.UnregisterSystemStatusListener(listener)
+	for {
+		select {
+		case status, ok := <-listener:
+			if !ok {
+				return
+			}
+			message := &WebSocketMessage{Type: MessageTypeSystem, Timestamp: time.Now(), Data: status}
+			if !c.deliver(ctx, message) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// deliverEvent sends a job event in the shape of its SSE counterpart
+func (c *Client) deliverEvent(ctx context.Context, jobID string, event JobEvent) bool {
+	eventType := event.Type
+	if eventType == JobEventSnapshot {
+		eventType = TopicJob
+	}
+	return c.deliver(ctx, &WebSocketMessage{
+		Type:      MessageTypeEvent,
+		JobID:     jobID,
+		Timestamp: event.Time,
+		Data:      event.Data,
+		EventID:   event.ID,
+		Event:     eventType,
+	})
+}
+
+// deliver queues a message, waiting while the client is slow. It returns false once the
+// subscription or the client is gone.
+func (c *Client) deliver(ctx context.Context, message *WebSocketMessage) bool {
+	data, err := message.ToJSON()
+	if err != nil {
+		AppLogger.Error("Error marshaling WebSocket message: %v", err)
+		return true
+	}
+	select {
+	case c.Send <- data:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-c.done:
+		return false
 	}
 }
 
-// writePump pumps messages from the hub to the WebSocket connection
+// writePump writes queued messages to the connection, one JSON message per frame
 func (c *Client) writePump() {
 	ticker := time.NewTicker(54 * time.Second)
 	defer func() {
@@ -160,29 +398,16 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.Send:
+		case message := <-c.Send:
 			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.Conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
-			w.Write(message)
-
-			// Add queued chat messages to the current websocket message
-			n := len(c.Send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
-			}
 
-			if err := w.Close(); err != nil {
-				return
-			}
+		case <-c.done:
+			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
 
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
@@ -194,10 +419,10 @@ func (c *Client) writePump() {
 }
 
 // serveWebSocket handles WebSocket requests from clients
-func serveWebSocket(hub *Hub, c *gin.Context) {
+func serveWebSocket(hub *Hub, jobManager *SimpleJobManager, c *gin.Context) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		AppLogger.Warn("WebSocket upgrade error: %v", err)
 		return
 	}
 
@@ -205,11 +430,15 @@ func serveWebSocket(hub *Hub, c *gin.Context) {
 	clientID := generateClientID()
 
 	client := &Client{
-		ID:       clientID,
-		Conn:     conn,
-		Send:     make(chan []byte, 256),
-		Hub:      hub,
-		LastPing: time.Now(),
+		ID:            clientID,
+		Conn:          conn,
+		Send:          make(chan []byte, 256),
+		Hub:           hub,
+		LastPing:      time.Now(),
+		jobManager:    jobManager,
+		principal:     principalFrom(c),
+		done:          make(chan struct{}),
+		subscriptions: make(map[string]context.CancelFunc),
 	}
 
 	client.Hub.register <- client
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// newWebSocketServer serves /ws for jm and returns its ws:// URL
+func newWebSocketServer(t *testing.T, jm *SimpleJobManager) string {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mountWebSocket(router, jm)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+func dialWebSocket(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntil reads messages until match returns true
+func readUntil(t *testing.T, conn *websocket.Conn, match func(WebSocketMessage) bool) WebSocketMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var message WebSocketMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("expected a message: %v", err)
+		}
+		if match(message) {
+			return message
+		}
+	}
+}
+
+func TestWebSocketOriginCheck(t *testing.T) {
+	url := newWebSocketServer(t, NewSimpleJobManager())
+	header := http.Header{"Origin": []string{"https://other.example.com"}}
+	if _, response, err := websocket.DefaultDialer.Dial(url, header); err == nil || response.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a foreign origin to be rejected, got %v", err)
+	}
+
+	t.Setenv("WS_ALLOWED_ORIGINS", "https://ui.example.com, https://other.example.com")
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("expected an allowed origin to connect, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestWebSocketSubscribesAndControlsJobs(t *testing.T) {
+	jm := NewSimpleJobManager()
+	conn := dialWebSocket(t, newWebSocketServer(t, jm))
+
+	jobID := jm.CreateJob(BenchmarkRequest{Model1: Model{Name: "llama"}})
+	jm.Enqueue(jobID, func() {})
+	conn.WriteJSON(ClientMessage{Action: ActionSubscribe, JobID: jobID, RequestID: "1"})
+	snapshot := readUntil(t, conn, func(message WebSocketMessage) bool { return message.Type == MessageTypeEvent })
+	if snapshot.JobID != jobID || snapshot.Event != TopicJob || snapshot.EventID == 0 {
+		t.Fatalf("expected a job snapshot, got %+v", snapshot)
+	}
+
+	// Pause and resume are acknowledged and appear as job events
+	conn.WriteJSON(ClientMessage{Action: ActionPause, JobID: jobID, RequestID: "2"})
+	readUntil(t, conn, func(message WebSocketMessage) bool { return message.Type == MessageTypeAck && message.RequestID == "2" })
+	paused := readUntil(t, conn, func(message WebSocketMessage) bool {
+		return message.Type == MessageTypeEvent && strings.Contains(string(mustJSON(message.Data)), `"paused":true`)
+	})
+	if paused.EventID <= snapshot.EventID {
+		t.Errorf("expected event IDs to increase, got %d after %d", paused.EventID, snapshot.EventID)
+	}
+	conn.WriteJSON(ClientMessage{Action: ActionResume, JobID: jobID, RequestID: "3"})
+	readUntil(t, conn, func(message WebSocketMessage) bool { return message.Type == MessageTypeAck && message.RequestID == "3" })
+	if job, _ := jm.GetJob(jobID); job.Paused {
+		t.Error("expected the job to continue")
+	}
+
+	// Cancelling a running job waits for its runner, which reports the cancellation
+	conn.WriteJSON(ClientMessage{Action: ActionCancel, JobID: jobID, RequestID: "4"})
+	readUntil(t, conn, func(message WebSocketMessage) bool { return message.Type == MessageTypeAck && message.RequestID == "4" })
+	jm.FailJob(jobID, "context canceled")
+	completed := readUntil(t, conn, func(message WebSocketMessage) bool { return message.Event == JobEventJobCompleted })
+	if !strings.Contains(string(mustJSON(completed.Data)), `"status":"cancelled"`) {
+		t.Errorf("expected a cancelled job, got %s", mustJSON(completed.Data))
+	}
+
+	// Errors carry the request ID
+	conn.WriteJSON(ClientMessage{Action: ActionPause, JobID: "missing", RequestID: "5"})
+	failure := readUntil(t, conn, func(message WebSocketMessage) bool { return message.Type == MessageTypeError })
+	if failure.RequestID != "5" || failure.Action != ActionPause {
+		t.Errorf("expected the failed action to be identified, got %+v", failure)
+	}
+
+	conn.WriteJSON(ClientMessage{Action: ActionSubscribe, Topic: TopicSystem})
+	status := readUntil(t, conn, func(message WebSocketMessage) bool { return message.Type == MessageTypeSystem })
+	if data, _ := status.Data.(map[string]interface{}); data["activeJobs"] == nil {
+		t.Errorf("expected system status, got %+v", status.Data)
+	}
+}
+
+func TestWebSocketReplaysFromLastEventID(t *testing.T) {
+	jm := NewSimpleJobManager()
+	jobID := jm.CreateJob(BenchmarkRequest{})
+	jm.UpdateJobProgress(jobID, 10, "first")
+	jm.CompleteJob(jobID, nil)
+
+	conn := dialWebSocket(t, newWebSocketServer(t, jm))
+	from := uint64(1)
+	conn.WriteJSON(ClientMessage{Action: ActionSubscribe, JobID: jobID, LastEventID: &from})
+	var ids []uint64
+	readUntil(t, conn, func(message WebSocketMessage) bool {
+		if message.Type == MessageTypeEvent {
+			ids = append(ids, message.EventID)
+		}
+		return message.Event == JobEventJobCompleted
+	})
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Errorf("expected events 2 and 3, got %v", ids)
+	}
+}
+
+func mustJSON(value interface{}) []byte {
+	data, _ := json.Marshal(value)
+	return data
+}
+
+func TestWebSocketReceivesExecutorProgress(t *testing.T) {
+	jm := NewSimpleJobManager()
+	conn := dialWebSocket(t, newWebSocketServer(t, jm))
+	// The hub registers the client asynchronously; a subscription round trip waits for it
+	conn.WriteJSON(ClientMessage{Action: ActionSubscribe, Topic: TopicSystem, RequestID: "1"})
+	readUntil(t, conn, func(message WebSocketMessage) bool { return message.Type == MessageTypeAck && message.RequestID == "1" })
+
+	endpoint := newFakeEndpoint(t, false)
+	request := newExecutorRequest(endpoint.URL, 1, 2)
+	jobID := jm.CreateJob(request)
+	if _, err := (&Executor{}).Execute(context.Background(), NewExecutionPlan(jobID, request), jm.executionEmitter(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	readUntil(t, conn, func(message WebSocketMessage) bool {
+		return message.Type == MessageTypeProgress && message.JobID == jobID
+	})
+	readUntil(t, conn, func(message WebSocketMessage) bool {
+		return message.Type == MessageTypeComplete && message.JobID == jobID
+	})
+}
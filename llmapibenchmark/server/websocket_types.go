@@ -14,14 +14,45 @@ const (
 	MessageTypeCancelled   = "cancelled"
 	MessageTypePing        = "ping"
 	MessageTypePong        = "pong"
+	MessageTypeEvent       = "event"         // A job event, as sent over SSE
+	MessageTypeSystem      = "system_status" // System status, as sent over SSE
+	MessageTypeAck         = "ack"           // A client action succeeded
 )
 
+// Actions sent by WebSocket clients
+const (
+	ActionSubscribe   = "subscribe"   // To a job's events, or to system status with topic "system"
+	ActionUnsubscribe = "unsubscribe"
+	ActionCancel      = "cancel"
+	ActionPause       = "pause"  // Hold a benchmark before its next concurrency level
+	ActionResume      = "resume" // Continue a paused benchmark
+)
+
+// Subscription topics
+const (
+	TopicJob    = "job"
+	TopicSystem = "system"
+)
+
+// ClientMessage is a control message sent by a WebSocket client
+type ClientMessage struct {
+	Action      string  `json:"action"`
+	Topic       string  `json:"topic,omitempty"` // "job" (default) or "system"
+	JobID       string  `json:"jobId,omitempty"`
+	LastEventID *uint64 `json:"lastEventId,omitempty"` // Replays the job events after this ID
+	RequestID   string  `json:"requestId,omitempty"`   // Echoed in the ack or error
+}
+
 // WebSocketMessage represents a message sent over WebSocket
 type WebSocketMessage struct {
 	Type      string      `json:"type"`
 	JobID     string      `json:"jobId,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 	Data      interface{} `json:"data,omitempty"`
+	EventID   uint64      `json:"id,omitempty"`        // Job events: the event's sequence ID
+	Event     string      `json:"event,omitempty"`     // Job events: the SSE event type
+	Action    string      `json:"action,omitempty"`    // Acks and errors: the client action
+	RequestID string      `json:"requestId,omitempty"` // Acks and errors: the client's request ID
 }
 
 // ProgressUpdate represents benchmark progress information